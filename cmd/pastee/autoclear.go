@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+// bindClearCountdown shows a countdown at the top of the tray menu while a
+// copied sensitive item is waiting to be cleared from the clipboard. Choosing
+// it clears the clipboard right away.
+func bindClearCountdown(menu *fyne.Menu) {
+	countdown := fyne.NewMenuItem("", monitor.ClearNow)
+
+	update := func(state monitor.ClearState) {
+		shown := slices.Contains(menu.Items, countdown)
+		switch {
+		case state.Pending:
+			countdown.Label = clearCountdownText(state.Remaining)
+			if !shown {
+				menu.Items = append([]*fyne.MenuItem{countdown}, menu.Items...)
+			}
+		case shown:
+			menu.Items = slices.DeleteFunc(menu.Items, func(item *fyne.MenuItem) bool { return item == countdown })
+		default:
+			return
+		}
+		menu.Refresh()
+	}
+
+	monitor.OnClearChange(func(state monitor.ClearState) {
+		fyne.Do(func() {
+			update(state)
+		})
+	})
+}
+
+// clearCountdownText is the tray label for a pending clear
+func clearCountdownText(remaining time.Duration) string {
+	return fmt.Sprintf("Clear Clipboard Now (%ds left)", int(remaining.Round(time.Second).Seconds()))
+}
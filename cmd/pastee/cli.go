@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/transform"
+)
+
+// subcommands run without starting the GUI: pastee <command> [flags]
+var subcommands = map[string]func(args []string) int{
+	"transform": runTransform,
+	"registers": runRegisters,
+	"help":      runHelp,
+}
+
+// commandSummaries is the overview printed by pastee help
+const commandSummaries = `usage: pastee [-paused]          start the clipboard manager
+       pastee <command> [flags]
+
+commands:
+  daemon      capture the clipboard without a window or tray
+  list        print the newest history items
+  search      find items by content or link title
+  get         print an item's content
+  copy        put an item on the clipboard
+  add         store text from stdin or -file
+  delete      remove items
+  favorite    mark or unmark favorites
+  clear       delete the history (registers are kept)
+  stats       count items by type
+  registers   list the filled registers
+  transform   apply a text transform to stdin
+  pause       pause capture in the running instance
+  resume      resume capture in the running instance
+  show        show the running app's window
+  pair        pair with another device for LAN sharing
+  peers       list the paired devices
+  unpair      forget a paired device
+
+Commands talk to the running app or daemon when there is one, and open the
+history directly otherwise. Run pastee <command> -h for the flags of a command.
+`
+
+// runSubcommand executes the CLI subcommand named by args[0], if any.
+// It returns the exit code and whether a subcommand was run.
+func runSubcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		return 0, false
+	}
+	// Keep stderr for subcommand errors; the GUI's progress logging would only be noise here
+	log.SetOutput(io.Discard)
+	return cmd(args[1:]), true
+}
+
+// cliError prints a subcommand error to stderr and returns the exit code
+func cliError(command string, err error) int {
+	fmt.Fprintf(os.Stderr, "pastee %s: %v\n", command, err)
+	return 1
+}
+
+// runTransform applies a text transform to stdin and prints the result
+func runTransform(args []string) int {
+	fs := flag.NewFlagSet("transform", flag.ContinueOnError)
+	as := fs.String("as", "", "transform to apply (see -list)")
+	list := fs.Bool("list", false, "list available transforms")
+	save := fs.Bool("save", false, "also store the result as a new history item")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee transform -as <name> [-save] < input")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *list {
+		for _, t := range transform.All() {
+			fmt.Printf("%-18s %s\n", t.Name, t.Label)
+		}
+		return 0
+	}
+
+	if *as == "" {
+		fs.Usage()
+		return 2
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return cliError("transform", err)
+	}
+
+	result, err := transform.Apply(*as, string(input))
+	if err != nil {
+		return cliError("transform", err)
+	}
+
+	if *save {
+		code := withHistory("transform", func(api historyAPI) error {
+			_, err := api.Add(addParams{Content: result})
+			return err
+		})
+		if code != 0 {
+			return code
+		}
+	}
+
+	fmt.Print(result)
+	return 0
+}
+
+// runRegisters prints each filled register with a preview of its item
+func runRegisters(args []string) int {
+	fs := flag.NewFlagSet("registers", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee registers [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	return withHistory("registers", func(api historyAPI) error {
+		registers, err := api.Registers()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(registers)
+		}
+		for _, r := range registers {
+			fmt.Printf("%s  %s\n", r.Name, preview(r.Item))
+		}
+		return nil
+	})
+}
+
+func runHelp(args []string) int {
+	fmt.Print(commandSummaries)
+	return 0
+}
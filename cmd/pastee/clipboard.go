@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hooks"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"golang.design/x/clipboard"
+)
+
+// clipboardData returns what to put on the clipboard for an item
+func clipboardData(item models.ClipboardItem) (clipboard.Format, []byte, error) {
+	if item.Type != "image" {
+		return clipboard.FmtText, []byte(item.Content), nil
+	}
+	data, err := os.ReadFile(item.ImagePath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read image file: %w", err)
+	}
+	return clipboard.FmtImage, data, nil
+}
+
+// copyDetached puts an item on the clipboard from a short-lived CLI process.
+// Sensitive text is cleared again after the configured delay, as it would be
+// by the running instance.
+func copyDetached(item models.ClipboardItem, _ bool) error {
+	format, data, err := clipboardData(item)
+	if err != nil {
+		return err
+	}
+	var clearAfter time.Duration
+	if cfg := config.Get().ClipboardClear; item.IsSensitive && format == clipboard.FmtText && cfg.Enabled && cfg.AfterSeconds > 0 {
+		clearAfter = time.Duration(cfg.AfterSeconds) * time.Second
+	}
+	return writeClipboard(format, data, clearAfter)
+}
+
+// copyInProcess puts an item on the clipboard from a process running the
+// monitor, marking it as our own write so it is not captured again
+func copyInProcess(item models.ClipboardItem, _ bool) error {
+	format, data, err := clipboardData(item)
+	if err != nil {
+		return err
+	}
+	switch {
+	case format == clipboard.FmtImage:
+		monitor.MarkSelfWrittenImage(data)
+		clipboard.Write(format, data)
+	case item.IsSensitive:
+		monitor.WriteSecretText(item.Content)
+	default:
+		monitor.MarkSelfWrittenText(item.Content)
+		clipboard.Write(format, data)
+	}
+	hooks.ItemCopied(item)
+	return nil
+}
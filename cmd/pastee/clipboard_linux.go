@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"golang.design/x/clipboard"
+)
+
+// serveClipboardCommand is the hidden subcommand that owns the selection for pastee copy
+const serveClipboardCommand = "serve-clipboard"
+
+func init() {
+	subcommands[serveClipboardCommand] = runServeClipboard
+}
+
+// writeClipboard puts data on the clipboard. X11 selections disappear with
+// their owner, so a detached copy of pastee keeps serving the data until
+// something else is copied, much like xclip does, or until clearAfter passes
+// if it is positive.
+func writeClipboard(format clipboard.Format, data []byte, clearAfter time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	formatArg := "text"
+	if format == clipboard.FmtImage {
+		formatArg = "image"
+	}
+	args := []string{serveClipboardCommand, formatArg}
+	if clearAfter > 0 {
+		args = append(args, strconv.Itoa(int(clearAfter/time.Second)))
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	ready, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Wait until the child owns the selection, so the next command can paste it
+	line, err := bufio.NewReader(ready).ReadString('\n')
+	if strings.TrimSpace(line) != "ready" {
+		cmd.Wait()
+		if err == nil || errors.Is(err, io.EOF) {
+			err = errors.New("clipboard is not available")
+		}
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// runServeClipboard owns the selection with stdin as its content and exits
+// once another application takes it over. An optional number of seconds
+// clears it earlier: the previous text is served in its place if
+// clipboard_clear.restore_previous is set, otherwise exiting empties it.
+func runServeClipboard(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		return 2
+	}
+	format := clipboard.FmtText
+	if args[0] == "image" {
+		format = clipboard.FmtImage
+	}
+	var clearAfter time.Duration
+	if len(args) == 2 {
+		seconds, err := strconv.Atoi(args[1])
+		if err != nil || seconds <= 0 {
+			return 2
+		}
+		clearAfter = time.Duration(seconds) * time.Second
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return cliError(serveClipboardCommand, err)
+	}
+	if err := clipboard.Init(); err != nil {
+		return cliError(serveClipboardCommand, err)
+	}
+	var previous []byte
+	if clearAfter > 0 && config.Get().ClipboardClear.RestorePrevious {
+		previous = clipboard.Read(clipboard.FmtText)
+	}
+	changed := clipboard.Write(format, data)
+	fmt.Println("ready")
+	os.Stdout.Close()
+
+	if clearAfter <= 0 {
+		<-changed
+		return 0
+	}
+	select {
+	case <-changed:
+		return 0
+	case <-time.After(clearAfter):
+	}
+	if len(previous) == 0 {
+		return 0
+	}
+	<-clipboard.Write(clipboard.FmtText, previous)
+	return 0
+}
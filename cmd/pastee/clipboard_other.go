@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.design/x/clipboard"
+)
+
+// writeClipboard puts data on the clipboard. The system keeps it after we
+// exit, so nothing is left to clear it after clearAfter; the user is warned.
+func writeClipboard(format clipboard.Format, data []byte, clearAfter time.Duration) error {
+	if err := clipboard.Init(); err != nil {
+		return err
+	}
+	clipboard.Write(format, data)
+	if clearAfter > 0 {
+		fmt.Fprintln(os.Stderr, "pastee: warning: the sensitive item stays on the clipboard; it is only cleared automatically while Pastee is running")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/daemon"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hooks"
+	"github.com/Sirpyerre/pasteeclipboard/internal/linkmeta"
+	"github.com/Sirpyerre/pasteeclipboard/internal/logging"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/scripting"
+)
+
+func init() {
+	subcommands["daemon"] = runDaemon
+}
+
+// runDaemon captures the clipboard without a window or tray until it receives
+// SIGINT or SIGTERM
+func runDaemon(args []string) int {
+	dataDir, err := database.DataDir()
+	if err != nil {
+		return cliError("daemon", err)
+	}
+
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	pidPath := fs.String("pidfile", filepath.Join(dataDir, "pastee.pid"), "file holding the daemon's process ID")
+	logPath := fs.String("log", filepath.Join(dataDir, logging.FileName), "file to append log output to, rotated as it grows; - for stderr")
+	paused := fs.Bool("paused", false, "start with clipboard capture paused until resumed")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee daemon [-pidfile path] [-log path] [-paused]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
+		return cliError("daemon", err)
+	}
+
+	var stopLogging func()
+	if *logPath == "-" {
+		stopLogging, err = logging.Start(os.Stderr, "")
+	} else {
+		stopLogging, err = logging.Start(nil, *logPath)
+	}
+	if err != nil {
+		return cliError("daemon", err)
+	}
+	defer stopLogging()
+
+	instanceListener, err := listenInstance()
+	if err != nil {
+		return cliError("daemon", err)
+	}
+	defer instanceListener.Close()
+
+	releasePID, err := daemon.AcquirePIDFile(*pidPath)
+	if err != nil {
+		return cliError("daemon", err)
+	}
+	defer func() {
+		if err := releasePID(); err != nil {
+			slog.Error("error removing pid file", "err", err)
+		}
+	}()
+
+	_, needsMigration, err := database.InitDB()
+	if err != nil {
+		slog.Error("error opening database", "err", err)
+		return cliError("daemon", err)
+	}
+	defer func() {
+		if err := database.CloseDB(); err != nil {
+			slog.Error("error closing database", "err", err)
+		}
+	}()
+	if needsMigration {
+		slog.Warn("History is not encrypted yet; open the app once to encrypt it")
+	}
+
+	if *paused {
+		monitor.Pause(0)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopScript := scripting.Start()
+
+	err = monitor.StartClipboardMonitor(func(item models.ClipboardItem, _ bool) {
+		slog.Debug("Captured item", "type", item.Type, "id", item.ID)
+		linkmeta.FetchInBackground(item, nil)
+	})
+	if err != nil {
+		slog.Error("error starting clipboard monitor", "err", err)
+		return cliError("daemon", err)
+	}
+	api := &localHistory{copy: copyInProcess, running: true}
+	stopLAN := startLANShare(api)
+	server := startInstanceServer(instanceListener, "daemon", api)
+	stopDBus := startDBusService(api)
+	stopHTTP := startHTTPAPI(api)
+	stopSync := startSync(api)
+	stopHooks := hooks.Start()
+	slog.Info("Pastee daemon started", "pid", os.Getpid())
+
+	<-ctx.Done()
+	slog.Info("Shutting down Pastee daemon")
+	server.Close()
+	stopDBus()
+	stopHTTP()
+	monitor.StopClipboardMonitor()
+	monitor.ClearNow() // Don't leave a copied secret behind
+	stopSync()
+	stopLAN()
+	stopHooks()
+	stopScript()
+	linkmeta.Wait()
+	return 0
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package main
+
+import (
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/dbusapi"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// startDBusService exports api as org.pastee.Clipboard on the session bus and
+// turns history changes into its signals. Call the returned function on exit.
+func startDBusService(api historyAPI) func() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		slog.Warn("D-Bus service unavailable", "err", err)
+		return func() {}
+	}
+	service, err := dbusapi.Export(conn, dbusBackend{api: api})
+	if err != nil {
+		slog.Error("error exporting D-Bus service", "err", err)
+		conn.Close()
+		return func() {}
+	}
+
+	removeListener := database.AddItemListener(database.ItemListener{
+		Added: func(item models.ClipboardItem) {
+			logSignalError(service.ItemAdded(toDBusItem(toItemJSON(item, false))))
+		},
+		Removed: func(id int, pruned bool) {
+			logSignalError(service.ItemRemoved(id))
+		},
+		Cleared: func() {
+			logSignalError(service.HistoryCleared())
+		},
+	})
+	slog.Info("D-Bus service registered", "name", dbusapi.BusName)
+
+	return func() {
+		removeListener()
+		conn.Close()
+	}
+}
+
+func logSignalError(err error) {
+	if err != nil {
+		slog.Error("error emitting D-Bus signal", "err", err)
+	}
+}
+
+func toDBusItem(j itemJSON) dbusapi.Item {
+	return dbusapi.Item{
+		ID:        int64(j.ID),
+		Type:      j.Type,
+		Content:   j.Content,
+		ImagePath: j.ImagePath,
+		Title:     j.Title,
+		Favorite:  j.Favorite,
+		Sensitive: j.Sensitive,
+		Created:   j.CreatedAt.Unix(),
+	}
+}
+
+// dbusBackend answers D-Bus calls with the same historyAPI the CLI uses. As
+// with pastee list, listed sensitive items come without their content.
+type dbusBackend struct {
+	api historyAPI
+}
+
+func (b dbusBackend) List(limit int, query string) ([]dbusapi.Item, error) {
+	items, err := b.api.List(listParams{Limit: limit, Query: query})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]dbusapi.Item, len(items))
+	for i, item := range items {
+		out[i] = toDBusItem(item)
+	}
+	return out, nil
+}
+
+func (b dbusBackend) Get(id int) (dbusapi.Item, error) {
+	item, err := b.api.Get(itemParams{ID: id})
+	return toDBusItem(item), err
+}
+
+func (b dbusBackend) Copy(id int) error {
+	return b.api.Copy(itemParams{ID: id})
+}
+
+func (b dbusBackend) Pause(seconds int) error {
+	_, err := b.api.Pause(pauseParams{Seconds: seconds})
+	return err
+}
+
+func (b dbusBackend) Resume() error {
+	_, err := b.api.Pause(pauseParams{Resume: true})
+	return err
+}
+
+func (b dbusBackend) Show() error {
+	return b.api.Show()
+}
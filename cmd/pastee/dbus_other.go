@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// startDBusService is a no-op: the D-Bus service is only offered on Linux
+func startDBusService(api historyAPI) func() {
+	return func() {}
+}
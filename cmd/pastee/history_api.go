@@ -0,0 +1,426 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/ipc"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/transform"
+)
+
+// historyAPI is what CLI commands can do with the history. localHistory works
+// on the database directly; remoteHistory asks the running instance over IPC,
+// which answers with its own localHistory.
+type historyAPI interface {
+	List(p listParams) ([]itemJSON, error)
+	Get(p itemParams) (itemJSON, error)
+	Copy(p itemParams) error
+	Add(p addParams) (itemJSON, error)
+	Delete(p idsParams) error
+	Favorite(p favoriteParams) error
+	Clear() error
+	Stats() (database.Stats, error)
+	Registers() ([]registerJSON, error)
+	Pause(p pauseParams) (pauseJSON, error)
+	Show() error
+	Pair(p pairParams) (pairJSON, error)
+	Peers() (peersJSON, error)
+	Unpair(p unpairParams) error
+}
+
+// itemJSON is the wire and -json form of a history item
+type itemJSON struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content,omitempty"`
+	ImagePath string    `json:"image_path,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Source    string    `json:"source"`
+	Favorite  bool      `json:"favorite"`
+	Sensitive bool      `json:"sensitive"`
+	Registers []string  `json:"registers,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toItemJSON(item models.ClipboardItem, reveal bool) itemJSON {
+	j := itemJSON{
+		ID:        item.ID,
+		Type:      item.Type,
+		Content:   item.Content,
+		ImagePath: item.ImagePath,
+		Title:     item.Title,
+		Source:    item.Source,
+		Favorite:  item.IsFavorite,
+		Sensitive: item.IsSensitive,
+		Registers: item.Registers,
+		CreatedAt: item.CreatedAt,
+	}
+	if item.IsSensitive && !reveal {
+		j.Content = ""
+	}
+	return j
+}
+
+type registerJSON struct {
+	Name string   `json:"name"`
+	Item itemJSON `json:"item"`
+}
+
+type listParams struct {
+	Limit     int    `json:"limit"`
+	Favorites bool   `json:"favorites,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Query     string `json:"query,omitempty"`
+	Reveal    bool   `json:"reveal,omitempty"` // Include the content of sensitive items
+}
+
+type itemParams struct {
+	ID int    `json:"id"`
+	As string `json:"as,omitempty"` // Transform applied to the content first
+}
+
+type addParams struct {
+	Content   string `json:"content"`
+	Favorite  bool   `json:"favorite,omitempty"`
+	Sensitive bool   `json:"sensitive,omitempty"`
+}
+
+type idsParams struct {
+	IDs []int `json:"ids"`
+}
+
+type favoriteParams struct {
+	IDs []int `json:"ids"`
+	Off bool  `json:"off,omitempty"`
+}
+
+type pauseParams struct {
+	Seconds int  `json:"seconds,omitempty"` // Zero pauses until resumed
+	Resume  bool `json:"resume,omitempty"`
+}
+
+type pauseJSON struct {
+	Paused bool      `json:"paused"`
+	Until  time.Time `json:"until,omitzero"`
+}
+
+// errNoItem is wrapped by errors about an unknown item ID
+var errNoItem = errors.New("no item")
+
+// errNeedsInstance is returned for commands that only make sense with pastee running
+var errNeedsInstance = errors.New("pastee is not running; start the app or pastee daemon first")
+
+// localHistory serves the history from this process's database
+type localHistory struct {
+	copy    func(item models.ClipboardItem, transformed bool) error // Puts an item on the clipboard
+	changed func()                                                  // Called after the history was modified; may be nil
+	show    func() error                                            // Shows the window; nil without one
+	running bool                                                    // Whether this process is the running instance
+	lan     *lanShare                                               // Set while LAN sharing runs
+}
+
+func (l *localHistory) notifyChanged() {
+	if l.changed != nil {
+		l.changed()
+	}
+}
+
+func (l *localHistory) List(p listParams) ([]itemJSON, error) {
+	// Filters are applied after reading, so read everything when filtering.
+	// A limit of zero or less means no limit.
+	readLimit := p.Limit
+	if p.Limit <= 0 || p.Favorites || p.Type != "" {
+		readLimit = -1
+	}
+	var items []models.ClipboardItem
+	var err error
+	if p.Query != "" {
+		items, err = database.SearchClipboardHistory(p.Query, readLimit)
+	} else {
+		items, err = database.GetClipboardHistory(readLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	shown := []itemJSON{}
+	for _, item := range items {
+		if (p.Favorites && !item.IsFavorite) || (p.Type != "" && item.Type != p.Type) {
+			continue
+		}
+		if p.Limit > 0 && len(shown) == p.Limit {
+			break
+		}
+		shown = append(shown, toItemJSON(item, p.Reveal))
+	}
+	return shown, nil
+}
+
+// lookup fetches an item, turning a missing ID into a readable error
+func (l *localHistory) lookup(id int) (*models.ClipboardItem, error) {
+	item, err := database.GetItemByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w with id %d", errNoItem, id)
+	}
+	return item, err
+}
+
+// lookupTransformed fetches an item and applies the requested transform to its content
+func (l *localHistory) lookupTransformed(p itemParams) (*models.ClipboardItem, error) {
+	item, err := l.lookup(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if p.As == "" {
+		return item, nil
+	}
+	if item.Type == "image" {
+		return nil, errors.New("transforms only apply to text items")
+	}
+	if item.Content, err = transform.Apply(p.As, item.Content); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (l *localHistory) Get(p itemParams) (itemJSON, error) {
+	item, err := l.lookupTransformed(p)
+	if err != nil {
+		return itemJSON{}, err
+	}
+	return toItemJSON(*item, true), nil
+}
+
+func (l *localHistory) Copy(p itemParams) error {
+	item, err := l.lookupTransformed(p)
+	if err != nil {
+		return err
+	}
+	return l.copy(*item, p.As != "")
+}
+
+func (l *localHistory) Add(p addParams) (itemJSON, error) {
+	if p.Content == "" {
+		return itemJSON{}, errors.New("nothing to add")
+	}
+	item, isNew, err := monitor.StoreText(models.ClipboardItem{
+		Content:     p.Content,
+		Source:      models.SourceCLI,
+		IsFavorite:  p.Favorite,
+		IsSensitive: p.Sensitive,
+	})
+	if err != nil {
+		return itemJSON{}, err
+	}
+	// An existing identical item only gains the flags
+	if p.Favorite && !isNew {
+		if err := database.UpdateItemFavorite(item.ID, true); err != nil {
+			return itemJSON{}, err
+		}
+	}
+	if p.Sensitive && !isNew {
+		if err := database.UpdateItemSensitivity(item.ID, true); err != nil {
+			return itemJSON{}, err
+		}
+	}
+	l.notifyChanged()
+
+	stored, err := l.lookup(item.ID)
+	if err != nil {
+		return itemJSON{}, err
+	}
+	return toItemJSON(*stored, true), nil
+}
+
+func (l *localHistory) Delete(p idsParams) error {
+	defer l.notifyChanged()
+	for _, id := range p.IDs {
+		if _, err := l.lookup(id); err != nil {
+			return err
+		}
+		if err := database.DeleteClipboardItem(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *localHistory) Favorite(p favoriteParams) error {
+	defer l.notifyChanged()
+	for _, id := range p.IDs {
+		if _, err := l.lookup(id); err != nil {
+			return err
+		}
+		if err := database.UpdateItemFavorite(id, !p.Off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *localHistory) Clear() error {
+	defer l.notifyChanged()
+	return database.DeleteAllClipboardItems()
+}
+
+func (l *localHistory) Stats() (database.Stats, error) {
+	return database.GetStats()
+}
+
+func (l *localHistory) Registers() ([]registerJSON, error) {
+	registers, err := database.ListRegisters()
+	if err != nil {
+		return nil, err
+	}
+	out := []registerJSON{}
+	for _, r := range registers {
+		out = append(out, registerJSON{Name: r.Name, Item: toItemJSON(r.Item, false)})
+	}
+	return out, nil
+}
+
+func (l *localHistory) Pause(p pauseParams) (pauseJSON, error) {
+	if !l.running {
+		return pauseJSON{}, errNeedsInstance
+	}
+	if p.Resume {
+		monitor.Resume()
+	} else {
+		monitor.Pause(time.Duration(p.Seconds) * time.Second)
+	}
+	state := monitor.CurrentPauseState()
+	return pauseJSON{Paused: state.Paused, Until: state.Until}, nil
+}
+
+func (l *localHistory) Show() error {
+	if !l.running {
+		return errNeedsInstance
+	}
+	if l.show == nil {
+		return errors.New("the running instance has no window")
+	}
+	return l.show()
+}
+
+// IPC method names, one per historyAPI method
+const (
+	methodList      = "list"
+	methodGet       = "get"
+	methodCopy      = "copy"
+	methodAdd       = "add"
+	methodDelete    = "delete"
+	methodFavorite  = "favorite"
+	methodClear     = "clear"
+	methodStats     = "stats"
+	methodRegisters = "registers"
+	methodPause     = "pause"
+	methodShow      = "show"
+	methodPair      = "pair"
+	methodPeers     = "peers"
+	methodUnpair    = "unpair"
+)
+
+// handle registers fn for method, decoding its params into a P
+func handle[P any](s *ipc.Server, method string, fn func(P) (any, error)) {
+	s.Handle(method, func(raw json.RawMessage) (any, error) {
+		var p P
+		if err := ipc.DecodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		return fn(p)
+	})
+}
+
+// serveHistory exposes api to IPC clients
+func serveHistory(s *ipc.Server, api historyAPI) {
+	type none struct{}
+	handle(s, methodList, func(p listParams) (any, error) { return api.List(p) })
+	handle(s, methodGet, func(p itemParams) (any, error) { return api.Get(p) })
+	handle(s, methodCopy, func(p itemParams) (any, error) { return nil, api.Copy(p) })
+	handle(s, methodAdd, func(p addParams) (any, error) { return api.Add(p) })
+	handle(s, methodDelete, func(p idsParams) (any, error) { return nil, api.Delete(p) })
+	handle(s, methodFavorite, func(p favoriteParams) (any, error) { return nil, api.Favorite(p) })
+	handle(s, methodClear, func(none) (any, error) { return nil, api.Clear() })
+	handle(s, methodStats, func(none) (any, error) { return api.Stats() })
+	handle(s, methodRegisters, func(none) (any, error) { return api.Registers() })
+	handle(s, methodPause, func(p pauseParams) (any, error) { return api.Pause(p) })
+	handle(s, methodShow, func(none) (any, error) { return nil, api.Show() })
+	handle(s, methodPair, func(p pairParams) (any, error) { return api.Pair(p) })
+	handle(s, methodPeers, func(none) (any, error) { return api.Peers() })
+	handle(s, methodUnpair, func(p unpairParams) (any, error) { return nil, api.Unpair(p) })
+}
+
+// remoteHistory forwards every call to the running instance
+type remoteHistory struct {
+	c *ipc.Client
+}
+
+func (r remoteHistory) List(p listParams) (items []itemJSON, err error) {
+	err = r.c.Call(methodList, p, &items)
+	return items, err
+}
+
+func (r remoteHistory) Get(p itemParams) (item itemJSON, err error) {
+	err = r.c.Call(methodGet, p, &item)
+	return item, err
+}
+
+func (r remoteHistory) Copy(p itemParams) error {
+	return r.c.Call(methodCopy, p, nil)
+}
+
+func (r remoteHistory) Add(p addParams) (item itemJSON, err error) {
+	err = r.c.Call(methodAdd, p, &item)
+	return item, err
+}
+
+func (r remoteHistory) Delete(p idsParams) error {
+	return r.c.Call(methodDelete, p, nil)
+}
+
+func (r remoteHistory) Favorite(p favoriteParams) error {
+	return r.c.Call(methodFavorite, p, nil)
+}
+
+func (r remoteHistory) Clear() error {
+	return r.c.Call(methodClear, nil, nil)
+}
+
+func (r remoteHistory) Stats() (stats database.Stats, err error) {
+	err = r.c.Call(methodStats, nil, &stats)
+	return stats, err
+}
+
+func (r remoteHistory) Registers() (registers []registerJSON, err error) {
+	err = r.c.Call(methodRegisters, nil, &registers)
+	return registers, err
+}
+
+func (r remoteHistory) Pause(p pauseParams) (state pauseJSON, err error) {
+	err = r.c.Call(methodPause, p, &state)
+	return state, err
+}
+
+func (r remoteHistory) Show() error {
+	return r.c.Call(methodShow, nil, nil)
+}
+
+func (r remoteHistory) Pair(p pairParams) (result pairJSON, err error) {
+	err = r.c.Call(methodPair, p, &result)
+	return result, err
+}
+
+func (r remoteHistory) Peers() (peers peersJSON, err error) {
+	err = r.c.Call(methodPeers, nil, &peers)
+	return peers, err
+}
+
+func (r remoteHistory) Unpair(p unpairParams) error {
+	return r.c.Call(methodUnpair, p, nil)
+}
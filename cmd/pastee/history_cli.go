@@ -0,0 +1,453 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	subcommands["list"] = runList
+	subcommands["search"] = runSearch
+	subcommands["get"] = runGet
+	subcommands["copy"] = runCopy
+	subcommands["add"] = runAdd
+	subcommands["delete"] = runDelete
+	subcommands["favorite"] = runFavorite
+	subcommands["clear"] = runClear
+	subcommands["stats"] = runStats
+	subcommands["pause"] = runPause
+	subcommands["resume"] = runResume
+	subcommands["show"] = runShow
+}
+
+// withHistory runs fn against the running instance or, without one, the
+// database, and turns its error into an exit code
+func withHistory(command string, fn func(api historyAPI) error) int {
+	api, done, err := connectHistory()
+	if err != nil {
+		return cliError(command, err)
+	}
+	defer done()
+	if err := fn(api); err != nil {
+		return cliError(command, err)
+	}
+	return 0
+}
+
+// parseArgs parses flags that may appear before or after positional arguments,
+// so both "get -json 5" and "get 5 -json" work
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// Everything after "--" is positional, even if it looks like a flag
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// parseIDs converts positional arguments into item IDs
+func parseIDs(args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing item id")
+	}
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid item id %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// preview shortens an item to a single line for listing
+func preview(item itemJSON) string {
+	switch {
+	case item.Type == "image":
+		return "[image]"
+	case item.Sensitive && item.Content == "":
+		return "[sensitive]"
+	}
+	text := strings.Join(strings.Fields(item.Content), " ")
+	if len([]rune(text)) > 60 {
+		text = string([]rune(text)[:60]) + "…"
+	}
+	return text
+}
+
+// printItems writes items as a table, or as a JSON array with asJSON
+func printItems(items []itemJSON, asJSON bool) error {
+	if asJSON {
+		return printJSON(items)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, item := range items {
+		marks := ""
+		if item.Favorite {
+			marks = "★"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", item.ID, item.Type, item.CreatedAt.Local().Format("2006-01-02 15:04"), marks, preview(item))
+	}
+	return w.Flush()
+}
+
+// runList prints the newest history items
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	p := listParams{}
+	fs.IntVar(&p.Limit, "limit", 20, "maximum number of items")
+	fs.BoolVar(&p.Favorites, "favorites", false, "only list favorites")
+	fs.StringVar(&p.Type, "type", "", "only list items of this type (text, link, image, …)")
+	fs.BoolVar(&p.Reveal, "reveal", false, "show the content of sensitive items")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee list [-limit n] [-favorites] [-type t] [-json] [-reveal]")
+		fs.PrintDefaults()
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+
+	return withHistory("list", func(api historyAPI) error {
+		items, err := api.List(p)
+		if err != nil {
+			return err
+		}
+		return printItems(items, *asJSON)
+	})
+}
+
+// runSearch prints the items whose content or link title contains the query
+func runSearch(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	p := listParams{}
+	fs.IntVar(&p.Limit, "limit", 20, "maximum number of items")
+	fs.BoolVar(&p.Reveal, "reveal", false, "show the content of sensitive items")
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee search [-limit n] [-json] [-reveal] <query>")
+		fs.PrintDefaults()
+	}
+	words, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(words) == 0 {
+		fs.Usage()
+		return 2
+	}
+	p.Query = strings.Join(words, " ")
+
+	return withHistory("search", func(api historyAPI) error {
+		items, err := api.List(p)
+		if err != nil {
+			return err
+		}
+		return printItems(items, *asJSON)
+	})
+}
+
+// parseItemArgs parses the flags and the single item ID of get and copy
+func parseItemArgs(fs *flag.FlagSet, args []string) (int, bool) {
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return 0, false
+	}
+	ids, err := parseIDs(rest)
+	if err != nil || len(ids) != 1 {
+		fs.Usage()
+		return 0, false
+	}
+	return ids[0], true
+}
+
+// runGet prints one item's content, or the image path of an image item
+func runGet(args []string) int {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the item as JSON")
+	as := fs.String("as", "", "transform the content first (see pastee transform -list)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee get [-json] [-as transform] <id>")
+		fs.PrintDefaults()
+	}
+	id, ok := parseItemArgs(fs, args)
+	if !ok {
+		return 2
+	}
+
+	return withHistory("get", func(api historyAPI) error {
+		item, err := api.Get(itemParams{ID: id, As: *as})
+		if err != nil {
+			return err
+		}
+		switch {
+		case *asJSON:
+			return printJSON(item)
+		case item.Type == "image":
+			_, err = fmt.Println(item.ImagePath)
+		default:
+			_, err = fmt.Print(item.Content)
+		}
+		return err
+	})
+}
+
+// runCopy puts an item on the system clipboard
+func runCopy(args []string) int {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	as := fs.String("as", "", "transform the content first (see pastee transform -list)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee copy [-as transform] <id>")
+		fs.PrintDefaults()
+	}
+	id, ok := parseItemArgs(fs, args)
+	if !ok {
+		return 2
+	}
+
+	return withHistory("copy", func(api historyAPI) error {
+		return api.Copy(itemParams{ID: id, As: *as})
+	})
+}
+
+// runAdd stores text from stdin or a file as a new history item
+func runAdd(args []string) int {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	p := addParams{}
+	file := fs.String("file", "", "read the content from this file instead of stdin")
+	fs.BoolVar(&p.Favorite, "favorite", false, "mark the item as a favorite")
+	fs.BoolVar(&p.Sensitive, "sensitive", false, "mark the item as sensitive")
+	asJSON := fs.Bool("json", false, "print the stored item as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee add [-file path] [-favorite] [-sensitive] [-json] < input")
+		fs.PrintDefaults()
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+
+	var input []byte
+	var err error
+	if *file != "" {
+		input, err = os.ReadFile(*file)
+	} else {
+		input, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return cliError("add", err)
+	}
+	if strings.TrimSpace(string(input)) == "" {
+		return cliError("add", errors.New("nothing to add"))
+	}
+	p.Content = string(input)
+
+	return withHistory("add", func(api historyAPI) error {
+		item, err := api.Add(p)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(item)
+		}
+		_, err = fmt.Println(item.ID)
+		return err
+	})
+}
+
+// runDelete removes items from history
+func runDelete(args []string) int {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee delete <id>...")
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	ids, err := parseIDs(rest)
+	if err != nil {
+		fs.Usage()
+		return 2
+	}
+
+	return withHistory("delete", func(api historyAPI) error {
+		return api.Delete(idsParams{IDs: ids})
+	})
+}
+
+// runFavorite marks items as favorites, or unmarks them with -off
+func runFavorite(args []string) int {
+	fs := flag.NewFlagSet("favorite", flag.ContinueOnError)
+	off := fs.Bool("off", false, "remove the favorite mark instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee favorite [-off] <id>...")
+		fs.PrintDefaults()
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	ids, err := parseIDs(rest)
+	if err != nil {
+		fs.Usage()
+		return 2
+	}
+
+	return withHistory("favorite", func(api historyAPI) error {
+		return api.Favorite(favoriteParams{IDs: ids, Off: *off})
+	})
+}
+
+// runClear deletes the whole history except items held in registers
+func runClear(args []string) int {
+	fs := flag.NewFlagSet("clear", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm deleting the history")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee clear -yes")
+		fs.PrintDefaults()
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+	if !*yes {
+		return cliError("clear", errors.New("refusing to delete the history without -yes"))
+	}
+
+	return withHistory("clear", func(api historyAPI) error {
+		return api.Clear()
+	})
+}
+
+// runStats prints item counts by type and flag
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee stats [-json]")
+		fs.PrintDefaults()
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+
+	return withHistory("stats", func(api historyAPI) error {
+		stats, err := api.Stats()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(stats)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Items:\t%d\n", stats.Total)
+		for _, itemType := range sortedKeys(stats.ByType) {
+			fmt.Fprintf(w, "  %s:\t%d\n", itemType, stats.ByType[itemType])
+		}
+		fmt.Fprintf(w, "Favorites:\t%d\n", stats.Favorites)
+		fmt.Fprintf(w, "Sensitive:\t%d\n", stats.Sensitive)
+		fmt.Fprintf(w, "Registers:\t%d\n", stats.Registers)
+		if stats.Total > 0 {
+			fmt.Fprintf(w, "Oldest:\t%s\n", stats.Oldest.Local().Format("2006-01-02 15:04"))
+			fmt.Fprintf(w, "Newest:\t%s\n", stats.Newest.Local().Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+	})
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runPause pauses capture in the running instance, for a duration if given
+func runPause(args []string) int {
+	fs := flag.NewFlagSet("pause", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee pause [duration]    e.g. pastee pause 15m")
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) > 1 {
+		fs.Usage()
+		return 2
+	}
+	var d time.Duration
+	if len(rest) == 1 {
+		if d, err = time.ParseDuration(rest[0]); err != nil || d <= 0 {
+			fs.Usage()
+			return 2
+		}
+	}
+
+	return withHistory("pause", func(api historyAPI) error {
+		// Round up, as 0 seconds would pause until resumed
+		state, err := api.Pause(pauseParams{Seconds: int(math.Ceil(d.Seconds()))})
+		if err != nil {
+			return err
+		}
+		printPauseState(state)
+		return nil
+	})
+}
+
+// runResume ends a pause in the running instance
+func runResume(args []string) int {
+	return withHistory("resume", func(api historyAPI) error {
+		state, err := api.Pause(pauseParams{Resume: true})
+		if err != nil {
+			return err
+		}
+		printPauseState(state)
+		return nil
+	})
+}
+
+func printPauseState(state pauseJSON) {
+	switch {
+	case !state.Paused:
+		fmt.Println("Capture is running")
+	case state.Until.IsZero():
+		fmt.Println("Capture is paused until resumed")
+	default:
+		fmt.Printf("Capture is paused until %s\n", state.Until.Local().Format("15:04"))
+	}
+}
+
+// runShow brings up the window of the running app
+func runShow(args []string) int {
+	return withHistory("show", func(api historyAPI) error {
+		return api.Show()
+	})
+}
@@ -2,7 +2,18 @@
 
 package main
 
-import "golang.design/x/hotkey"
+import (
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+	"golang.design/x/hotkey"
+)
 
 // AltModifier represents the Alt/Option key on macOS
 const AltModifier = hotkey.ModOption
+
+// hotkeyModifiers are the modifier names accepted in hotkey settings on macOS
+var hotkeyModifiers = hotkeys.Modifiers{
+	{Name: "ctrl", Aliases: []string{"control"}, Mod: hotkeys.ModMask(hotkey.ModCtrl)},
+	{Name: "option", Aliases: []string{"alt", "opt"}, Mod: hotkeys.ModMask(AltModifier)},
+	{Name: "shift", Mod: hotkeys.ModMask(hotkey.ModShift)},
+	{Name: "cmd", Aliases: []string{"command", "super", "meta"}, Mod: hotkeys.ModMask(hotkey.ModCmd)},
+}
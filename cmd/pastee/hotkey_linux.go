@@ -2,7 +2,15 @@
 
 package main
 
-import "golang.design/x/hotkey"
+import "github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
 
 // AltModifier represents the Alt key on Linux (typically Mod1)
-const AltModifier = hotkey.Mod1
+const AltModifier = hotkeys.ModMask(1 << 3)
+
+// hotkeyModifiers are the modifier names accepted in hotkey settings, as X11 modifier masks
+var hotkeyModifiers = hotkeys.Modifiers{
+	{Name: "ctrl", Aliases: []string{"control"}, Mod: 1 << 2},
+	{Name: "alt", Aliases: []string{"mod1"}, Mod: AltModifier},
+	{Name: "shift", Mod: 1 << 0},
+	{Name: "super", Aliases: []string{"win", "meta", "mod4"}, Mod: 1 << 6},
+}
@@ -2,7 +2,18 @@
 
 package main
 
-import "golang.design/x/hotkey"
+import (
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+	"golang.design/x/hotkey"
+)
 
 // AltModifier represents the Alt key on Windows
 const AltModifier = hotkey.ModAlt
+
+// hotkeyModifiers are the modifier names accepted in hotkey settings on Windows
+var hotkeyModifiers = hotkeys.Modifiers{
+	{Name: "ctrl", Aliases: []string{"control"}, Mod: hotkeys.ModMask(hotkey.ModCtrl)},
+	{Name: "alt", Mod: hotkeys.ModMask(AltModifier)},
+	{Name: "shift", Mod: hotkeys.ModMask(hotkey.ModShift)},
+	{Name: "win", Aliases: []string{"super", "meta"}, Mod: hotkeys.ModMask(hotkey.ModWin)},
+}
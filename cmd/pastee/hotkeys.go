@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/gui"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+)
+
+// hotkeySpecs lists the configured combination of each action, in registration order
+func hotkeySpecs(cfg config.HotkeysConfig) []struct{ action, spec string } {
+	specs := []struct{ action, spec string }{
+		{hotkeys.ActionToggleWindow, cfg.ToggleWindow},
+		{hotkeys.ActionPauseCapture, cfg.PauseCapture},
+		{hotkeys.ActionPastePrevious, cfg.PastePrevious},
+	}
+	for _, name := range database.RegisterNames() {
+		if spec := cfg.Registers[name]; spec != "" {
+			specs = append(specs, struct{ action, spec string }{hotkeys.RegisterAction(name), spec})
+		}
+	}
+	return specs
+}
+
+// registerHotkeyActions returns one action per register that copies its item
+func registerHotkeyActions(pasteeApp *gui.PastyClipboard) map[string]func() {
+	actions := make(map[string]func())
+	for _, name := range database.RegisterNames() {
+		actions[hotkeys.RegisterAction(name)] = func() {
+			fyne.Do(func() { pasteeApp.CopyRegister(name) })
+		}
+	}
+	return actions
+}
+
+// bindHotkeys registers the configured hotkeys and tells the user about any that are taken
+func bindHotkeys(m *hotkeys.Manager, actions map[string]func(), pasteeApp *gui.PastyClipboard) {
+	var errs []error
+	for _, h := range hotkeySpecs(config.Get().Hotkeys) {
+		if err := m.Bind(h.action, h.spec, actions[h.action]); err != nil {
+			slog.Error("error registering hotkey", "action", h.action, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		if h.spec != "" {
+			slog.Info("Hotkey registered", "binding", m.Binding(h.action), "action", h.action)
+		}
+	}
+	if len(errs) > 0 {
+		pasteeApp.ShowHotkeyError(errors.Join(errs...))
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/httpapi"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// startHTTPAPI serves api on 127.0.0.1 when http_api is enabled in the config,
+// streaming new items to event subscribers. Call the returned function on exit.
+func startHTTPAPI(api historyAPI) func() {
+	cfg := config.Get().HTTPAPI
+	if !cfg.Enabled {
+		return func() {}
+	}
+
+	dataDir, err := database.DataDir()
+	if err != nil {
+		slog.Error("error locating data directory, HTTP API disabled", "err", err)
+		return func() {}
+	}
+	token, err := httpapi.LoadOrCreateToken(filepath.Join(dataDir, httpapi.TokenFileName))
+	if err != nil {
+		slog.Warn("HTTP API disabled", "err", err)
+		return func() {}
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.Port)))
+	if err != nil {
+		slog.Error("error starting HTTP API", "err", err)
+		return func() {}
+	}
+
+	server := httpapi.NewServer(httpBackend{api: api}, token)
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("HTTP API stopped", "err", err)
+		}
+	}()
+	removeListener := database.AddItemListener(database.ItemListener{
+		Added: func(item models.ClipboardItem) {
+			server.ItemAdded(httpapi.Item(toItemJSON(item, false)))
+		},
+	})
+	slog.Info("HTTP API listening", "url", "http://"+ln.Addr().String())
+
+	return func() {
+		removeListener()
+		server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Error("error stopping HTTP API", "err", err)
+		}
+	}
+}
+
+// httpBackend answers HTTP requests with the same historyAPI the CLI uses
+type httpBackend struct {
+	api historyAPI
+}
+
+// httpError marks unknown item IDs so they are answered with 404
+func httpError(err error) error {
+	if errors.Is(err, errNoItem) {
+		return httpapi.NotFound(err)
+	}
+	return err
+}
+
+func (b httpBackend) List(opts httpapi.ListOptions) ([]httpapi.Item, error) {
+	items, err := b.api.List(listParams(opts))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]httpapi.Item, len(items))
+	for i, item := range items {
+		out[i] = httpapi.Item(item)
+	}
+	return out, nil
+}
+
+func (b httpBackend) Get(id int) (httpapi.Item, error) {
+	item, err := b.api.Get(itemParams{ID: id})
+	return httpapi.Item(item), httpError(err)
+}
+
+func (b httpBackend) Add(item httpapi.NewItem) (httpapi.Item, error) {
+	stored, err := b.api.Add(addParams(item))
+	return httpapi.Item(stored), err
+}
+
+func (b httpBackend) Delete(id int) error {
+	return httpError(b.api.Delete(idsParams{IDs: []int{id}}))
+}
+
+func (b httpBackend) SetFavorite(id int, favorite bool) error {
+	return httpError(b.api.Favorite(favoriteParams{IDs: []int{id}, Off: !favorite}))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/ipc"
+)
+
+// ipcAddress is where the running instance listens for CLI commands
+func ipcAddress() (string, error) {
+	dataDir, err := database.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return ipc.DefaultAddress(dataDir), nil
+}
+
+// listenInstance claims the IPC address for this process. It returns
+// ipc.ErrAlreadyRunning when another instance holds it.
+func listenInstance() (net.Listener, error) {
+	addr, err := ipcAddress()
+	if err != nil {
+		return nil, err
+	}
+	return ipc.Listen(addr)
+}
+
+// startInstanceServer serves api on ln in the background. Close the returned
+// server on exit.
+func startInstanceServer(ln net.Listener, name string, api historyAPI) *ipc.Server {
+	server := ipc.NewServer(name)
+	serveHistory(server, api)
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			slog.Warn("IPC server stopped", "err", err)
+		}
+	}()
+	return server
+}
+
+// connectHistory talks to the running instance if there is one, so only one
+// process writes to the database; otherwise it opens the database directly.
+// Call the returned function when done.
+func connectHistory() (historyAPI, func(), error) {
+	addr, err := ipcAddress()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := ipc.Dial(addr)
+	if err == nil {
+		return remoteHistory{c: client}, func() { client.Close() }, nil
+	}
+	if !errors.Is(err, ipc.ErrNotRunning) {
+		return nil, nil, fmt.Errorf("cannot talk to the running instance: %w", err)
+	}
+
+	if _, _, err := database.InitDB(); err != nil {
+		return nil, nil, err
+	}
+	local := &localHistory{copy: copyDetached}
+	return local, func() { database.CloseDB() }, nil
+}
+
+// forwardShow asks the running instance to show its window
+func forwardShow() error {
+	addr, err := ipcAddress()
+	if err != nil {
+		return err
+	}
+	client, err := ipc.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return remoteHistory{c: client}.Show()
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/lanshare"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+func init() {
+	subcommands["pair"] = runPair
+	subcommands["peers"] = runPeers
+	subcommands["unpair"] = runUnpair
+}
+
+// errLANDisabled is returned by the pairing commands when LAN sharing is off
+var errLANDisabled = errors.New("LAN sharing is off; enable lan_share in config.json and restart pastee")
+
+// lanShare is the running LAN sharing node
+type lanShare struct {
+	node *lanshare.Node
+	name string
+}
+
+type pairParams struct {
+	Code string `json:"code,omitempty"` // Pair with the device that showed this code; empty to show one
+	Host string `json:"host,omitempty"` // Address put in a new code; detected when empty
+}
+
+type pairJSON struct {
+	Code    string          `json:"code,omitempty"`
+	Expires time.Time       `json:"expires,omitzero"`
+	Peer    *config.LANPeer `json:"peer,omitempty"` // The device paired with
+}
+
+type peersJSON struct {
+	Name        string           `json:"name"`
+	Fingerprint string           `json:"fingerprint"`
+	Peers       []config.LANPeer `json:"peers"`
+}
+
+type unpairParams struct {
+	Peer string `json:"peer"` // Name or fingerprint
+}
+
+// startLANShare listens for paired devices when lan_share is enabled in the
+// config and pushes new items to them. It must run before api is served, as
+// it sets api.lan. Call the returned function on exit.
+func startLANShare(api *localHistory) func() {
+	cfg := config.Get().LANShare
+	if !cfg.Enabled {
+		return func() {}
+	}
+
+	dataDir, err := database.DataDir()
+	if err != nil {
+		slog.Error("error locating data directory, LAN sharing disabled", "err", err)
+		return func() {}
+	}
+	identity, err := lanshare.LoadOrCreateIdentity(filepath.Join(dataDir, lanshare.IdentityFileName))
+	if err != nil {
+		slog.Warn("LAN sharing disabled", "err", err)
+		return func() {}
+	}
+	name := cfg.Name
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(cfg.Port)))
+	if err != nil {
+		slog.Error("error starting LAN sharing", "err", err)
+		return func() {}
+	}
+
+	node, err := lanshare.NewNode(lanshare.Options{
+		Name:     name,
+		Identity: identity,
+		Port:     cfg.Port,
+		Peers:    lanPeers,
+		AddPeer:  saveLANPeer,
+		OnItem: func(from lanshare.Peer, item lanshare.Item) {
+			receiveLANItem(api, from, item)
+		},
+	})
+	if err != nil {
+		ln.Close()
+		slog.Warn("LAN sharing disabled", "err", err)
+		return func() {}
+	}
+	go func() {
+		if err := node.Serve(ln); err != nil {
+			slog.Warn("LAN sharing stopped", "err", err)
+		}
+	}()
+	removeListener := database.AddItemListener(database.ItemListener{
+		Added: func(item models.ClipboardItem) { pushLANItem(node, item) },
+	})
+	api.lan = &lanShare{node: node, name: name}
+	slog.Info("LAN sharing started", "addr", ln.Addr(), "name", name)
+
+	return func() {
+		removeListener()
+		node.Close()
+	}
+}
+
+func lanPeers() []lanshare.Peer {
+	peers := config.Get().LANShare.Peers
+	out := make([]lanshare.Peer, len(peers))
+	for i, p := range peers {
+		out[i] = lanshare.Peer(p)
+	}
+	return out
+}
+
+// saveLANPeer adds p to the config, replacing an earlier pairing with the same device
+func saveLANPeer(p lanshare.Peer) error {
+	return config.Update(func(c *config.Config) {
+		var peers []config.LANPeer
+		for _, existing := range c.LANShare.Peers {
+			if existing.Fingerprint != p.Fingerprint {
+				peers = append(peers, existing)
+			}
+		}
+		c.LANShare.Peers = append(peers, config.LANPeer(p))
+	})
+}
+
+// pushLANItem sends a new item to the paired devices in the background.
+// Items received from a device are never sent on.
+func pushLANItem(node *lanshare.Node, item models.ClipboardItem) {
+	if item.Source == models.SourceLAN {
+		return
+	}
+	go func() {
+		shared := lanshare.Item{Type: item.Type, Content: item.Content, Sensitive: item.IsSensitive}
+		if item.Type == monitor.TypeImage {
+			data, err := os.ReadFile(item.ImagePath)
+			if err != nil {
+				slog.Error("LAN share: error reading image", "err", err)
+				return
+			}
+			shared.Content, shared.Image = "", data
+		}
+		for _, err := range node.Push(shared) {
+			slog.Error("LAN share: error pushing item", "err", err)
+		}
+	}()
+}
+
+// receiveLANItem stores an item pushed by a paired device and, if configured,
+// puts it on the clipboard
+func receiveLANItem(api *localHistory, from lanshare.Peer, item lanshare.Item) {
+	var stored models.ClipboardItem
+	var err error
+	if item.Type == monitor.TypeImage {
+		stored, _, err = monitor.StoreImage(item.Image, models.SourceLAN)
+	} else {
+		stored, _, err = monitor.StoreText(models.ClipboardItem{
+			Content:     item.Content,
+			Source:      models.SourceLAN,
+			IsSensitive: item.Sensitive,
+		})
+	}
+	if err != nil {
+		slog.Error("LAN share: error storing item", "from", from.Name, "err", err)
+		return
+	}
+	slog.Info("LAN share: received item", "type", stored.Type, "from", from.Name)
+
+	if config.Get().LANShare.SetClipboard && api.copy != nil {
+		if err := api.copy(stored, false); err != nil {
+			slog.Error("LAN share: error copying received item", "err", err)
+		}
+	}
+	api.notifyChanged()
+}
+
+// lanAddress guesses the address other devices on the network reach this one at
+func lanAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && ipNet.IP.IsPrivate() {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+func (l *localHistory) Pair(p pairParams) (pairJSON, error) {
+	if !l.running {
+		return pairJSON{}, errNeedsInstance
+	}
+	if l.lan == nil {
+		return pairJSON{}, errLANDisabled
+	}
+	if p.Code != "" {
+		peer, err := l.lan.node.Pair(p.Code)
+		if err != nil {
+			return pairJSON{}, err
+		}
+		paired := config.LANPeer(peer)
+		return pairJSON{Peer: &paired}, nil
+	}
+
+	host := p.Host
+	if host == "" {
+		host = lanAddress()
+	}
+	code, err := l.lan.node.StartPairing(host)
+	return pairJSON{Code: code, Expires: time.Now().Add(lanshare.PairingTimeout)}, err
+}
+
+func (l *localHistory) Peers() (peersJSON, error) {
+	if !l.running {
+		return peersJSON{}, errNeedsInstance
+	}
+	if l.lan == nil {
+		return peersJSON{}, errLANDisabled
+	}
+	return peersJSON{
+		Name:        l.lan.name,
+		Fingerprint: l.lan.node.Fingerprint(),
+		Peers:       config.Get().LANShare.Peers,
+	}, nil
+}
+
+func (l *localHistory) Unpair(p unpairParams) error {
+	if !l.running {
+		return errNeedsInstance
+	}
+	if l.lan == nil {
+		return errLANDisabled
+	}
+	var kept []config.LANPeer
+	for _, peer := range config.Get().LANShare.Peers {
+		if peer.Name != p.Peer && peer.Fingerprint != p.Peer {
+			kept = append(kept, peer)
+		}
+	}
+	if len(kept) == len(config.Get().LANShare.Peers) {
+		return fmt.Errorf("no paired device named %q", p.Peer)
+	}
+	return config.Update(func(c *config.Config) { c.LANShare.Peers = kept })
+}
+
+// runPair shows a pairing code, or pairs with the device that showed one
+func runPair(args []string) int {
+	fs := flag.NewFlagSet("pair", flag.ContinueOnError)
+	host := fs.String("host", "", "address the other device reaches this one at (default: detected)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee pair [-host address]    show a pairing code")
+		fmt.Fprintln(fs.Output(), "       pastee pair <code>             pair with the device showing code")
+		fs.PrintDefaults()
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) > 1 {
+		fs.Usage()
+		return 2
+	}
+	p := pairParams{Host: *host}
+	if len(rest) == 1 {
+		p.Code = rest[0]
+	}
+
+	return withHistory("pair", func(api historyAPI) error {
+		result, err := api.Pair(p)
+		if err != nil {
+			return err
+		}
+		if result.Peer != nil {
+			fmt.Printf("Paired with %s (%s)\n", result.Peer.Name, result.Peer.Address)
+			return nil
+		}
+		fmt.Printf("On the other device, run this before %s:\n\n", result.Expires.Local().Format("15:04"))
+		fmt.Printf("  pastee pair %s\n", result.Code)
+		return nil
+	})
+}
+
+// runPeers lists the paired devices
+func runPeers(args []string) int {
+	fs := flag.NewFlagSet("peers", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee peers [-json]")
+		fs.PrintDefaults()
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+
+	return withHistory("peers", func(api historyAPI) error {
+		peers, err := api.Peers()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(peers)
+		}
+
+		fmt.Printf("This device: %s (%s)\n", peers.Name, peers.Fingerprint)
+		if len(peers.Peers) == 0 {
+			fmt.Println("No paired devices; pair one with pastee pair")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nNAME\tADDRESS\tSEND\tRECEIVE\tFILTERS")
+		for _, p := range peers.Peers {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Address, yesNo(p.Send), yesNo(p.Receive), peerFilters(p))
+		}
+		return w.Flush()
+	})
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func peerFilters(p config.LANPeer) string {
+	var filters []string
+	for _, t := range p.BlockTypes {
+		filters = append(filters, "no "+t)
+	}
+	if !p.ShareSensitive {
+		filters = append(filters, "no sensitive")
+	}
+	if len(filters) == 0 {
+		return "-"
+	}
+	return strings.Join(filters, ", ")
+}
+
+// runUnpair forgets a paired device
+func runUnpair(args []string) int {
+	fs := flag.NewFlagSet("unpair", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pastee unpair <name or fingerprint>")
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	return withHistory("unpair", func(api historyAPI) error {
+		return api.Unpair(unpairParams{Peer: rest[0]})
+	})
+}
@@ -2,88 +2,217 @@ package main
 
 import (
 	_ "embed"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/driver/desktop"
 
+	"github.com/Sirpyerre/pasteeclipboard/internal/autopaste"
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
 	"github.com/Sirpyerre/pasteeclipboard/internal/gui"
-	"golang.design/x/hotkey"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hooks"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+	"github.com/Sirpyerre/pasteeclipboard/internal/ipc"
+	"github.com/Sirpyerre/pasteeclipboard/internal/logging"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/scripting"
 )
 
 //go:embed assets/pastee32x32nobackground.png
 var iconData []byte
 
+var startPaused = flag.Bool("paused", false, "start with clipboard capture paused until resumed")
+
 func main() {
+	loadConfig()
+	if code, ok := runSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	flag.Parse()
+
+	// A second launch shows the running instance's window instead of starting another monitor
+	instanceListener, err := listenInstance()
+	if errors.Is(err, ipc.ErrAlreadyRunning) {
+		if err := forwardShow(); err != nil {
+			slog.Error("Pastee is already running but could not be shown", "err", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	stopLogging := startLogging()
+	defer stopLogging()
+	if err != nil {
+		slog.Warn("error starting IPC server, CLI commands will open the database directly", "err", err)
+	}
+
+	if *startPaused {
+		monitor.Pause(0)
+	}
+
+	stopScript := scripting.Start()
+
 	a := app.NewWithID("pastee.clipboard")
 	icon := fyne.NewStaticResource("icon.png", iconData)
 	pasteeApp := gui.NewPastyClipboard(a, icon)
 
-	var isWindowVisible bool
-
-	// register global shortcut (cross-platform: Ctrl+Alt+P)
-	hk := hotkey.New([]hotkey.Modifier{hotkey.ModCtrl, AltModifier}, hotkey.KeyP)
+	// showWithoutPasteTarget shows the window from the tray, the CLI or D-Bus;
+	// items chosen there are not pasted into whatever had focus before
+	showWithoutPasteTarget := func() {
+		autopaste.Forget()
+		pasteeApp.ShowWindow()
+	}
 
-	go func() {
-		log.Println("--- Adding shortcut. Press CTRL+ALT+P to show window. ---")
-		err := hk.Register()
-		if err != nil {
-			log.Println("Error registering shortcut:", err)
-			return
-		}
+	toggleWindow := func() {
+		fyne.Do(func() {
+			if pasteeApp.WindowVisible() {
+				pasteeApp.HideWindow()
+				return
+			}
+			// Remember the focused window before Pastee takes focus, so a chosen item can be pasted back into it
+			if !autopaste.Enabled() {
+				showWithoutPasteTarget()
+				return
+			}
+			if err := autopaste.RememberActiveWindow(); err != nil {
+				slog.Warn("Could not remember active window for auto-paste", "err", err)
+			}
+			pasteeApp.ShowWindow()
+		})
+	}
 
-		for range hk.Keydown() {
-			fyne.Do(func() {
-				if !isWindowVisible {
-					pasteeApp.Win.Show()
-					isWindowVisible = true
-					pasteeApp.Win.RequestFocus()
+	hotkeyActions := map[string]func(){
+		hotkeys.ActionToggleWindow: toggleWindow,
+		hotkeys.ActionPauseCapture: togglePause,
+		hotkeys.ActionPastePrevious: func() {
+			fyne.Do(pasteeApp.PastePrevious)
+		},
+	}
+	for action, fn := range registerHotkeyActions(pasteeApp) {
+		hotkeyActions[action] = fn
+	}
+	hotkeyManager := hotkeys.NewManager(hotkeyModifiers)
+	pasteeApp.SetHotkeyBinder(func(action, spec string) error {
+		return hotkeyManager.Bind(action, spec, hotkeyActions[action])
+	})
+	go bindHotkeys(hotkeyManager, hotkeyActions, pasteeApp)
+
+	api := &localHistory{
+		copy: func(item models.ClipboardItem, transformed bool) error {
+			var err error
+			fyne.DoAndWait(func() {
+				if transformed {
+					pasteeApp.CopyText(item.Content, item.IsSensitive)
 				} else {
-					pasteeApp.Win.Hide()
-					isWindowVisible = false
+					err = pasteeApp.CopyItem(item)
 				}
 			})
-		}
-
-	}()
+			return err
+		},
+		changed: func() { fyne.Do(pasteeApp.ReloadHistory) },
+		show: func() error {
+			fyne.Do(showWithoutPasteTarget)
+			return nil
+		},
+		running: true,
+	}
+	stopLAN := startLANShare(api)
+	var instanceServer *ipc.Server
+	if instanceListener != nil {
+		instanceServer = startInstanceServer(instanceListener, "gui", api)
+	}
+	stopDBus := startDBusService(api)
+	stopHTTP := startHTTPAPI(api)
+	stopSync := startSync(api)
+	stopHooks := hooks.Start()
 
 	if desk, ok := a.(desktop.App); ok {
 		showHideItem := fyne.NewMenuItem("Show/Hide", func() {
-			if isWindowVisible {
-				pasteeApp.Win.Hide()
-				isWindowVisible = false
+			if pasteeApp.WindowVisible() {
+				pasteeApp.HideWindow()
 			} else {
-				pasteeApp.Win.Show()
-				pasteeApp.Win.RequestFocus()
-				isWindowVisible = true
+				showWithoutPasteTarget()
 			}
 		})
 
 		quitItem := fyne.NewMenuItem("Quit", func() {
-			log.Println("Exiting...")
+			slog.Info("Exiting")
 			pasteeApp.App.Quit()
 			a.Quit()
 		})
 
-		menu := fyne.NewMenu("Pastee Clipboard", showHideItem, fyne.NewMenuItemSeparator(), quitItem)
+		hotkeysItem := fyne.NewMenuItem("Hotkeys…", func() {
+			showWithoutPasteTarget()
+			pasteeApp.ShowHotkeySettings()
+		})
+
+		pauseControls := newTrayPauseControls()
+		var menu *fyne.Menu
+		dndItem := newDoNotDisturbItem(func() *fyne.Menu { return menu })
+
+		menuItems := []*fyne.MenuItem{showHideItem, hotkeysItem, fyne.NewMenuItemSeparator()}
+		menuItems = append(menuItems, pauseControls.items()...)
+		menuItems = append(menuItems, dndItem, fyne.NewMenuItemSeparator(), quitItem)
+		menu = fyne.NewMenu("Pastee Clipboard", menuItems...)
 
 		icon := fyne.NewStaticResource("icon.png", iconData)
 		desk.SetSystemTrayIcon(icon)
 		desk.SetSystemTrayMenu(menu)
+		pauseControls.bind(desk, menu, icon)
+		bindClearCountdown(menu)
 	}
 
 	pasteeApp.Win.Resize(fyne.NewSize(400, 600))
-	pasteeApp.Win.SetCloseIntercept(func() {
-		pasteeApp.Win.Hide()
-		isWindowVisible = false
-	})
+	pasteeApp.Win.SetCloseIntercept(pasteeApp.HideWindow)
 
 	// default hide window
-	pasteeApp.Win.Hide()
-	isWindowVisible = false
+	pasteeApp.HideWindow()
 
 	pasteeApp.App.Run()
 
-	log.Println("Finished running Pastee Clipboard")
+	monitor.ClearNow() // Don't leave a copied secret behind
+	hotkeyManager.UnbindAll()
+	if instanceServer != nil {
+		instanceServer.Close()
+	}
+	stopDBus()
+	stopHTTP()
+	stopSync()
+	stopLAN()
+	stopHooks()
+	stopScript()
+	slog.Info("Finished running Pastee Clipboard")
+}
+
+// startLogging sends log output to stderr and the log file in the data directory
+func startLogging() func() {
+	var path string
+	if dataDir, err := database.DataDir(); err == nil && os.MkdirAll(dataDir, os.ModePerm) == nil {
+		path = filepath.Join(dataDir, logging.FileName)
+	}
+	stop, err := logging.Start(os.Stderr, path)
+	if err != nil {
+		slog.Warn("logging to stderr only", "err", err)
+		stop, _ = logging.Start(os.Stderr, "")
+	}
+	return stop
+}
+
+// loadConfig reads config.json from the data directory, falling back to defaults
+func loadConfig() {
+	dataDir, err := database.DataDir()
+	if err != nil {
+		slog.Error("error locating data directory", "err", err)
+		return
+	}
+	if _, err := config.Load(filepath.Join(dataDir, config.FileName)); err != nil {
+		slog.Error("error loading config, using defaults", "err", err)
+	}
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/notify"
+)
+
+// newDoNotDisturbItem returns a tray menu entry that turns notifications off
+// and on, checked while they are off. The choice is saved to the config.
+func newDoNotDisturbItem(menu func() *fyne.Menu) *fyne.MenuItem {
+	item := fyne.NewMenuItem("Do Not Disturb", nil)
+	item.Checked = config.Get().Notifications.DoNotDisturb
+	item.Action = func() {
+		on := !config.Get().Notifications.DoNotDisturb
+		if err := notify.SetDoNotDisturb(on); err != nil {
+			slog.Error("error saving do not disturb", "err", err)
+			return
+		}
+		item.Checked = on
+		menu().Refresh()
+	}
+	return item
+}
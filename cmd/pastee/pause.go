@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/gui"
+	"github.com/Sirpyerre/pasteeclipboard/internal/imageutil"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+// pauseDurations are the timed options offered in the tray "Pause Capture" submenu
+var pauseDurations = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"For 5 minutes", 5 * time.Minute},
+	{"For 15 minutes", 15 * time.Minute},
+	{"For 1 hour", time.Hour},
+	{"Until resumed", 0},
+}
+
+// trayPauseControls keeps the tray menu and icon in sync with the monitor pause state
+type trayPauseControls struct {
+	status *fyne.MenuItem
+	pause  *fyne.MenuItem
+	resume *fyne.MenuItem
+}
+
+func newTrayPauseControls() *trayPauseControls {
+	c := &trayPauseControls{}
+
+	c.status = fyne.NewMenuItem(gui.PauseStatusText(monitor.CurrentPauseState()), nil)
+	c.status.Disabled = true
+
+	var options []*fyne.MenuItem
+	for _, opt := range pauseDurations {
+		options = append(options, fyne.NewMenuItem(opt.label, func() {
+			monitor.Pause(opt.duration)
+		}))
+	}
+	c.pause = fyne.NewMenuItem("Pause Capture", nil)
+	c.pause.ChildMenu = fyne.NewMenu("", options...)
+
+	c.resume = fyne.NewMenuItem("Resume Capture", func() {
+		monitor.Resume()
+	})
+
+	return c
+}
+
+func (c *trayPauseControls) items() []*fyne.MenuItem {
+	return []*fyne.MenuItem{c.status, c.pause, c.resume}
+}
+
+// bind refreshes the tray whenever capture is paused or resumed
+func (c *trayPauseControls) bind(desk desktop.App, menu *fyne.Menu, icon fyne.Resource) {
+	pausedIcon := icon
+	if data, err := imageutil.GrayscalePNG(icon.Content()); err == nil {
+		pausedIcon = fyne.NewStaticResource("icon-paused.png", data)
+	} else {
+		slog.Error("error creating paused tray icon", "err", err)
+	}
+
+	update := func(state monitor.PauseState) {
+		c.status.Label = gui.PauseStatusText(state)
+		c.resume.Disabled = !state.Paused
+		if state.Paused {
+			desk.SetSystemTrayIcon(pausedIcon)
+		} else {
+			desk.SetSystemTrayIcon(icon)
+		}
+		menu.Refresh()
+	}
+
+	update(monitor.CurrentPauseState())
+	monitor.OnPauseChange(func(state monitor.PauseState) {
+		fyne.Do(func() {
+			update(state)
+		})
+	})
+}
+
+// togglePause pauses capture until resumed, or resumes it if already paused
+func togglePause() {
+	if monitor.IsPaused() {
+		slog.Info("Resuming clipboard capture")
+		monitor.Resume()
+	} else {
+		slog.Info("Pausing clipboard capture until resumed")
+		monitor.Pause(0)
+	}
+}
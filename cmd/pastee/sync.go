@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/foldersync"
+	"github.com/Sirpyerre/pasteeclipboard/internal/keystore"
+)
+
+// startSync syncs the history through the shared folder when sync is enabled
+// in the config, reloading api's views after other devices' changes. Call the
+// returned function on exit.
+func startSync(api *localHistory) func() {
+	cfg := config.Get().Sync
+	if !cfg.Enabled {
+		return func() {}
+	}
+
+	dataDir, err := database.DataDir()
+	if err != nil {
+		slog.Error("error locating data directory, sync disabled", "err", err)
+		return func() {}
+	}
+	passphrase, err := syncPassphrase(cfg)
+	if err != nil {
+		slog.Warn("Sync disabled", "err", err)
+		return func() {}
+	}
+	syncer, err := foldersync.New(foldersync.DatabaseStore{}, foldersync.Options{
+		Folder:        cfg.Folder,
+		Passphrase:    passphrase,
+		StatePath:     filepath.Join(dataDir, foldersync.StateFileName),
+		FavoritesOnly: cfg.FavoritesOnly,
+		OnApplied:     api.notifyChanged,
+	})
+	if err != nil {
+		slog.Warn("Sync disabled", "err", err)
+		return func() {}
+	}
+
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	removeListener := database.AddItemListener(syncer.Listener())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		syncer.Run(ctx, interval)
+	}()
+	slog.Info("Syncing through a shared folder", "folder", cfg.Folder, "device", syncer.DeviceID())
+
+	return func() {
+		removeListener()
+		cancel()
+		<-done
+	}
+}
+
+// syncPassphrase returns the sync passphrase from the system keychain. A
+// passphrase written into config.json is moved to the keychain first, so the
+// key to the shared folder is not left in a plain file.
+func syncPassphrase(cfg config.SyncConfig) (string, error) {
+	store := keystore.NewSyncPassphraseStore()
+	if cfg.Passphrase != "" {
+		if err := store.Set([]byte(cfg.Passphrase)); err != nil {
+			return "", fmt.Errorf("error storing the sync passphrase in the keychain: %w", err)
+		}
+		err := config.Update(func(c *config.Config) {
+			c.Sync.Passphrase = ""
+			c.Sync.KeychainPassphrase = true
+		})
+		if err != nil {
+			slog.Error("error removing the sync passphrase from the config", "err", err)
+		} else {
+			slog.Info("Moved the sync passphrase from the config to the system keychain")
+		}
+		return cfg.Passphrase, nil
+	}
+
+	if !cfg.KeychainPassphrase {
+		return "", errors.New("no sync passphrase set")
+	}
+	passphrase, err := store.Get()
+	if errors.Is(err, keystore.ErrKeyNotFound) {
+		return "", errors.New("the sync passphrase is missing from the keychain; set it in the config again")
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading the sync passphrase from the keychain: %w", err)
+	}
+	return string(passphrase), nil
+}
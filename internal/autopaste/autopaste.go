@@ -0,0 +1,112 @@
+package autopaste
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+// ErrUnsupported is returned on platforms without auto-paste support
+var ErrUnsupported = errors.New("auto-paste is not supported on this platform")
+
+// Window identifies a window (or, on macOS, an application process) that can be refocused
+type Window uint64
+
+// Platform performs the OS-specific parts of auto-paste
+type Platform interface {
+	ActiveWindow() (Window, error)
+	Activate(w Window) error
+	SendPaste() error // Synthesizes the platform's paste keystroke in the focused window
+}
+
+var (
+	mu       sync.Mutex
+	platform Platform = newPlatform()
+	previous Window
+	hasPrev  bool
+)
+
+// SetPlatform replaces the platform hooks, e.g. with a fake in tests
+func SetPlatform(p Platform) {
+	mu.Lock()
+	defer mu.Unlock()
+	platform = p
+	hasPrev = false
+}
+
+// Enabled reports whether auto-paste is turned on in the config
+func Enabled() bool {
+	return config.Get().AutoPaste.Enabled
+}
+
+// RememberActiveWindow records the focused window so PasteIntoPrevious can
+// return to it. Call it before Pastee's window is shown.
+func RememberActiveWindow() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	w, err := platform.ActiveWindow()
+	if err != nil {
+		hasPrev = false
+		return err
+	}
+	previous = w
+	hasPrev = true
+	return nil
+}
+
+// Forget drops the remembered window. Call it when Pastee's window is shown
+// some other way than RememberActiveWindow expects, so a chosen item is not
+// pasted into a window the user has long left.
+func Forget() {
+	mu.Lock()
+	defer mu.Unlock()
+	hasPrev = false
+}
+
+// Remembered reports whether a window is waiting to be pasted into
+func Remembered() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return hasPrev
+}
+
+// PasteIntoPrevious refocuses the remembered window and pastes the clipboard into it
+func PasteIntoPrevious() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !hasPrev {
+		return errors.New("no previously focused window")
+	}
+	// A remembered window is pasted into once; the next paste needs a new one
+	hasPrev = false
+	if err := platform.Activate(previous); err != nil {
+		return fmt.Errorf("failed to restore focus: %w", err)
+	}
+
+	// Give the window manager a moment to hand focus over before typing
+	time.Sleep(time.Duration(config.Get().AutoPaste.DelayMs) * time.Millisecond)
+
+	if err := platform.SendPaste(); err != nil {
+		return fmt.Errorf("failed to send paste keystroke: %w", err)
+	}
+	return nil
+}
+
+// PasteIntoActive pastes the clipboard into whichever window has focus
+func PasteIntoActive() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Let the user release the hotkey first, so its modifiers do not combine with the paste
+	time.Sleep(time.Duration(config.Get().AutoPaste.DelayMs) * time.Millisecond)
+
+	if err := platform.SendPaste(); err != nil {
+		return fmt.Errorf("failed to send paste keystroke: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build darwin
+
+package autopaste
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// macPlatform drives System Events through osascript. The first use prompts
+// for the Accessibility permission that synthesized keystrokes require.
+type macPlatform struct{}
+
+func newPlatform() Platform {
+	return macPlatform{}
+}
+
+// ActiveWindow returns the process ID of the frontmost application
+func (macPlatform) ActiveWindow() (Window, error) {
+	out, err := osascript(`tell application "System Events" to get unix id of first application process whose frontmost is true`)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.ParseUint(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected frontmost process id %q", out)
+	}
+	return Window(pid), nil
+}
+
+func (macPlatform) Activate(w Window) error {
+	_, err := osascript(fmt.Sprintf(`tell application "System Events" to set frontmost of first application process whose unix id is %d to true`, w))
+	return err
+}
+
+func (macPlatform) SendPaste() error {
+	_, err := osascript(`tell application "System Events" to keystroke "v" using command down`)
+	return err
+}
+
+func osascript(script string) (string, error) {
+	out, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		return "", fmt.Errorf("osascript failed: %w", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,147 @@
+//go:build linux
+
+package autopaste
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/x11"
+)
+
+// X keysyms for the paste keystroke
+const (
+	keysymControlL xproto.Keysym = 0xffe3
+	keysymV        xproto.Keysym = 0x0076
+)
+
+// x11Platform restores focus through EWMH and types Ctrl+V with the XTest extension
+type x11Platform struct {
+	mu   sync.Mutex
+	conn *xgb.Conn
+}
+
+func newPlatform() Platform {
+	return &x11Platform{}
+}
+
+// connection opens the X connection on first use, so nothing is done
+// unless auto-paste is actually used
+func (p *x11Platform) connection() (*xgb.Conn, xproto.Window, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		conn, err := xgb.NewConn()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to connect to X server: %w", err)
+		}
+		if err := xtest.Init(conn); err != nil {
+			conn.Close()
+			return nil, 0, fmt.Errorf("XTest extension unavailable: %w", err)
+		}
+		p.conn = conn
+	}
+	return p.conn, xproto.Setup(p.conn).DefaultScreen(p.conn).Root, nil
+}
+
+// ActiveWindow prefers the window manager's _NET_ACTIVE_WINDOW and falls
+// back to the X input focus when no EWMH window manager is running
+func (p *x11Platform) ActiveWindow() (Window, error) {
+	conn, root, err := p.connection()
+	if err != nil {
+		return 0, err
+	}
+
+	if atom, err := internAtom(conn, "_NET_ACTIVE_WINDOW"); err == nil {
+		reply, err := xproto.GetProperty(conn, false, root, atom, xproto.AtomWindow, 0, 1).Reply()
+		if err == nil && reply.ValueLen > 0 {
+			if w := xgb.Get32(reply.Value); w != 0 {
+				return Window(w), nil
+			}
+		}
+	}
+
+	focus, err := xproto.GetInputFocus(conn).Reply()
+	if err != nil {
+		return 0, err
+	}
+	if focus.Focus == xproto.WindowNone || focus.Focus == root || focus.Focus == xproto.InputFocusPointerRoot {
+		return 0, errors.New("no window has focus")
+	}
+	return Window(focus.Focus), nil
+}
+
+// Activate asks the window manager to raise and focus w, then sets the
+// input focus directly for sessions without a window manager
+func (p *x11Platform) Activate(w Window) error {
+	conn, root, err := p.connection()
+	if err != nil {
+		return err
+	}
+
+	if atom, err := internAtom(conn, "_NET_ACTIVE_WINDOW"); err == nil {
+		ev := xproto.ClientMessageEvent{
+			Format: 32,
+			Window: xproto.Window(w),
+			Type:   atom,
+			// Source indication 2 marks the request as coming from a pager-like tool
+			Data: xproto.ClientMessageDataUnionData32New([]uint32{2, xproto.TimeCurrentTime, 0, 0, 0}),
+		}
+		mask := uint32(xproto.EventMaskSubstructureNotify | xproto.EventMaskSubstructureRedirect)
+		if err := xproto.SendEventChecked(conn, false, root, mask, string(ev.Bytes())).Check(); err != nil {
+			return err
+		}
+	}
+
+	return xproto.SetInputFocusChecked(conn, xproto.InputFocusParent, xproto.Window(w), xproto.TimeCurrentTime).Check()
+}
+
+// SendPaste presses and releases Ctrl+V through XTest
+func (p *x11Platform) SendPaste() error {
+	conn, root, err := p.connection()
+	if err != nil {
+		return err
+	}
+
+	ctrl, err := x11.KeycodeFor(conn, keysymControlL)
+	if err != nil {
+		return err
+	}
+	v, err := x11.KeycodeFor(conn, keysymV)
+	if err != nil {
+		return err
+	}
+
+	events := []struct {
+		typ  byte
+		code xproto.Keycode
+	}{
+		{xproto.KeyPress, ctrl},
+		{xproto.KeyPress, v},
+		{xproto.KeyRelease, v},
+		{xproto.KeyRelease, ctrl},
+	}
+	for _, e := range events {
+		if err := xtest.FakeInputChecked(conn, e.typ, byte(e.code), 0, root, 0, 0, 0).Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func internAtom(conn *xgb.Conn, name string) (xproto.Atom, error) {
+	reply, err := xproto.InternAtom(conn, true, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, err
+	}
+	if reply.Atom == xproto.AtomNone {
+		return 0, fmt.Errorf("atom %s does not exist", name)
+	}
+	return reply.Atom, nil
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package autopaste
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/x11"
+)
+
+// TestX11_PasteReachesFocusedWindow needs an X server, e.g.
+// "xvfb-run go test ./internal/autopaste"
+func TestX11_PasteReachesFocusedWindow(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set; run under Xvfb")
+	}
+
+	conn, err := xgb.NewConn()
+	if err != nil {
+		t.Skipf("cannot connect to X server: %v", err)
+	}
+	defer conn.Close()
+
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = xproto.CreateWindowChecked(conn, screen.RootDepth, win, screen.Root, 0, 0, 100, 100, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual,
+		xproto.CwEventMask, []uint32{xproto.EventMaskKeyPress | xproto.EventMaskStructureNotify}).Check()
+	if err != nil {
+		t.Fatalf("CreateWindow failed: %v", err)
+	}
+	if err := xproto.MapWindowChecked(conn, win).Check(); err != nil {
+		t.Fatalf("MapWindow failed: %v", err)
+	}
+	waitForEvent(t, conn, func(ev xgb.Event) bool {
+		_, ok := ev.(xproto.MapNotifyEvent)
+		return ok
+	})
+
+	p := &x11Platform{}
+	if err := p.Activate(Window(win)); err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+	active, err := p.ActiveWindow()
+	if err != nil {
+		t.Fatalf("ActiveWindow failed: %v", err)
+	}
+	if active != Window(win) {
+		t.Fatalf("ActiveWindow = %#x, want %#x", active, win)
+	}
+
+	if err := p.SendPaste(); err != nil {
+		t.Fatalf("SendPaste failed: %v", err)
+	}
+	vCode, err := x11.KeycodeFor(conn, keysymV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForEvent(t, conn, func(ev xgb.Event) bool {
+		key, ok := ev.(xproto.KeyPressEvent)
+		return ok && key.Detail == vCode && key.State&xproto.ModMaskControl != 0
+	})
+}
+
+func waitForEvent(t *testing.T, conn *xgb.Conn, match func(xgb.Event) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		ev, err := conn.PollForEvent()
+		if err != nil {
+			t.Fatalf("X error: %v", err)
+		}
+		if ev == nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if match(ev) {
+			return
+		}
+	}
+	t.Fatal("timed out waiting for X event")
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package autopaste
+
+type unsupportedPlatform struct{}
+
+func newPlatform() Platform {
+	return unsupportedPlatform{}
+}
+
+func (unsupportedPlatform) ActiveWindow() (Window, error) { return 0, ErrUnsupported }
+func (unsupportedPlatform) Activate(Window) error         { return ErrUnsupported }
+func (unsupportedPlatform) SendPaste() error              { return ErrUnsupported }
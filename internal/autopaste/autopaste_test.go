@@ -0,0 +1,107 @@
+package autopaste
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakePlatform struct {
+	active Window
+	calls  []string
+	err    error
+}
+
+func (f *fakePlatform) ActiveWindow() (Window, error) {
+	f.calls = append(f.calls, "active")
+	return f.active, f.err
+}
+
+func (f *fakePlatform) Activate(w Window) error {
+	f.calls = append(f.calls, "activate")
+	if w != f.active {
+		return errors.New("activated the wrong window")
+	}
+	return nil
+}
+
+func (f *fakePlatform) SendPaste() error {
+	f.calls = append(f.calls, "paste")
+	return nil
+}
+
+func TestPasteIntoPrevious_RestoresFocusThenPastes(t *testing.T) {
+	fake := &fakePlatform{active: 42}
+	SetPlatform(fake)
+	defer SetPlatform(newPlatform())
+
+	if err := RememberActiveWindow(); err != nil {
+		t.Fatalf("RememberActiveWindow failed: %v", err)
+	}
+	if err := PasteIntoPrevious(); err != nil {
+		t.Fatalf("PasteIntoPrevious failed: %v", err)
+	}
+
+	expected := []string{"active", "activate", "paste"}
+	if !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("calls = %v, want %v", fake.calls, expected)
+	}
+}
+
+func TestPasteIntoPrevious_WithoutRememberedWindow(t *testing.T) {
+	fake := &fakePlatform{err: errors.New("no focus")}
+	SetPlatform(fake)
+	defer SetPlatform(newPlatform())
+
+	if err := RememberActiveWindow(); err == nil {
+		t.Fatal("expected RememberActiveWindow to fail")
+	}
+	if err := PasteIntoPrevious(); err == nil {
+		t.Fatal("expected PasteIntoPrevious to fail without a remembered window")
+	}
+	for _, call := range fake.calls {
+		if call == "paste" {
+			t.Error("no keystroke should be sent when focus cannot be restored")
+		}
+	}
+}
+
+func TestPasteIntoPrevious_OnlyOncePerRemember(t *testing.T) {
+	fake := &fakePlatform{active: 42}
+	SetPlatform(fake)
+	defer SetPlatform(newPlatform())
+
+	if err := RememberActiveWindow(); err != nil {
+		t.Fatalf("RememberActiveWindow failed: %v", err)
+	}
+	if err := PasteIntoPrevious(); err != nil {
+		t.Fatalf("PasteIntoPrevious failed: %v", err)
+	}
+	fake.calls = nil
+	if Remembered() {
+		t.Error("the window should be forgotten once pasted into")
+	}
+	if err := PasteIntoPrevious(); err == nil {
+		t.Error("expected a second PasteIntoPrevious without a new remember to fail")
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("calls = %v, want no Activate or SendPaste", fake.calls)
+	}
+}
+
+func TestForget(t *testing.T) {
+	fake := &fakePlatform{active: 42}
+	SetPlatform(fake)
+	defer SetPlatform(newPlatform())
+
+	if err := RememberActiveWindow(); err != nil {
+		t.Fatalf("RememberActiveWindow failed: %v", err)
+	}
+	Forget()
+	if Remembered() {
+		t.Error("Forget should drop the remembered window")
+	}
+	if err := PasteIntoPrevious(); err == nil {
+		t.Error("expected PasteIntoPrevious to fail after Forget")
+	}
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package autopaste
+
+import (
+	"errors"
+	"syscall"
+)
+
+var (
+	user32                  = syscall.NewLazyDLL("user32.dll")
+	procGetForegroundWindow = user32.NewProc("GetForegroundWindow")
+	procSetForegroundWindow = user32.NewProc("SetForegroundWindow")
+	procKeybdEvent          = user32.NewProc("keybd_event")
+)
+
+const (
+	vkControl      = 0x11
+	vkV            = 0x56
+	keyeventfKeyUp = 0x0002
+)
+
+// windowsPlatform uses the user32 foreground window and keyboard APIs
+type windowsPlatform struct{}
+
+func newPlatform() Platform {
+	return windowsPlatform{}
+}
+
+func (windowsPlatform) ActiveWindow() (Window, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		return 0, errors.New("no foreground window")
+	}
+	return Window(hwnd), nil
+}
+
+func (windowsPlatform) Activate(w Window) error {
+	// Windows may refuse to hand over the foreground, e.g. while another app holds it locked
+	if ok, _, _ := procSetForegroundWindow.Call(uintptr(w)); ok == 0 {
+		return errors.New("foreground change was refused")
+	}
+	return nil
+}
+
+func (windowsPlatform) SendPaste() error {
+	for _, key := range []struct{ vk, flags uintptr }{
+		{vkControl, 0},
+		{vkV, 0},
+		{vkV, keyeventfKeyUp},
+		{vkControl, keyeventfKeyUp},
+	} {
+		procKeybdEvent.Call(key.vk, 0, key.flags, 0)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileName is the name of the settings file inside the data directory
+const FileName = "config.json"
+
+// Config holds user settings. Fields missing from the file keep their defaults.
+type Config struct {
+	Primary        PrimaryConfig        `json:"primary"`
+	Detectors      DetectorsConfig      `json:"detectors"`
+	LinkCleaning   LinkCleaningConfig   `json:"link_cleaning"`
+	LinkTitles     LinkTitlesConfig     `json:"link_titles"`
+	AutoPaste      AutoPasteConfig      `json:"auto_paste"`
+	Hotkeys        HotkeysConfig        `json:"hotkeys"`
+	HTTPAPI        HTTPAPIConfig        `json:"http_api"`
+	Sync           SyncConfig           `json:"sync"`
+	LANShare       LANShareConfig       `json:"lan_share"`
+	Hooks          HooksConfig          `json:"hooks"`
+	Secrets        SecretsConfig        `json:"secrets"`
+	ClipboardClear ClipboardClearConfig `json:"clipboard_clear"`
+	Notifications  NotificationsConfig  `json:"notifications"`
+	Logging        LoggingConfig        `json:"logging"`
+}
+
+// PrimaryConfig controls X11 PRIMARY selection capture (Linux only)
+type PrimaryConfig struct {
+	Enabled    bool `json:"enabled"`
+	MinLength  int  `json:"min_length"`  // Selections shorter than this are ignored
+	DebounceMs int  `json:"debounce_ms"` // Selection must be stable this long before capture
+	Sync       bool `json:"sync"`        // Mirror PRIMARY and CLIPBOARD into each other
+}
+
+// DetectorsConfig selects which content-type detectors run on captured text
+type DetectorsConfig struct {
+	Disabled []string `json:"disabled"` // Detector names, e.g. "markdown"
+}
+
+// LinkCleaningConfig extends the built-in tracking-parameter rules applied to captured links
+type LinkCleaningConfig struct {
+	Enabled      bool                `json:"enabled"`
+	StripParams  []string            `json:"strip_params"`  // Extra names; "prefix_*" matches a prefix
+	KeepParams   []string            `json:"keep_params"`   // Names never stripped
+	Redirectors  []RedirectorRule    `json:"redirectors"`   // Extra link wrappers to unwrap
+	DomainParams map[string][]string `json:"domain_params"` // Extra names stripped only on a domain
+}
+
+// LinkTitlesConfig controls fetching page titles for captured links
+type LinkTitlesConfig struct {
+	Enabled   bool  `json:"enabled"`
+	TimeoutMs int   `json:"timeout_ms"` // Limit for connecting, TLS, headers and the whole request
+	MaxBytes  int64 `json:"max_bytes"`  // Bytes of a page read while looking for its title
+}
+
+// AutoPasteConfig controls pasting into the previously focused window after an item is chosen
+type AutoPasteConfig struct {
+	Enabled bool `json:"enabled"`
+	DelayMs int  `json:"delay_ms"` // Wait after restoring focus before sending the paste keystroke
+}
+
+// HotkeysConfig binds global key combinations such as "ctrl+alt+p" to actions.
+// An empty string leaves the action unbound.
+type HotkeysConfig struct {
+	ToggleWindow  string `json:"toggle_window"`
+	PauseCapture  string `json:"pause_capture"`
+	PastePrevious string `json:"paste_previous"`
+
+	// Registers maps a register name (a-z, 1-9) to the combination that copies
+	// its item. None are bound by default, so no keys are taken from other
+	// applications until you choose them.
+	Registers map[string]string `json:"registers,omitempty"`
+}
+
+// HTTPAPIConfig controls the token-authenticated HTTP API on 127.0.0.1
+type HTTPAPIConfig struct {
+	Enabled bool `json:"enabled"`
+	Port    int  `json:"port"`
+}
+
+// SyncConfig controls syncing the history with other devices through a shared folder
+type SyncConfig struct {
+	Enabled            bool   `json:"enabled"`
+	Folder             string `json:"folder"`               // Folder shared with the other devices, e.g. in Dropbox
+	Passphrase         string `json:"passphrase,omitempty"` // Encrypts the folder's contents; moved to the system keychain on start
+	KeychainPassphrase bool   `json:"keychain_passphrase"`  // The passphrase is in the system keychain
+	FavoritesOnly      bool   `json:"favorites_only"`       // Only sync favorites
+	IntervalSeconds    int    `json:"interval_seconds"`     // How often the folder is checked for other devices' changes
+}
+
+// LANShareConfig controls pushing new items to paired devices on the local network
+type LANShareConfig struct {
+	Enabled      bool      `json:"enabled"`
+	Port         int       `json:"port"`
+	Name         string    `json:"name"`          // Shown to paired devices; the host name when empty
+	SetClipboard bool      `json:"set_clipboard"` // Also put items pushed by peers on the clipboard
+	Peers        []LANPeer `json:"peers"`         // Added by pastee pair
+}
+
+// LANPeer is a paired device and what is shared with it
+type LANPeer struct {
+	Name           string   `json:"name"`
+	Fingerprint    string   `json:"fingerprint"` // Of the device's certificate
+	Address        string   `json:"address"`     // host:port the device listens on
+	Send           bool     `json:"send"`        // Push new items to the device
+	Receive        bool     `json:"receive"`     // Accept items pushed by the device
+	BlockTypes     []string `json:"block_types,omitempty"`
+	ShareSensitive bool     `json:"share_sensitive"`
+}
+
+// HooksConfig controls running user commands on clipboard events
+type HooksConfig struct {
+	Enabled       bool          `json:"enabled"`
+	TimeoutMs     int           `json:"timeout_ms"`     // A command still running after this is killed
+	MaxConcurrent int           `json:"max_concurrent"` // Event commands running at the same time
+	Commands      []HookCommand `json:"commands"`
+}
+
+// HookCommand is a command run on an event with the item as JSON on stdin
+type HookCommand struct {
+	Event          string   `json:"event"`           // item_added, item_copied, item_deleted or transform
+	Command        []string `json:"command"`         // Executable and arguments
+	Types          []string `json:"types,omitempty"` // Item types the command runs for; all when empty
+	AllowSensitive bool     `json:"allow_sensitive"` // Also run it for sensitive items
+}
+
+// SecretsConfig controls flagging captured text that holds credentials
+type SecretsConfig struct {
+	Enabled     bool     `json:"enabled"`
+	SkipStorage bool     `json:"skip_storage"` // Don't store detected secrets at all instead of marking them sensitive
+	Disabled    []string `json:"disabled"`     // Detector names, e.g. "high_entropy"
+}
+
+// ClipboardClearConfig controls removing sensitive items from the clipboard after they are copied
+type ClipboardClearConfig struct {
+	Enabled         bool `json:"enabled"`
+	AfterSeconds    int  `json:"after_seconds"`
+	RestorePrevious bool `json:"restore_previous"` // Put back what was copied before instead of emptying the clipboard
+}
+
+// NotificationsConfig controls desktop notifications for captured items
+type NotificationsConfig struct {
+	Mode          string            `json:"mode"`            // "on", "summary" (type only, no content) or "off"
+	Types         map[string]string `json:"types,omitempty"` // Mode per item type, overriding Mode
+	Duplicates    bool              `json:"duplicates"`      // Also notify when a copied item was already in the history
+	MinIntervalMs int               `json:"min_interval_ms"` // Items captured sooner after a notification are batched into one
+	DoNotDisturb  bool              `json:"do_not_disturb"`
+}
+
+// LoggingConfig controls the log written to stderr and pastee.log
+type LoggingConfig struct {
+	Level      string `json:"level"`       // "debug", "info", "warn" or "error"
+	LogContent bool   `json:"log_content"` // Log clipboard contents instead of their length and hash; for debugging only
+	MaxSizeMB  int    `json:"max_size_mb"` // pastee.log is rotated once it grows past this
+	MaxFiles   int    `json:"max_files"`   // Rotated files kept besides the current one
+}
+
+// RedirectorRule identifies a link wrapper carrying its destination in a query parameter
+type RedirectorRule struct {
+	Host  string `json:"host"`
+	Path  string `json:"path"`
+	Param string `json:"param"`
+}
+
+var (
+	mu          sync.RWMutex
+	current     = Default()
+	currentPath string // File the current config was loaded from or saved to
+)
+
+// Default returns the settings used when no config file exists
+func Default() *Config {
+	return &Config{
+		Primary: PrimaryConfig{
+			Enabled:    false,
+			MinLength:  3,
+			DebounceMs: 1000,
+			Sync:       false,
+		},
+		LinkCleaning: LinkCleaningConfig{
+			Enabled: true,
+		},
+		LinkTitles: LinkTitlesConfig{
+			Enabled:   false,
+			TimeoutMs: 5000,
+			MaxBytes:  512 * 1024,
+		},
+		AutoPaste: AutoPasteConfig{
+			Enabled: false,
+			DelayMs: 150,
+		},
+		Hotkeys: HotkeysConfig{
+			ToggleWindow: "ctrl+alt+p",
+			PauseCapture: "ctrl+alt+shift+p",
+		},
+		HTTPAPI: HTTPAPIConfig{
+			Enabled: false,
+			Port:    7744,
+		},
+		Sync: SyncConfig{
+			Enabled:         false,
+			IntervalSeconds: 10,
+		},
+		LANShare: LANShareConfig{
+			Enabled:      false,
+			Port:         7745,
+			SetClipboard: true,
+		},
+		Hooks: HooksConfig{
+			Enabled:       false,
+			TimeoutMs:     5000,
+			MaxConcurrent: 2,
+		},
+		Secrets: SecretsConfig{
+			Enabled: true,
+		},
+		ClipboardClear: ClipboardClearConfig{
+			Enabled:         true,
+			AfterSeconds:    30,
+			RestorePrevious: true,
+		},
+		Notifications: NotificationsConfig{
+			Mode:          "on",
+			MinIntervalMs: 3000,
+		},
+		Logging: LoggingConfig{
+			Level:     "info",
+			MaxSizeMB: 5,
+			MaxFiles:  3,
+		},
+	}
+}
+
+// Load reads the config file at path and makes it the current config.
+// A missing file is not an error; defaults are used instead.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	mu.Lock()
+	current = cfg
+	currentPath = path
+	mu.Unlock()
+	return cfg, nil
+}
+
+// Save writes cfg to path and makes it the current config
+func Save(path string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	mu.Lock()
+	current = cfg
+	currentPath = path
+	mu.Unlock()
+	return nil
+}
+
+// Update saves a changed copy of the current config to the file it came from.
+// The copy is shallow, so fn must replace slices and maps rather than edit them.
+func Update(fn func(*Config)) error {
+	mu.RLock()
+	next := *current
+	path := currentPath
+	mu.RUnlock()
+
+	if path == "" {
+		return errors.New("no config file loaded")
+	}
+	fn(&next)
+	return Save(path, &next)
+}
+
+// Get returns the current config. Callers must not modify it.
+func Get() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_MissingFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Primary.Enabled {
+		t.Error("PRIMARY capture should be disabled by default")
+	}
+	if cfg.Primary.DebounceMs != Default().Primary.DebounceMs {
+		t.Errorf("expected default debounce, got %d", cfg.Primary.DebounceMs)
+	}
+	if cfg.LinkTitles.Enabled {
+		t.Error("link title fetching should be disabled by default")
+	}
+	if cfg.HTTPAPI.Enabled {
+		t.Error("the HTTP API should be disabled by default")
+	}
+	if cfg.Sync.Enabled || cfg.Sync.IntervalSeconds != 10 {
+		t.Errorf("sync should be disabled with a 10 second interval by default, got %+v", cfg.Sync)
+	}
+	if cfg.LANShare.Enabled {
+		t.Error("LAN sharing should be disabled by default")
+	}
+	if cfg.Hooks.Enabled || cfg.Hooks.TimeoutMs <= 0 || cfg.Hooks.MaxConcurrent <= 0 {
+		t.Errorf("hooks should be disabled with a timeout and concurrency limit by default, got %+v", cfg.Hooks)
+	}
+	if !cfg.Secrets.Enabled || cfg.Secrets.SkipStorage {
+		t.Errorf("secrets should be flagged but stored by default, got %+v", cfg.Secrets)
+	}
+	if !cfg.ClipboardClear.Enabled || cfg.ClipboardClear.AfterSeconds != 30 || !cfg.ClipboardClear.RestorePrevious {
+		t.Errorf("copied secrets should be cleared after 30s by default, got %+v", cfg.ClipboardClear)
+	}
+	if cfg.Notifications.Mode != "on" || cfg.Notifications.Duplicates || cfg.Notifications.MinIntervalMs <= 0 {
+		t.Errorf("notifications should be on and rate limited, without duplicates, by default, got %+v", cfg.Notifications)
+	}
+	if cfg.Logging.LogContent || cfg.Logging.Level != "info" || cfg.Logging.MaxSizeMB <= 0 {
+		t.Errorf("logs should be rotated and leave out contents by default, got %+v", cfg.Logging)
+	}
+}
+
+func TestLoad_PartialFileKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"primary": {"enabled": true}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.Primary.Enabled {
+		t.Error("expected enabled from file")
+	}
+	if cfg.Primary.MinLength != Default().Primary.MinLength {
+		t.Errorf("expected default min length, got %d", cfg.Primary.MinLength)
+	}
+	if Get() != cfg {
+		t.Error("Load should update the current config")
+	}
+}
+
+func TestSave_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	cfg := Default()
+	cfg.Primary.Sync = true
+
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.Primary.Sync {
+		t.Error("expected sync to round-trip")
+	}
+}
+
+func TestLoad_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{not json`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestUpdate_SavesToLoadedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := Update(func(cfg *Config) { cfg.Hotkeys.PastePrevious = "ctrl+alt+v" }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Hotkeys.PastePrevious != "ctrl+alt+v" {
+		t.Errorf("expected updated hotkey to be saved, got %q", loaded.Hotkeys.PastePrevious)
+	}
+	if loaded.Hotkeys.ToggleWindow != Default().Hotkeys.ToggleWindow {
+		t.Errorf("expected other settings to be kept, got %q", loaded.Hotkeys.ToggleWindow)
+	}
+}
+
+func TestLoad_RegisterHotkeysOnlyAsConfigured(t *testing.T) {
+	if len(Default().Hotkeys.Registers) != 0 {
+		t.Errorf("no register hotkeys should be bound by default, got %v", Default().Hotkeys.Registers)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	data := `{"hotkeys": {"registers": {"a": "ctrl+alt+a"}}}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer Load(filepath.Join(t.TempDir(), "missing.json"))
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := map[string]string{"a": "ctrl+alt+a"}
+	if len(cfg.Hotkeys.Registers) != len(want) || cfg.Hotkeys.Registers["a"] != want["a"] {
+		t.Errorf("register hotkeys = %v, want %v", cfg.Hotkeys.Registers, want)
+	}
+}
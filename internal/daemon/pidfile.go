@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrRunning is returned by AcquirePIDFile when another daemon is alive
+var ErrRunning = errors.New("pastee daemon is already running")
+
+// AcquirePIDFile writes the current process ID to path. A file left behind by
+// a process that no longer exists is replaced. The returned release function
+// removes the file again.
+func AcquirePIDFile(path string) (release func() error, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := fmt.Fprintf(f, "%d\n", os.Getpid())
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, werr
+			}
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		pid, err := ReadPIDFile(path)
+		if err == nil && processAlive(pid) {
+			return nil, fmt.Errorf("%w (pid %d)", ErrRunning, pid)
+		}
+		// Stale or unreadable: take it over
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("could not create pid file %s", path)
+}
+
+// ReadPIDFile returns the process ID stored in path
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s", path)
+	}
+	return pid, nil
+}
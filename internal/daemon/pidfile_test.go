@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAcquirePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pastee.pid")
+
+	release, err := AcquirePIDFile(path)
+	if err != nil {
+		t.Fatalf("AcquirePIDFile failed: %v", err)
+	}
+	if pid, err := ReadPIDFile(path); err != nil || pid != os.Getpid() {
+		t.Fatalf("pid file holds %d (%v), want %d", pid, err, os.Getpid())
+	}
+
+	// This process is alive, so a second daemon must refuse to start
+	if _, err := AcquirePIDFile(path); !errors.Is(err, ErrRunning) {
+		t.Errorf("expected ErrRunning, got %v", err)
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected pid file to be removed")
+	}
+}
+
+func TestAcquirePIDFile_ReplacesStaleFile(t *testing.T) {
+	// A finished child gives us a pid that is no longer running
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run child: %v", err)
+	}
+
+	for _, content := range []string{strconv.Itoa(cmd.Process.Pid), "garbage"} {
+		path := filepath.Join(t.TempDir(), "pastee.pid")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		release, err := AcquirePIDFile(path)
+		if err != nil {
+			t.Fatalf("stale pid file %q was not replaced: %v", content, err)
+		}
+		release()
+	}
+}
@@ -1,7 +1,9 @@
 package database
 
 import (
-	"log"
+	"database/sql"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/Sirpyerre/pasteeclipboard/internal/imageutil"
@@ -15,41 +17,121 @@ type ClipboardItemDB struct {
 	CreatedAt time.Time
 }
 
+// itemColumns lists the clipboard_history columns read by scanItem, in order
+const itemColumns = `id, content, type, COALESCE(image_path, ''), COALESCE(preview_path, ''), COALESCE(is_sensitive, 0), COALESCE(is_favorite, 0), COALESCE(source, 'clipboard'), COALESCE(original_content, ''),
+	COALESCE((SELECT title FROM link_metadata WHERE link_metadata.url = clipboard_history.content), ''),
+	COALESCE((SELECT group_concat(name) FROM registers WHERE registers.item_id = clipboard_history.id), ''),
+	COALESCE(tags, ''), expires_at, COALESCE(detected_secret, ''), created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(row rowScanner) (models.ClipboardItem, error) {
+	var item models.ClipboardItem
+	var registers, tags string
+	var expiresAt sql.NullTime
+	err := row.Scan(&item.ID, &item.Content, &item.Type, &item.ImagePath, &item.PreviewPath, &item.IsSensitive, &item.IsFavorite, &item.Source, &item.OriginalContent, &item.Title, &registers, &tags, &expiresAt, &item.DetectedSecret, &item.CreatedAt)
+	item.Registers = splitRegisters(registers)
+	item.Tags = splitTags(tags)
+	item.ExpiresAt = expiresAt.Time
+	return item, err
+}
+
 func InsertClipboardItem(content, itemType string) (int64, error) {
-	stmt, err := db.Prepare(`INSERT INTO clipboard_history (content, type) VALUES (?, ?)`)
+	return InsertTextItem(models.ClipboardItem{Content: content, Type: itemType, Source: models.SourceClipboard})
+}
+
+// InsertTextItem inserts a text item with its capture metadata and flags.
+// A zero CreatedAt means now.
+func InsertTextItem(item models.ClipboardItem) (int64, error) {
+	stmt, err := db.Prepare(`INSERT INTO clipboard_history (content, type, source, original_content, is_favorite, is_sensitive, tags, expires_at, detected_secret, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))`)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(content, itemType)
+	res, err := stmt.Exec(item.Content, item.Type, item.Source, nullIfEmpty(item.OriginalContent), item.IsFavorite, item.IsSensitive,
+		nullIfEmpty(strings.Join(item.Tags, ",")), timestampOrNull(item.ExpiresAt), nullIfEmpty(item.DetectedSecret), timestampOrNull(item.CreatedAt))
 	if err != nil {
 		return 0, err
 	}
 
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	notifyAdded(id)
+	return id, nil
+}
+
+// nullIfEmpty stores optional text columns as NULL rather than an empty string
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// timestampOrNull formats t the way CURRENT_TIMESTAMP does, so items sort correctly
+func timestampOrNull(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.DateTime)
 }
 
 // InsertImageItem inserts an image clipboard item with paths and hash
 func InsertImageItem(imagePath, previewPath, imageHash, itemType string) (int64, error) {
-	stmt, err := db.Prepare(`INSERT INTO clipboard_history (content, type, image_path, preview_path, image_hash) VALUES (?, ?, ?, ?, ?)`)
+	return InsertImage(models.ClipboardItem{Type: itemType, ImagePath: imagePath, PreviewPath: previewPath, Source: models.SourceClipboard}, imageHash)
+}
+
+// InsertImage inserts an image item with its paths, capture metadata and flags
+func InsertImage(item models.ClipboardItem, imageHash string) (int64, error) {
+	stmt, err := db.Prepare(`INSERT INTO clipboard_history (content, type, image_path, preview_path, image_hash, source, is_sensitive, tags, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
 	// For images, we store empty string as content
-	res, err := stmt.Exec("", itemType, imagePath, previewPath, imageHash)
+	res, err := stmt.Exec("", item.Type, item.ImagePath, item.PreviewPath, imageHash, item.Source, item.IsSensitive,
+		nullIfEmpty(strings.Join(item.Tags, ",")), timestampOrNull(item.ExpiresAt))
 	if err != nil {
 		return 0, err
 	}
 
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	notifyAdded(id)
+	return id, nil
 }
 
 func GetClipboardHistory(limit int) ([]models.ClipboardItem, error) {
-	stmt := `SELECT id, content, type, COALESCE(image_path, ''), COALESCE(preview_path, ''), COALESCE(is_sensitive, 0), COALESCE(is_favorite, 0) FROM clipboard_history ORDER BY created_at DESC LIMIT ?`
-	rows, err := db.Query(stmt, limit)
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history ORDER BY created_at DESC LIMIT ?`
+	return queryItems(stmt, limit)
+}
+
+// SearchClipboardHistory returns the newest items whose content or link title
+// contains query, ignoring case
+func SearchClipboardHistory(query string, limit int) ([]models.ClipboardItem, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history
+		WHERE content LIKE ? ESCAPE '\'
+		OR (SELECT title FROM link_metadata WHERE link_metadata.url = clipboard_history.content) LIKE ? ESCAPE '\'
+		ORDER BY created_at DESC LIMIT ?`
+	return queryItems(stmt, pattern, pattern, limit)
+}
+
+// likeEscaper makes LIKE wildcards in a search query match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func queryItems(stmt string, args ...any) ([]models.ClipboardItem, error) {
+	rows, err := db.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +139,8 @@ func GetClipboardHistory(limit int) ([]models.ClipboardItem, error) {
 
 	var items []models.ClipboardItem
 	for rows.Next() {
-		var item models.ClipboardItem
-		if err := rows.Scan(&item.ID, &item.Content, &item.Type, &item.ImagePath, &item.PreviewPath, &item.IsSensitive, &item.IsFavorite); err != nil {
+		item, err := scanItem(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, item)
@@ -82,17 +164,26 @@ func DeleteClipboardItem(id int) error {
 		return err
 	}
 
+	// Registers holding the item are emptied with it
+	if _, err := db.Exec(`DELETE FROM registers WHERE item_id = ?`, id); err != nil {
+		return err
+	}
+
 	// Delete associated image files if they exist
 	if imagePath != "" || previewPath != "" {
 		imageutil.DeleteImage(imagePath, previewPath)
 	}
 
+	notifyRemoved(id, false)
 	return nil
 }
 
+// DeleteAllClipboardItems clears the history except items held in a register
 func DeleteAllClipboardItems() error {
 	// Get all items with image paths
-	stmt := `SELECT COALESCE(image_path, ''), COALESCE(preview_path, '') FROM clipboard_history WHERE image_path IS NOT NULL OR preview_path IS NOT NULL`
+	stmt := `SELECT COALESCE(image_path, ''), COALESCE(preview_path, '') FROM clipboard_history
+			 WHERE (image_path IS NOT NULL OR preview_path IS NOT NULL)
+			 AND id NOT IN (SELECT item_id FROM registers)`
 	rows, err := db.Query(stmt)
 	if err != nil {
 		return err
@@ -115,8 +206,14 @@ func DeleteAllClipboardItems() error {
 		}
 	}
 
+	// Remember which items go, for the listeners
+	removedIDs, err := queryIDs("SELECT id FROM clipboard_history WHERE id NOT IN (SELECT item_id FROM registers)")
+	if err != nil {
+		return err
+	}
+
 	// Delete all from database
-	_, err = db.Exec("DELETE FROM clipboard_history")
+	_, err = db.Exec("DELETE FROM clipboard_history WHERE id NOT IN (SELECT item_id FROM registers)")
 	if err != nil {
 		return err
 	}
@@ -130,9 +227,31 @@ func DeleteAllClipboardItems() error {
 		imageutil.DeleteImage(imagePaths[i], preview)
 	}
 
+	for _, id := range removedIDs {
+		notifyRemoved(id, false)
+	}
+	notifyCleared()
 	return nil
 }
 
+func queryIDs(stmt string, args ...any) ([]int, error) {
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // CheckDuplicateContent checks if the exact content already exists in the database
 func CheckDuplicateContent(content string) (bool, error) {
 	stmt := `SELECT COUNT(*) FROM clipboard_history WHERE content = ?`
@@ -144,11 +263,20 @@ func CheckDuplicateContent(content string) (bool, error) {
 	return count > 0, nil
 }
 
+// GetItemByID retrieves an item by its ID, or sql.ErrNoRows if there is none
+func GetItemByID(id int) (*models.ClipboardItem, error) {
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history WHERE id = ?`
+	item, err := scanItem(db.QueryRow(stmt, id))
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
 // GetItemByContent retrieves an existing item by its content
 func GetItemByContent(content string) (*models.ClipboardItem, error) {
-	stmt := `SELECT id, content, type, COALESCE(image_path, ''), COALESCE(preview_path, ''), COALESCE(is_sensitive, 0), COALESCE(is_favorite, 0) FROM clipboard_history WHERE content = ? LIMIT 1`
-	var item models.ClipboardItem
-	err := db.QueryRow(stmt, content).Scan(&item.ID, &item.Content, &item.Type, &item.ImagePath, &item.PreviewPath, &item.IsSensitive, &item.IsFavorite)
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history WHERE content = ? LIMIT 1`
+	item, err := scanItem(db.QueryRow(stmt, content))
 	if err != nil {
 		return nil, err
 	}
@@ -157,9 +285,8 @@ func GetItemByContent(content string) (*models.ClipboardItem, error) {
 
 // GetItemByImagePath retrieves an existing item by its image path
 func GetItemByImagePath(imagePath string) (*models.ClipboardItem, error) {
-	stmt := `SELECT id, content, type, COALESCE(image_path, ''), COALESCE(preview_path, ''), COALESCE(is_sensitive, 0), COALESCE(is_favorite, 0) FROM clipboard_history WHERE image_path = ? LIMIT 1`
-	var item models.ClipboardItem
-	err := db.QueryRow(stmt, imagePath).Scan(&item.ID, &item.Content, &item.Type, &item.ImagePath, &item.PreviewPath, &item.IsSensitive, &item.IsFavorite)
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history WHERE image_path = ? LIMIT 1`
+	item, err := scanItem(db.QueryRow(stmt, imagePath))
 	if err != nil {
 		return nil, err
 	}
@@ -186,9 +313,8 @@ func CheckDuplicateImageHash(imageHash string) (bool, error) {
 
 // GetItemByImageHash retrieves an existing item by its image hash
 func GetItemByImageHash(imageHash string) (*models.ClipboardItem, error) {
-	stmt := `SELECT id, content, type, COALESCE(image_path, ''), COALESCE(preview_path, ''), COALESCE(is_sensitive, 0), COALESCE(is_favorite, 0) FROM clipboard_history WHERE image_hash = ? LIMIT 1`
-	var item models.ClipboardItem
-	err := db.QueryRow(stmt, imageHash).Scan(&item.ID, &item.Content, &item.Type, &item.ImagePath, &item.PreviewPath, &item.IsSensitive, &item.IsFavorite)
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history WHERE image_hash = ? LIMIT 1`
+	item, err := scanItem(db.QueryRow(stmt, imageHash))
 	if err != nil {
 		return nil, err
 	}
@@ -198,22 +324,43 @@ func GetItemByImageHash(imageHash string) (*models.ClipboardItem, error) {
 // UpdateItemFavorite updates the favorite flag for a clipboard item
 func UpdateItemFavorite(id int, isFavorite bool) error {
 	stmt := `UPDATE clipboard_history SET is_favorite = ? WHERE id = ?`
-	_, err := db.Exec(stmt, isFavorite, id)
-	return err
+	if _, err := db.Exec(stmt, isFavorite, id); err != nil {
+		return err
+	}
+	notifyUpdated(id)
+	return nil
 }
 
-// UpdateItemContent updates the content and type of a clipboard item
+// UpdateItemContent updates the content and type of a clipboard item.
+// Any original (pre-cleaning) content no longer applies and is cleared.
 func UpdateItemContent(id int, content string, itemType string) error {
-	stmt := `UPDATE clipboard_history SET content = ?, type = ? WHERE id = ?`
-	_, err := db.Exec(stmt, content, itemType, id)
-	return err
+	stmt := `UPDATE clipboard_history SET content = ?, type = ?, original_content = NULL WHERE id = ?`
+	if _, err := db.Exec(stmt, content, itemType, id); err != nil {
+		return err
+	}
+	notifyUpdated(id)
+	return nil
 }
 
 // UpdateItemSensitivity updates the sensitivity flag for a clipboard item
 func UpdateItemSensitivity(id int, isSensitive bool) error {
 	stmt := `UPDATE clipboard_history SET is_sensitive = ? WHERE id = ?`
-	_, err := db.Exec(stmt, isSensitive, id)
-	return err
+	if _, err := db.Exec(stmt, isSensitive, id); err != nil {
+		return err
+	}
+	notifyUpdated(id)
+	return nil
+}
+
+// UpdateItemCapture updates what capturing decides about an item: its
+// sensitivity, detected secret kind, tags and expiry time (zero for never)
+func UpdateItemCapture(id int, isSensitive bool, detectedSecret string, tags []string, expiresAt time.Time) error {
+	stmt := `UPDATE clipboard_history SET is_sensitive = ?, detected_secret = ?, tags = ?, expires_at = ? WHERE id = ?`
+	if _, err := db.Exec(stmt, isSensitive, nullIfEmpty(detectedSecret), nullIfEmpty(strings.Join(tags, ",")), timestampOrNull(expiresAt), id); err != nil {
+		return err
+	}
+	notifyUpdated(id)
+	return nil
 }
 
 // GetHistoryCount returns the total number of items in history
@@ -223,7 +370,8 @@ func GetHistoryCount() (int, error) {
 	return count, err
 }
 
-// EnforceHistoryLimit removes oldest non-favorite items if history exceeds MaxHistoryItems
+// EnforceHistoryLimit removes oldest items if history exceeds MaxHistoryItems.
+// Favorites and items held in a register are never removed.
 func EnforceHistoryLimit() error {
 	count, err := GetHistoryCount()
 	if err != nil {
@@ -241,6 +389,7 @@ func EnforceHistoryLimit() error {
 	stmt := `SELECT id, COALESCE(image_path, ''), COALESCE(preview_path, '')
 			 FROM clipboard_history
 			 WHERE COALESCE(is_favorite, 0) = 0
+			 AND id NOT IN (SELECT item_id FROM registers)
 			 ORDER BY created_at ASC
 			 LIMIT ?`
 	rows, err := db.Query(stmt, toDelete)
@@ -277,6 +426,60 @@ func EnforceHistoryLimit() error {
 		imageutil.DeleteImage(img.imagePath, img.previewPath)
 	}
 
-	log.Printf("History limit enforced: deleted %d oldest items (limit: %d)\n", toDelete, MaxHistoryItems)
+	for _, id := range idsToDelete {
+		notifyRemoved(id, true)
+	}
+
+	slog.Info("History limit enforced", "deleted", toDelete, "limit", MaxHistoryItems)
 	return nil
 }
+
+// DeleteExpiredItems deletes the items whose expiry time has passed, even
+// favorites and items held in a register, and returns how many went
+func DeleteExpiredItems() (int, error) {
+	now := timestampOrNull(time.Now())
+	rows, err := db.Query(`SELECT id, COALESCE(image_path, ''), COALESCE(preview_path, '')
+		FROM clipboard_history WHERE expires_at IS NOT NULL AND expires_at <= ?`, now)
+	if err != nil {
+		return 0, err
+	}
+	type expired struct {
+		id                     int
+		imagePath, previewPath string
+	}
+	var items []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.imagePath, &e.previewPath); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range items {
+		if _, err := db.Exec("DELETE FROM clipboard_history WHERE id = ?", e.id); err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec("DELETE FROM registers WHERE item_id = ?", e.id); err != nil {
+			return 0, err
+		}
+		if e.imagePath != "" || e.previewPath != "" {
+			imageutil.DeleteImage(e.imagePath, e.previewPath)
+		}
+		notifyRemoved(e.id, true)
+	}
+	return len(items), nil
+}
+
+// splitTags parses the comma-separated tags column
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
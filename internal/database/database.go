@@ -3,7 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -20,7 +20,8 @@ const (
 
 var db *sql.DB
 
-func getDataDir() (string, error) {
+// DataDir returns the directory holding the database, images and settings
+func DataDir() (string, error) {
 	// Try current directory first (for development)
 	if _, err := os.Stat("data"); err == nil {
 		return "data", nil
@@ -35,7 +36,7 @@ func getDataDir() (string, error) {
 }
 
 func InitDB() (*sql.DB, bool, error) {
-	dataDir, err := getDataDir()
+	dataDir, err := DataDir()
 	if err != nil {
 		return nil, false, err
 	}
@@ -72,10 +73,20 @@ func InitDB() (*sql.DB, bool, error) {
 		return nil, false, err
 	}
 
-	log.Printf("init DB in: %s (encrypted: %v, needsMigration: %v)", dbPath, useEncrypted, needsMigration)
+	slog.Info("init DB", "path", dbPath, "encrypted", useEncrypted, "needsMigration", needsMigration)
 	return db, needsMigration, nil
 }
 
+// CloseDB closes the database opened by InitDB
+func CloseDB() error {
+	if db == nil {
+		return nil
+	}
+	err := db.Close()
+	db = nil
+	return err
+}
+
 func checkMigrationNeeded(unencryptedPath, encryptedPath string) (bool, error) {
 	if _, err := os.Stat(encryptedPath); err == nil {
 		return false, nil
@@ -109,7 +120,7 @@ func openUnencryptedDatabase(path string) (*sql.DB, error) {
 }
 
 func PerformMigration() error {
-	dataDir, err := getDataDir()
+	dataDir, err := DataDir()
 	if err != nil {
 		return err
 	}
@@ -125,9 +136,9 @@ func PerformMigration() error {
 
 	backupPath, err := encryption.BackupDatabase(unencryptedPath)
 	if err != nil {
-		log.Printf("Warning: Failed to create backup: %v", err)
+		slog.Warn("Failed to create backup", "err", err)
 	} else {
-		log.Printf("Backup created at: %s", backupPath)
+		slog.Info("Backup created", "path", backupPath)
 	}
 
 	if err := encryption.MigrateToEncrypted(unencryptedPath, encryptedPath, key); err != nil {
@@ -136,7 +147,7 @@ func PerformMigration() error {
 
 	oldPath := unencryptedPath + ".old"
 	if err := os.Rename(unencryptedPath, oldPath); err != nil {
-		log.Printf("Warning: Failed to rename old database: %v", err)
+		slog.Warn("Failed to rename old database", "err", err)
 	}
 
 	return nil
@@ -177,6 +188,11 @@ func migrateSchema() error {
 	hasImageHash := false
 	hasIsSensitive := false
 	hasIsFavorite := false
+	hasSource := false
+	hasOriginalContent := false
+	hasTags := false
+	hasExpiresAt := false
+	hasDetectedSecret := false
 
 	for rows.Next() {
 		var cid int
@@ -199,6 +215,16 @@ func migrateSchema() error {
 			hasIsSensitive = true
 		case "is_favorite":
 			hasIsFavorite = true
+		case "source":
+			hasSource = true
+		case "original_content":
+			hasOriginalContent = true
+		case "tags":
+			hasTags = true
+		case "expires_at":
+			hasExpiresAt = true
+		case "detected_secret":
+			hasDetectedSecret = true
 		}
 	}
 
@@ -206,35 +232,103 @@ func migrateSchema() error {
 		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN image_path TEXT"); err != nil {
 			return err
 		}
-		log.Println("Added image_path column to clipboard_history table")
+		slog.Info("Added column to clipboard_history table", "column", "image_path")
 	}
 
 	if !hasPreviewPath {
 		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN preview_path TEXT"); err != nil {
 			return err
 		}
-		log.Println("Added preview_path column to clipboard_history table")
+		slog.Info("Added column to clipboard_history table", "column", "preview_path")
 	}
 
 	if !hasImageHash {
 		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN image_hash TEXT"); err != nil {
 			return err
 		}
-		log.Println("Added image_hash column to clipboard_history table")
+		slog.Info("Added column to clipboard_history table", "column", "image_hash")
 	}
 
 	if !hasIsSensitive {
 		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN is_sensitive BOOLEAN DEFAULT 0"); err != nil {
 			return err
 		}
-		log.Println("Added is_sensitive column to clipboard_history table")
+		slog.Info("Added column to clipboard_history table", "column", "is_sensitive")
 	}
 
 	if !hasIsFavorite {
 		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN is_favorite BOOLEAN DEFAULT 0"); err != nil {
 			return err
 		}
-		log.Println("Added is_favorite column to clipboard_history table")
+		slog.Info("Added column to clipboard_history table", "column", "is_favorite")
+	}
+
+	if !hasSource {
+		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN source TEXT DEFAULT 'clipboard'"); err != nil {
+			return err
+		}
+		slog.Info("Added column to clipboard_history table", "column", "source")
+	}
+
+	if !hasOriginalContent {
+		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN original_content TEXT"); err != nil {
+			return err
+		}
+		slog.Info("Added column to clipboard_history table", "column", "original_content")
+	}
+
+	if !hasTags {
+		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN tags TEXT"); err != nil {
+			return err
+		}
+		slog.Info("Added column to clipboard_history table", "column", "tags")
+	}
+
+	if !hasExpiresAt {
+		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN expires_at TIMESTAMP"); err != nil {
+			return err
+		}
+		slog.Info("Added column to clipboard_history table", "column", "expires_at")
+	}
+
+	if !hasDetectedSecret {
+		if _, err := db.Exec("ALTER TABLE clipboard_history ADD COLUMN detected_secret TEXT"); err != nil {
+			return err
+		}
+		slog.Info("Added column to clipboard_history table", "column", "detected_secret")
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS link_metadata (
+		url TEXT PRIMARY KEY,
+		title TEXT,
+		canonical_url TEXT,
+		error TEXT,
+		fetched_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS registers (
+		name TEXT PRIMARY KEY,
+		item_id INTEGER NOT NULL,
+		assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS sync_items (
+		uid TEXT PRIMARY KEY,
+		item_id INTEGER,
+		clock INTEGER NOT NULL,
+		device TEXT NOT NULL,
+		deleted BOOLEAN NOT NULL DEFAULT 0,
+		digest TEXT NOT NULL DEFAULT '',
+		favorite BOOLEAN NOT NULL DEFAULT 0
+	)`); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_sync_items_item ON sync_items(item_id)`); err != nil {
+		return err
 	}
 
 	return nil
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// ItemListener is told about changes to the history, whichever part of the
+// app made them. Callbacks run on the goroutine that changed the database, so
+// they must return quickly; any of them may be nil.
+type ItemListener struct {
+	Added   func(item models.ClipboardItem) // A new item was inserted
+	Updated func(item models.ClipboardItem) // Content, type, favorite or sensitive flag changed
+	Removed func(id int, pruned bool)       // An item was deleted, or pruned by the history limit
+	Cleared func()                          // The history was cleared, after Removed for each item
+}
+
+var (
+	listenersMu sync.RWMutex
+	listeners   = make(map[*ItemListener]bool)
+)
+
+// AddItemListener registers l and returns a function that removes it again
+func AddItemListener(l ItemListener) (remove func()) {
+	key := &l
+	listenersMu.Lock()
+	listeners[key] = true
+	listenersMu.Unlock()
+	return func() {
+		listenersMu.Lock()
+		delete(listeners, key)
+		listenersMu.Unlock()
+	}
+}
+
+func currentListeners() []*ItemListener {
+	listenersMu.RLock()
+	defer listenersMu.RUnlock()
+	list := make([]*ItemListener, 0, len(listeners))
+	for l := range listeners {
+		list = append(list, l)
+	}
+	return list
+}
+
+// notifyAdded reads back the inserted item and passes it to the listeners
+func notifyAdded(id int64) {
+	notifyItem(int(id), func(l *ItemListener) func(models.ClipboardItem) { return l.Added })
+}
+
+// notifyUpdated reads back the changed item and passes it to the listeners
+func notifyUpdated(id int) {
+	notifyItem(id, func(l *ItemListener) func(models.ClipboardItem) { return l.Updated })
+}
+
+func notifyItem(id int, callback func(*ItemListener) func(models.ClipboardItem)) {
+	var fns []func(models.ClipboardItem)
+	for _, l := range currentListeners() {
+		if fn := callback(l); fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	if len(fns) == 0 {
+		return
+	}
+	item, err := GetItemByID(id)
+	if err != nil {
+		slog.Error("Failed to read item for listeners", "id", id, "err", err)
+		return
+	}
+	for _, fn := range fns {
+		fn(*item)
+	}
+}
+
+func notifyRemoved(id int, pruned bool) {
+	for _, l := range currentListeners() {
+		if l.Removed != nil {
+			l.Removed(id, pruned)
+		}
+	}
+}
+
+func notifyCleared() {
+	for _, l := range currentListeners() {
+		if l.Cleared != nil {
+			l.Cleared()
+		}
+	}
+}
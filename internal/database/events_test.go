@@ -0,0 +1,101 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestItemListener(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var added, updated []models.ClipboardItem
+	var removed []int
+	cleared := 0
+	remove := AddItemListener(ItemListener{
+		Added:   func(item models.ClipboardItem) { added = append(added, item) },
+		Updated: func(item models.ClipboardItem) { updated = append(updated, item) },
+		Removed: func(id int, pruned bool) {
+			if pruned {
+				t.Errorf("item %d should not be reported as pruned", id)
+			}
+			removed = append(removed, id)
+		},
+		Cleared: func() { cleared++ },
+	})
+
+	id, err := InsertTextItem(models.ClipboardItem{Content: "hello", Type: "text", Source: models.SourceCLI, IsSensitive: true})
+	if err != nil {
+		t.Fatalf("InsertTextItem failed: %v", err)
+	}
+	if len(added) != 1 || added[0].ID != int(id) || added[0].Content != "hello" || added[0].Source != models.SourceCLI || !added[0].IsSensitive {
+		t.Fatalf("Added should receive the stored item, got %+v", added)
+	}
+
+	if err := UpdateItemFavorite(int(id), true); err != nil {
+		t.Fatalf("UpdateItemFavorite failed: %v", err)
+	}
+	if len(updated) != 1 || !updated[0].IsFavorite {
+		t.Errorf("Updated should receive the favorited item, got %+v", updated)
+	}
+
+	if err := DeleteClipboardItem(int(id)); err != nil {
+		t.Fatalf("DeleteClipboardItem failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != int(id) {
+		t.Errorf("Removed should receive %d, got %v", id, removed)
+	}
+
+	other, err := InsertClipboardItem("other", "text")
+	if err != nil {
+		t.Fatalf("InsertClipboardItem failed: %v", err)
+	}
+	if err := DeleteAllClipboardItems(); err != nil {
+		t.Fatalf("DeleteAllClipboardItems failed: %v", err)
+	}
+	if len(removed) != 2 || removed[1] != int(other) || cleared != 1 {
+		t.Errorf("clearing should report each item, then Cleared once; got %v and %d", removed, cleared)
+	}
+
+	remove()
+	if _, err := InsertClipboardItem("after", "text"); err != nil {
+		t.Fatalf("InsertClipboardItem failed: %v", err)
+	}
+	if len(added) != 2 {
+		t.Errorf("a removed listener should not be called, got %d items", len(added))
+	}
+}
+
+func TestItemListener_HistoryLimit(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var ids []int
+	for i := 0; i <= MaxHistoryItems; i++ {
+		id, err := InsertClipboardItem(fmt.Sprintf("item %d", i), "text")
+		if err != nil {
+			t.Fatalf("InsertClipboardItem failed: %v", err)
+		}
+		ids = append(ids, int(id))
+	}
+	if _, err := db.Exec(`UPDATE clipboard_history SET created_at = datetime('now', '-1 hour') WHERE id = ?`, ids[0]); err != nil {
+		t.Fatalf("Failed to age item: %v", err)
+	}
+
+	var removed []int
+	defer AddItemListener(ItemListener{Removed: func(id int, pruned bool) {
+		if !pruned {
+			t.Errorf("item %d should be reported as pruned", id)
+		}
+		removed = append(removed, id)
+	}})()
+
+	if err := EnforceHistoryLimit(); err != nil {
+		t.Fatalf("EnforceHistoryLimit failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != ids[0] {
+		t.Errorf("pruning should report the oldest item %d, got %v", ids[0], removed)
+	}
+}
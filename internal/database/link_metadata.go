@@ -0,0 +1,23 @@
+package database
+
+import (
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// GetLinkMetadata returns the cached metadata for a link, or sql.ErrNoRows
+func GetLinkMetadata(url string) (*models.LinkMetadata, error) {
+	stmt := `SELECT url, COALESCE(title, ''), COALESCE(canonical_url, ''), COALESCE(error, ''), fetched_at FROM link_metadata WHERE url = ?`
+	var meta models.LinkMetadata
+	err := db.QueryRow(stmt, url).Scan(&meta.URL, &meta.Title, &meta.CanonicalURL, &meta.Error, &meta.FetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// SaveLinkMetadata stores or replaces the cached metadata for a link
+func SaveLinkMetadata(meta models.LinkMetadata) error {
+	stmt := `INSERT OR REPLACE INTO link_metadata (url, title, canonical_url, error, fetched_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(stmt, meta.URL, nullIfEmpty(meta.Title), nullIfEmpty(meta.CanonicalURL), nullIfEmpty(meta.Error), meta.FetchedAt)
+	return err
+}
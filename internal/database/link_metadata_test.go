@@ -0,0 +1,61 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestLinkMetadata_CacheAndItemTitle(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := GetLinkMetadata("https://example.com/"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Expected sql.ErrNoRows for uncached link, got %v", err)
+	}
+
+	if _, err := InsertClipboardItem("https://example.com/", "link"); err != nil {
+		t.Fatalf("InsertClipboardItem failed: %v", err)
+	}
+
+	meta := models.LinkMetadata{
+		URL:          "https://example.com/",
+		Title:        "Example Domain",
+		CanonicalURL: "https://example.com/",
+		FetchedAt:    time.Now(),
+	}
+	if err := SaveLinkMetadata(meta); err != nil {
+		t.Fatalf("SaveLinkMetadata failed: %v", err)
+	}
+
+	cached, err := GetLinkMetadata("https://example.com/")
+	if err != nil {
+		t.Fatalf("GetLinkMetadata failed: %v", err)
+	}
+	if cached.Title != "Example Domain" || cached.Error != "" {
+		t.Errorf("Unexpected cached metadata: %+v", cached)
+	}
+
+	item, err := GetItemByContent("https://example.com/")
+	if err != nil {
+		t.Fatalf("GetItemByContent failed: %v", err)
+	}
+	if item.Title != "Example Domain" {
+		t.Errorf("Expected item title from cache, got %q", item.Title)
+	}
+
+	// A later failed lookup replaces the entry
+	if err := SaveLinkMetadata(models.LinkMetadata{URL: meta.URL, FetchedAt: time.Now(), Error: "timeout"}); err != nil {
+		t.Fatalf("SaveLinkMetadata failed: %v", err)
+	}
+	cached, err = GetLinkMetadata("https://example.com/")
+	if err != nil {
+		t.Fatalf("GetLinkMetadata failed: %v", err)
+	}
+	if cached.Title != "" || cached.Error != "timeout" {
+		t.Errorf("Unexpected cached metadata after failure: %+v", cached)
+	}
+}
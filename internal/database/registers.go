@@ -0,0 +1,111 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// RegisterNames lists the valid register names in display order: a-z, then 1-9
+func RegisterNames() []string {
+	var names []string
+	for r := 'a'; r <= 'z'; r++ {
+		names = append(names, string(r))
+	}
+	for r := '1'; r <= '9'; r++ {
+		names = append(names, string(r))
+	}
+	return names
+}
+
+// IsValidRegister reports whether name is a single letter a-z or digit 1-9
+func IsValidRegister(name string) bool {
+	return len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= '1' && name[0] <= '9')
+}
+
+// SetRegister points a register at a history item, replacing what it held
+func SetRegister(name string, itemID int) error {
+	if !IsValidRegister(name) {
+		return fmt.Errorf("invalid register %q: use a-z or 1-9", name)
+	}
+	_, err := db.Exec(`INSERT OR REPLACE INTO registers (name, item_id, assigned_at) VALUES (?, ?, CURRENT_TIMESTAMP)`, name, itemID)
+	return err
+}
+
+// ClearRegister empties a register; the item itself stays in history
+func ClearRegister(name string) error {
+	_, err := db.Exec(`DELETE FROM registers WHERE name = ?`, name)
+	return err
+}
+
+// GetRegisterItem returns the item held by a register, or sql.ErrNoRows if it is empty
+func GetRegisterItem(name string) (*models.ClipboardItem, error) {
+	stmt := `SELECT ` + itemColumns + ` FROM clipboard_history WHERE id = (SELECT item_id FROM registers WHERE name = ?)`
+	item, err := scanItem(db.QueryRow(stmt, name))
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListRegisters returns the filled registers in display order
+func ListRegisters() ([]models.Register, error) {
+	stmt := `SELECT r.name, ` + itemColumns + `
+			 FROM registers r JOIN clipboard_history ON clipboard_history.id = r.item_id`
+	rows, err := db.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byName := make(map[string]models.Register)
+	for rows.Next() {
+		var name string
+		item, err := scanItem(prefixScanner{row: rows, first: &name})
+		if err != nil {
+			return nil, err
+		}
+		byName[name] = models.Register{Name: name, Item: item}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var registers []models.Register
+	for _, name := range RegisterNames() {
+		if r, ok := byName[name]; ok {
+			registers = append(registers, r)
+		}
+	}
+	return registers, nil
+}
+
+// prefixScanner scans one extra leading column before the item columns
+type prefixScanner struct {
+	row   rowScanner
+	first any
+}
+
+func (p prefixScanner) Scan(dest ...any) error {
+	return p.row.Scan(append([]any{p.first}, dest...)...)
+}
+
+// splitRegisters turns the comma-separated names read with an item into a
+// list in display order
+func splitRegisters(names string) []string {
+	if names == "" {
+		return nil
+	}
+	list := strings.Split(names, ",")
+	sort.Slice(list, func(i, j int) bool {
+		// Letters sort before digits, as in RegisterNames
+		iDigit, jDigit := list[i][0] <= '9', list[j][0] <= '9'
+		if iDigit != jDigit {
+			return jDigit
+		}
+		return list[i] < list[j]
+	})
+	return list
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRegisters_SetGetList(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	first, _ := InsertClipboardItem("first", "text")
+	second, _ := InsertClipboardItem("second", "text")
+
+	for name, id := range map[string]int64{"1": first, "b": second, "a": first} {
+		if err := SetRegister(name, int(id)); err != nil {
+			t.Fatalf("SetRegister(%q) failed: %v", name, err)
+		}
+	}
+	if err := SetRegister("0", int(first)); err == nil {
+		t.Error("expected register 0 to be rejected")
+	}
+
+	item, err := GetRegisterItem("b")
+	if err != nil {
+		t.Fatalf("GetRegisterItem failed: %v", err)
+	}
+	if item.Content != "second" {
+		t.Errorf("register b holds %q", item.Content)
+	}
+
+	item, err = GetItemByContent("first")
+	if err != nil {
+		t.Fatalf("GetItemByContent failed: %v", err)
+	}
+	if !reflect.DeepEqual(item.Registers, []string{"a", "1"}) {
+		t.Errorf("Registers = %v, want [a 1]", item.Registers)
+	}
+
+	registers, err := ListRegisters()
+	if err != nil {
+		t.Fatalf("ListRegisters failed: %v", err)
+	}
+	var names []string
+	for _, r := range registers {
+		names = append(names, r.Name+"="+r.Item.Content)
+	}
+	if !reflect.DeepEqual(names, []string{"a=first", "b=second", "1=first"}) {
+		t.Errorf("ListRegisters = %v", names)
+	}
+
+	if err := ClearRegister("b"); err != nil {
+		t.Fatalf("ClearRegister failed: %v", err)
+	}
+	if _, err := GetRegisterItem("b"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected empty register after clear, got %v", err)
+	}
+}
+
+func TestRegisters_SurviveLimitAndClearAll(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	oldest, _ := InsertClipboardItem("kept in register", "text")
+	if _, err := db.Exec("UPDATE clipboard_history SET created_at = datetime('now', '-1 day') WHERE id = ?", oldest); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRegister("q", int(oldest)); err != nil {
+		t.Fatalf("SetRegister failed: %v", err)
+	}
+	for i := 0; i < MaxHistoryItems+5; i++ {
+		if _, err := InsertClipboardItem(fmt.Sprintf("item %d", i), "text"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := EnforceHistoryLimit(); err != nil {
+		t.Fatalf("EnforceHistoryLimit failed: %v", err)
+	}
+	if _, err := GetRegisterItem("q"); err != nil {
+		t.Fatalf("registered item was removed by the history limit: %v", err)
+	}
+
+	if err := DeleteAllClipboardItems(); err != nil {
+		t.Fatalf("DeleteAllClipboardItems failed: %v", err)
+	}
+	count, _ := GetHistoryCount()
+	if count != 1 {
+		t.Errorf("expected only the registered item to remain, got %d items", count)
+	}
+	if _, err := GetRegisterItem("q"); err != nil {
+		t.Errorf("registered item was removed by Clear All: %v", err)
+	}
+
+	// Deleting the item itself empties the register
+	if err := DeleteClipboardItem(int(oldest)); err != nil {
+		t.Fatalf("DeleteClipboardItem failed: %v", err)
+	}
+	if _, err := GetRegisterItem("q"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected register to be emptied with its item, got %v", err)
+	}
+}
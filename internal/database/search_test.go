@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestGetItemByID(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	id, _ := InsertClipboardItem("by id", "text")
+	item, err := GetItemByID(int(id))
+	if err != nil {
+		t.Fatalf("GetItemByID failed: %v", err)
+	}
+	if item.Content != "by id" {
+		t.Errorf("got content %q", item.Content)
+	}
+	if item.CreatedAt.IsZero() {
+		t.Error("expected created_at to be read")
+	}
+
+	if _, err := GetItemByID(int(id) + 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing item, got %v", err)
+	}
+}
+
+func TestSearchClipboardHistory(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	InsertClipboardItem("Deploy checklist", "text")
+	InsertClipboardItem("100% done", "text")
+	InsertClipboardItem("1000 done", "text")
+	InsertClipboardItem("https://example.com/a", "link")
+	if err := SaveLinkMetadata(models.LinkMetadata{URL: "https://example.com/a", Title: "Release Notes", FetchedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"deploy", []string{"Deploy checklist"}},
+		{"0%", []string{"100% done"}},
+		{"release", []string{"https://example.com/a"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		items, err := SearchClipboardHistory(tt.query, 10)
+		if err != nil {
+			t.Fatalf("SearchClipboardHistory(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Content)
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("SearchClipboardHistory(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	stats, err := GetStats()
+	if err != nil {
+		t.Fatalf("GetStats on empty history failed: %v", err)
+	}
+	if stats.Total != 0 || !stats.Oldest.IsZero() {
+		t.Errorf("unexpected stats for empty history: %+v", stats)
+	}
+
+	a, _ := InsertClipboardItem("a", "text")
+	b, _ := InsertClipboardItem("b", "text")
+	InsertClipboardItem("https://example.com", "link")
+	UpdateItemFavorite(int(a), true)
+	UpdateItemSensitivity(int(b), true)
+	SetRegister("1", int(a))
+
+	stats, err = GetStats()
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.Total != 3 || stats.ByType["text"] != 2 || stats.ByType["link"] != 1 {
+		t.Errorf("unexpected counts: %+v", stats)
+	}
+	if stats.Favorites != 1 || stats.Sensitive != 1 || stats.Registers != 1 {
+		t.Errorf("unexpected flag counts: %+v", stats)
+	}
+	if stats.Newest.IsZero() || stats.Newest.Before(stats.Oldest) {
+		t.Errorf("unexpected time range: %v – %v", stats.Oldest, stats.Newest)
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import "time"
+
+// Stats summarizes the stored history
+type Stats struct {
+	Total     int            `json:"total"`
+	ByType    map[string]int `json:"by_type"`
+	Favorites int            `json:"favorites"`
+	Sensitive int            `json:"sensitive"`
+	Registers int            `json:"registers"`
+	Oldest    time.Time      `json:"oldest,omitzero"`
+	Newest    time.Time      `json:"newest,omitzero"`
+}
+
+// GetStats counts the items in history by type and flag
+func GetStats() (Stats, error) {
+	stats := Stats{ByType: make(map[string]int)}
+
+	rows, err := db.Query(`SELECT type, COUNT(*) FROM clipboard_history GROUP BY type`)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemType string
+		var count int
+		if err := rows.Scan(&itemType, &count); err != nil {
+			return stats, err
+		}
+		stats.ByType[itemType] = count
+		stats.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	err = db.QueryRow(`SELECT
+		COALESCE(SUM(COALESCE(is_favorite, 0)), 0),
+		COALESCE(SUM(COALESCE(is_sensitive, 0)), 0),
+		(SELECT COUNT(*) FROM registers)
+		FROM clipboard_history`).Scan(&stats.Favorites, &stats.Sensitive, &stats.Registers)
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.Total > 0 {
+		if err := db.QueryRow(`SELECT created_at FROM clipboard_history ORDER BY created_at ASC LIMIT 1`).Scan(&stats.Oldest); err != nil {
+			return stats, err
+		}
+		if err := db.QueryRow(`SELECT created_at FROM clipboard_history ORDER BY created_at DESC LIMIT 1`).Scan(&stats.Newest); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// SyncRecord is what folder sync knows about one item shared between devices:
+// its global ID, the version that last changed it and what that version held
+type SyncRecord struct {
+	UID      string
+	ItemID   int // Local item, zero once deleted
+	Clock    uint64
+	Device   string
+	Deleted  bool
+	Digest   string // Hash of the synced content, to tell real edits from echoes
+	Favorite bool
+}
+
+const syncColumns = `uid, COALESCE(item_id, 0), clock, device, deleted, digest, favorite`
+
+func scanSyncRecord(row rowScanner) (SyncRecord, error) {
+	var r SyncRecord
+	err := row.Scan(&r.UID, &r.ItemID, &r.Clock, &r.Device, &r.Deleted, &r.Digest, &r.Favorite)
+	return r, err
+}
+
+// GetSyncRecord returns the record for uid; ok is false when there is none
+func GetSyncRecord(uid string) (record SyncRecord, ok bool, err error) {
+	record, err = scanSyncRecord(db.QueryRow(`SELECT `+syncColumns+` FROM sync_items WHERE uid = ?`, uid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return SyncRecord{}, false, nil
+	}
+	return record, err == nil, err
+}
+
+// GetSyncRecordsForItem returns the records pointing at a local item. There
+// can be several when devices captured the same content independently.
+func GetSyncRecordsForItem(itemID int) ([]SyncRecord, error) {
+	rows, err := db.Query(`SELECT `+syncColumns+` FROM sync_items WHERE item_id = ? AND deleted = 0 ORDER BY uid`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []SyncRecord
+	for rows.Next() {
+		r, err := scanSyncRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// SaveSyncRecord inserts or replaces the record for r.UID
+func SaveSyncRecord(r SyncRecord) error {
+	var itemID any
+	if r.ItemID != 0 {
+		itemID = r.ItemID
+	}
+	_, err := db.Exec(`INSERT OR REPLACE INTO sync_items (uid, item_id, clock, device, deleted, digest, favorite)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, r.UID, itemID, r.Clock, r.Device, r.Deleted, r.Digest, r.Favorite)
+	return err
+}
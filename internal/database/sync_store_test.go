@@ -0,0 +1,55 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestSyncRecords(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, ok, err := GetSyncRecord("missing"); err != nil || ok {
+		t.Fatalf("Expected no record, got ok=%v err=%v", ok, err)
+	}
+
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	id, err := InsertTextItem(models.ClipboardItem{Content: "synced", Type: "text", IsFavorite: true, CreatedAt: created})
+	if err != nil {
+		t.Fatalf("InsertTextItem failed: %v", err)
+	}
+	item, err := GetItemByID(int(id))
+	if err != nil {
+		t.Fatalf("GetItemByID failed: %v", err)
+	}
+	if !item.CreatedAt.Equal(created) {
+		t.Errorf("Expected the given creation time %v, got %v", created, item.CreatedAt)
+	}
+
+	record := SyncRecord{UID: "abc", ItemID: int(id), Clock: 7, Device: "dev1", Digest: "d", Favorite: true}
+	if err := SaveSyncRecord(record); err != nil {
+		t.Fatalf("SaveSyncRecord failed: %v", err)
+	}
+	got, ok, err := GetSyncRecord("abc")
+	if err != nil || !ok || got != record {
+		t.Errorf("Expected %+v, got %+v (ok=%v err=%v)", record, got, ok, err)
+	}
+	records, err := GetSyncRecordsForItem(int(id))
+	if err != nil || len(records) != 1 || records[0] != record {
+		t.Errorf("Expected the record for the item, got %+v (%v)", records, err)
+	}
+
+	// A tombstone keeps its clock but no longer belongs to the item
+	tombstone := SyncRecord{UID: "abc", Clock: 8, Device: "dev2", Deleted: true}
+	if err := SaveSyncRecord(tombstone); err != nil {
+		t.Fatalf("SaveSyncRecord failed: %v", err)
+	}
+	if got, _, _ := GetSyncRecord("abc"); got != tombstone {
+		t.Errorf("Expected %+v, got %+v", tombstone, got)
+	}
+	if records, _ := GetSyncRecordsForItem(int(id)); len(records) != 0 {
+		t.Errorf("Expected no records for the item after the delete, got %+v", records)
+	}
+}
@@ -0,0 +1,143 @@
+// Package dbusapi exports the running instance's history on the D-Bus session
+// bus as org.pastee.Clipboard, for desktop integrations and gdbus scripts.
+package dbusapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// Names of the exported service, object and interface
+const (
+	BusName    = "org.pastee.Clipboard"
+	ObjectPath = dbus.ObjectPath("/org/pastee/Clipboard")
+	Interface  = "org.pastee.Clipboard"
+)
+
+// ErrNameTaken is returned by Export when another process owns BusName
+var ErrNameTaken = errors.New(BusName + " is already owned by another process")
+
+// Item is a history item as sent over D-Bus, signature (xssssbbx). Created
+// is a Unix timestamp in seconds.
+type Item struct {
+	ID        int64
+	Type      string
+	Content   string
+	ImagePath string
+	Title     string
+	Favorite  bool
+	Sensitive bool
+	Created   int64
+}
+
+// Backend does the work behind each method; the running instance provides it
+type Backend interface {
+	List(limit int, query string) ([]Item, error) // Newest first; an empty query lists everything
+	Get(id int) (Item, error)
+	Copy(id int) error
+	Pause(seconds int) error // Zero pauses until Resume
+	Resume() error
+	Show() error
+}
+
+// Service owns BusName on a connection and emits the history signals
+type Service struct {
+	conn *dbus.Conn
+}
+
+// Export publishes backend at ObjectPath and claims BusName
+func Export(conn *dbus.Conn, backend Backend) (*Service, error) {
+	obj := &object{backend: backend}
+	if err := conn.Export(obj, ObjectPath, Interface); err != nil {
+		return nil, err
+	}
+	node := &introspect.Node{
+		Name: string(ObjectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{Name: Interface, Methods: introspect.Methods(obj), Signals: signals},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), ObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %w", BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return nil, ErrNameTaken
+	}
+	return &Service{conn: conn}, nil
+}
+
+// ItemAdded announces a new history item
+func (s *Service) ItemAdded(item Item) error {
+	return s.conn.Emit(ObjectPath, Interface+".ItemAdded", item)
+}
+
+// ItemRemoved announces that an item was deleted
+func (s *Service) ItemRemoved(id int) error {
+	return s.conn.Emit(ObjectPath, Interface+".ItemRemoved", int64(id))
+}
+
+// HistoryCleared announces that everything not held in a register was deleted
+func (s *Service) HistoryCleared() error {
+	return s.conn.Emit(ObjectPath, Interface+".HistoryCleared")
+}
+
+// signals describes the signals for introspection
+var signals = []introspect.Signal{
+	{Name: "ItemAdded", Args: []introspect.Arg{{Name: "item", Type: "(xssssbbx)"}}},
+	{Name: "ItemRemoved", Args: []introspect.Arg{{Name: "id", Type: "x"}}},
+	{Name: "HistoryCleared"},
+}
+
+// object carries the exported methods; every exported method becomes a D-Bus method
+type object struct {
+	backend Backend
+}
+
+func failed(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
+
+// ListItems returns the newest items; limit 0 returns all of them
+func (o *object) ListItems(limit int32) ([]Item, *dbus.Error) {
+	items, err := o.backend.List(int(limit), "")
+	return items, failed(err)
+}
+
+// Search returns the newest items whose content or link title contains query
+func (o *object) Search(query string, limit int32) ([]Item, *dbus.Error) {
+	items, err := o.backend.List(int(limit), query)
+	return items, failed(err)
+}
+
+func (o *object) GetItem(id int64) (Item, *dbus.Error) {
+	item, err := o.backend.Get(int(id))
+	return item, failed(err)
+}
+
+func (o *object) CopyItem(id int64) *dbus.Error {
+	return failed(o.backend.Copy(int(id)))
+}
+
+func (o *object) Pause(seconds uint32) *dbus.Error {
+	return failed(o.backend.Pause(int(seconds)))
+}
+
+func (o *object) Resume() *dbus.Error {
+	return failed(o.backend.Resume())
+}
+
+func (o *object) Show() *dbus.Error {
+	return failed(o.backend.Show())
+}
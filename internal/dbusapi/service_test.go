@@ -0,0 +1,278 @@
+package dbusapi
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// busConfig is a minimal session bus that lets every local client talk to every other
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startBus runs a private dbus-daemon for the test and returns its address
+func startBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf(busConfig, filepath.Join(dir, "bus"))), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configPath, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connect(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connecting to bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// fakeBackend serves a fixed list of items and records what was asked of it
+type fakeBackend struct {
+	items  []Item
+	copied []int
+	paused []int
+	resume int
+	shown  int
+}
+
+func (f *fakeBackend) List(limit int, query string) ([]Item, error) {
+	var out []Item
+	for _, item := range f.items {
+		if query != "" && !strings.Contains(item.Content, query) {
+			continue
+		}
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func (f *fakeBackend) Get(id int) (Item, error) {
+	for _, item := range f.items {
+		if item.ID == int64(id) {
+			return item, nil
+		}
+	}
+	return Item{}, fmt.Errorf("no item with id %d", id)
+}
+
+func (f *fakeBackend) Copy(id int) error {
+	if _, err := f.Get(id); err != nil {
+		return err
+	}
+	f.copied = append(f.copied, id)
+	return nil
+}
+
+func (f *fakeBackend) Pause(seconds int) error {
+	f.paused = append(f.paused, seconds)
+	return nil
+}
+
+func (f *fakeBackend) Resume() error {
+	f.resume++
+	return nil
+}
+
+func (f *fakeBackend) Show() error {
+	f.shown++
+	return nil
+}
+
+func TestServiceMethods(t *testing.T) {
+	address := startBus(t)
+	backend := &fakeBackend{items: []Item{
+		{ID: 2, Type: "link", Content: "https://example.com", Title: "Example", Created: 1700000100},
+		{ID: 1, Type: "text", Content: "hello world", Favorite: true, Created: 1700000000},
+	}}
+	if _, err := Export(connect(t, address), backend); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	obj := connect(t, address).Object(BusName, ObjectPath)
+
+	var items []Item
+	if err := obj.Call(Interface+".ListItems", 0, int32(0)).Store(&items); err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	if len(items) != 2 || items[0] != backend.items[0] || items[1] != backend.items[1] {
+		t.Errorf("ListItems returned %+v", items)
+	}
+
+	if err := obj.Call(Interface+".Search", 0, "hello", int32(10)).Store(&items); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("Search returned %+v", items)
+	}
+
+	var item Item
+	if err := obj.Call(Interface+".GetItem", 0, int64(2)).Store(&item); err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item.Title != "Example" {
+		t.Errorf("GetItem returned %+v", item)
+	}
+
+	if err := obj.Call(Interface+".CopyItem", 0, int64(1)).Err; err != nil {
+		t.Fatalf("CopyItem failed: %v", err)
+	}
+	if err := obj.Call(Interface+".Pause", 0, uint32(300)).Err; err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if err := obj.Call(Interface+".Resume", 0).Err; err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if err := obj.Call(Interface+".Show", 0).Err; err != nil {
+		t.Fatalf("Show failed: %v", err)
+	}
+	if len(backend.copied) != 1 || backend.copied[0] != 1 || len(backend.paused) != 1 || backend.paused[0] != 300 ||
+		backend.resume != 1 || backend.shown != 1 {
+		t.Errorf("backend calls: %+v", backend)
+	}
+
+	err := obj.Call(Interface+".GetItem", 0, int64(99)).Store(&item)
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) || dbusErr.Name != "org.freedesktop.DBus.Error.Failed" || !strings.Contains(err.Error(), "no item with id 99") {
+		t.Errorf("GetItem of a missing item should fail with the backend's message, got %v", err)
+	}
+}
+
+func TestServiceSignals(t *testing.T) {
+	address := startBus(t)
+	service, err := Export(connect(t, address), &fakeBackend{})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	listener := connect(t, address)
+	if err := listener.AddMatchSignal(dbus.WithMatchInterface(Interface), dbus.WithMatchObjectPath(ObjectPath)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	listener.Signal(signals)
+
+	added := Item{ID: 7, Type: "text", Content: "new", Created: 1700000000}
+	if err := service.ItemAdded(added); err != nil {
+		t.Fatalf("ItemAdded failed: %v", err)
+	}
+	if err := service.ItemRemoved(7); err != nil {
+		t.Fatalf("ItemRemoved failed: %v", err)
+	}
+	if err := service.HistoryCleared(); err != nil {
+		t.Fatalf("HistoryCleared failed: %v", err)
+	}
+
+	next := func() *dbus.Signal {
+		select {
+		case sig := <-signals:
+			return sig
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a signal")
+			return nil
+		}
+	}
+
+	sig := next()
+	var got Item
+	if sig.Name != Interface+".ItemAdded" || dbus.Store(sig.Body, &got) != nil || got != added {
+		t.Errorf("expected ItemAdded with %+v, got %s %v", added, sig.Name, sig.Body)
+	}
+	sig = next()
+	if sig.Name != Interface+".ItemRemoved" || len(sig.Body) != 1 || sig.Body[0] != int64(7) {
+		t.Errorf("expected ItemRemoved(7), got %s %v", sig.Name, sig.Body)
+	}
+	sig = next()
+	if sig.Name != Interface+".HistoryCleared" {
+		t.Errorf("expected HistoryCleared, got %s", sig.Name)
+	}
+}
+
+func TestExportNameTaken(t *testing.T) {
+	address := startBus(t)
+	if _, err := Export(connect(t, address), &fakeBackend{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := Export(connect(t, address), &fakeBackend{}); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("a second Export should fail with ErrNameTaken, got %v", err)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	address := startBus(t)
+	if _, err := Export(connect(t, address), &fakeBackend{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	node, err := introspect.Call(connect(t, address).Object(BusName, ObjectPath))
+	if err != nil {
+		t.Fatalf("Introspect failed: %v", err)
+	}
+	members := map[string]bool{}
+	for _, iface := range node.Interfaces {
+		if iface.Name != Interface {
+			continue
+		}
+		for _, m := range iface.Methods {
+			members[m.Name] = true
+		}
+		for _, s := range iface.Signals {
+			members[s.Name] = true
+		}
+	}
+	for _, name := range []string{"ListItems", "GetItem", "CopyItem", "Search", "Pause", "Resume", "Show", "ItemAdded", "ItemRemoved", "HistoryCleared"} {
+		if !members[name] {
+			t.Errorf("introspection data is missing %s", name)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package encryption
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -11,7 +11,7 @@ import (
 )
 
 func MigrateToEncrypted(unencryptedPath, encryptedPath, key string) error {
-	log.Printf("Starting migration from %s to %s", unencryptedPath, encryptedPath)
+	slog.Info("Starting migration", "from", unencryptedPath, "to", encryptedPath)
 
 	sourceDB, err := sql.Open("sqlite3", unencryptedPath)
 	if err != nil {
@@ -43,7 +43,7 @@ func MigrateToEncrypted(unencryptedPath, encryptedPath, key string) error {
 		return fmt.Errorf("failed to commit migration: %w", err)
 	}
 
-	log.Println("Migration completed successfully")
+	slog.Info("Migration completed successfully")
 	return nil
 }
 
@@ -111,7 +111,7 @@ func copyData(src *sql.DB, dest *sql.Tx) error {
 		count++
 	}
 
-	log.Printf("Migrated %d clipboard items", count)
+	slog.Info("Migrated clipboard items", "count", count)
 	return nil
 }
 
@@ -127,6 +127,6 @@ func BackupDatabase(path string) (string, error) {
 		return "", fmt.Errorf("failed to write backup file: %w", err)
 	}
 
-	log.Printf("Created backup at %s", backupPath)
+	slog.Info("Created backup", "path", backupPath)
 	return backupPath, nil
 }
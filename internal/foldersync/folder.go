@@ -0,0 +1,237 @@
+package foldersync
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Layout of the shared folder: a header, one directory of operation files
+// per device, and the encrypted images those operations refer to
+const (
+	headerFile = "pastee-sync.json"
+	opsDir     = "ops"
+	blobsDir   = "blobs"
+	opExt      = ".op"
+	blobExt    = ".blob"
+)
+
+// formatVersion is written to the header and every operation file
+const formatVersion = 1
+
+// kdfIterations is the PBKDF2-SHA256 cost of turning the passphrase into keys
+var kdfIterations = 600_000
+
+// checkPlaintext is sealed into the header so a wrong passphrase is detected up front
+var checkPlaintext = []byte("pastee-sync")
+
+// ErrWrongPassphrase is returned by New when the passphrase does not match the folder's
+var ErrWrongPassphrase = errors.New("sync passphrase does not match the one the folder was set up with")
+
+type header struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Check   []byte `json:"check"`
+}
+
+// keys encrypts operations and images and names image blobs
+type keys struct {
+	aead cipher.AEAD
+	mac  []byte
+}
+
+// openFolder derives the keys for folder from passphrase, writing the folder's
+// header when this is the first device to use it
+func openFolder(folder, passphrase string) (*keys, error) {
+	if err := os.MkdirAll(filepath.Join(folder, blobsDir), 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(folder, headerFile)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createHeader(path, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var h header
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", headerFile, err)
+	}
+	if h.Version != formatVersion {
+		return nil, fmt.Errorf("sync folder uses format version %d; this version of pastee supports %d", h.Version, formatVersion)
+	}
+	k, err := deriveKeys(passphrase, h.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.aead.Open(nil, h.Nonce, h.Check, nil); err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return k, nil
+}
+
+func createHeader(path, passphrase string) (*keys, error) {
+	h := header{Version: formatVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(h.Salt); err != nil {
+		return nil, err
+	}
+	k, err := deriveKeys(passphrase, h.Salt)
+	if err != nil {
+		return nil, err
+	}
+	h.Nonce, h.Check = k.seal(checkPlaintext, nil)
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// Another device may have set up the folder at the same moment; keep its header
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return openFolder(filepath.Dir(path), passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return k, f.Close()
+}
+
+func deriveKeys(passphrase string, salt []byte) (*keys, error) {
+	if passphrase == "" {
+		return nil, errors.New("sync passphrase is empty")
+	}
+	material, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, 64)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(material[:32])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &keys{aead: aead, mac: material[32:]}, nil
+}
+
+func (k *keys) seal(plaintext, additional []byte) (nonce, ciphertext []byte) {
+	nonce = make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return nonce, k.aead.Seal(nil, nonce, plaintext, additional)
+}
+
+func (k *keys) open(nonce, ciphertext, additional []byte) ([]byte, error) {
+	if len(nonce) != k.aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	return k.aead.Open(nil, nonce, ciphertext, additional)
+}
+
+// blobName names an image by a keyed hash, so equal images share a blob
+// without the name revealing the image
+func (k *keys) blobName(data []byte) string {
+	mac := hmac.New(sha256.New, k.mac)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// envelope is the on-disk form of an operation: encrypted with the folder
+// key and signed by the device that wrote it
+type envelope struct {
+	Version   int    `json:"v"`
+	Device    string `json:"device"`
+	PublicKey []byte `json:"key"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+	Signature []byte `json:"sig"`
+}
+
+// identity binds the ciphertext to the device that wrote it
+func (e *envelope) identity() []byte {
+	return append([]byte(e.Device+"\x00"), e.PublicKey...)
+}
+
+func (e *envelope) signedBytes() []byte {
+	b := []byte("pastee-sync-op\x00")
+	b = append(b, e.identity()...)
+	b = append(b, e.Nonce...)
+	return append(b, e.Data...)
+}
+
+// sealOp encrypts and signs op for the device holding priv
+func (k *keys) sealOp(op Op, priv ed25519.PrivateKey) ([]byte, error) {
+	plaintext, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	e := envelope{Version: formatVersion, Device: op.Device, PublicKey: priv.Public().(ed25519.PublicKey)}
+	e.Nonce, e.Data = k.seal(plaintext, e.identity())
+	e.Signature = ed25519.Sign(priv, e.signedBytes())
+	return json.Marshal(e)
+}
+
+// openOp checks the signature and decrypts an operation file. It returns the
+// signing key so the caller can check it against the one pinned for the device.
+func (k *keys) openOp(data []byte) (Op, ed25519.PublicKey, error) {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Op{}, nil, fmt.Errorf("invalid operation file: %w", err)
+	}
+	if e.Version != formatVersion {
+		return Op{}, nil, fmt.Errorf("unsupported operation format %d", e.Version)
+	}
+	if len(e.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(e.PublicKey, e.signedBytes(), e.Signature) {
+		return Op{}, nil, errors.New("invalid signature")
+	}
+	plaintext, err := k.open(e.Nonce, e.Data, e.identity())
+	if err != nil {
+		return Op{}, nil, errors.New("cannot decrypt operation")
+	}
+	var op Op
+	if err := json.Unmarshal(plaintext, &op); err != nil {
+		return Op{}, nil, fmt.Errorf("invalid operation: %w", err)
+	}
+	if op.Device != e.Device {
+		return Op{}, nil, errors.New("operation device does not match its signer")
+	}
+	return op, e.PublicKey, nil
+}
+
+// writeFileAtomic writes data next to path and renames it into place, so
+// other devices never sync a half-written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
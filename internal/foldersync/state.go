@@ -0,0 +1,93 @@
+package foldersync
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// StateFileName is the file in the data directory holding this device's sync identity
+const StateFileName = "sync-state.json"
+
+// state is what one device keeps locally: who it is, its Lamport clock, the
+// signing keys it has seen for other devices and which of their operation
+// files it has already replayed
+type state struct {
+	DeviceID   string             `json:"device_id"`
+	PrivateKey ed25519.PrivateKey `json:"private_key"`
+	Clock      uint64             `json:"clock"`
+	Peers      map[string][]byte  `json:"peers"`    // Device ID to its pinned public key
+	Replayed   map[string]bool    `json:"replayed"` // Operation files by path relative to ops/
+}
+
+// loadState reads the state at path, creating a new device identity when there is none
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newState()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("invalid sync state %s: %w", path, err)
+	}
+	if st.DeviceID == "" || len(st.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid sync state %s: missing device identity", path)
+	}
+	if st.Peers == nil {
+		st.Peers = make(map[string][]byte)
+	}
+	if st.Replayed == nil {
+		st.Replayed = make(map[string]bool)
+	}
+	return &st, nil
+}
+
+func newState() (*state, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &state{
+		DeviceID:   randomID(8),
+		PrivateKey: priv,
+		Peers:      make(map[string][]byte),
+		Replayed:   make(map[string]bool),
+	}, nil
+}
+
+func (st *state) save(path string) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// tick advances the clock for a local operation
+func (st *state) tick() uint64 {
+	st.Clock++
+	return st.Clock
+}
+
+// observe moves the clock past one seen in another device's operation
+func (st *state) observe(clock uint64) {
+	if clock > st.Clock {
+		st.Clock = clock
+	}
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
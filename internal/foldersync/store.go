@@ -0,0 +1,213 @@
+package foldersync
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/imageutil"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+// Operation kinds. Every kind but delete carries the whole item, so applying
+// the newest operation for an item is enough to converge.
+const (
+	KindInsert   = "insert"
+	KindEdit     = "edit"
+	KindFavorite = "favorite"
+	KindDelete   = "delete"
+)
+
+// Op is one change to one item, as written to the shared folder
+type Op struct {
+	Kind   string    `json:"kind"`
+	UID    string    `json:"uid"` // The item's ID shared by all devices
+	Clock  uint64    `json:"clock"`
+	Device string    `json:"device"`
+	Item   *ItemData `json:"item,omitempty"` // Nil for deletes
+}
+
+// newer reports whether version (clock, device) wins over (otherClock,
+// otherDevice): the higher clock wins, and the device ID breaks ties
+func newer(clock uint64, device string, otherClock uint64, otherDevice string) bool {
+	if clock != otherClock {
+		return clock > otherClock
+	}
+	return device > otherDevice
+}
+
+// ItemData is the synced part of an item
+type ItemData struct {
+	Type      string    `json:"type"`
+	Content   string    `json:"content,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Favorite  bool      `json:"favorite"`
+	Sensitive bool      `json:"sensitive"`
+	Blob      string    `json:"blob,omitempty"` // Encrypted image in blobs/, for image items
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// digest hashes everything but the favorite flag and time, so an edit can be
+// told from a favorite change and from the echo of an applied operation
+func (d ItemData) digest() string {
+	h := sha256.New()
+	for _, field := range []string{d.Type, d.Content, strconv.FormatBool(d.Sensitive), d.Blob} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Store is the local history as seen by the syncer
+type Store interface {
+	Items() ([]models.ClipboardItem, error)
+	Record(uid string) (database.SyncRecord, bool, error)
+	RecordsForItem(itemID int) ([]database.SyncRecord, error)
+	SaveRecord(r database.SyncRecord) error
+	// Put updates the item itemID, or stores data as a new item when itemID
+	// is zero, and returns the item's ID. image holds the picture of a new
+	// image item.
+	Put(itemID int, data ItemData, image []byte) (int, error)
+	Delete(itemID int) error
+}
+
+// DatabaseStore is the Store backed by the history database
+type DatabaseStore struct{}
+
+func (DatabaseStore) Items() ([]models.ClipboardItem, error) {
+	return database.GetClipboardHistory(-1)
+}
+
+func (DatabaseStore) Record(uid string) (database.SyncRecord, bool, error) {
+	return database.GetSyncRecord(uid)
+}
+
+func (DatabaseStore) RecordsForItem(itemID int) ([]database.SyncRecord, error) {
+	return database.GetSyncRecordsForItem(itemID)
+}
+
+func (DatabaseStore) SaveRecord(r database.SyncRecord) error {
+	return database.SaveSyncRecord(r)
+}
+
+func (s DatabaseStore) Put(itemID int, data ItemData, image []byte) (int, error) {
+	if itemID != 0 {
+		item, err := database.GetItemByID(itemID)
+		if err == nil {
+			return itemID, s.update(*item, data)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	// An item with the same content captured on this device becomes the synced one
+	var existing *models.ClipboardItem
+	var err error
+	if data.Type == monitor.TypeImage {
+		existing, err = database.GetItemByImageHash(monitor.ImageHash(image))
+	} else {
+		existing, err = database.GetItemByContent(data.Content)
+	}
+	if err == nil {
+		return existing.ID, s.update(*existing, data)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	id, err := s.insert(data, image)
+	if err != nil {
+		return 0, err
+	}
+	if err := database.EnforceHistoryLimit(); err != nil {
+		return 0, fmt.Errorf("error enforcing history limit: %w", err)
+	}
+	return id, nil
+}
+
+func (DatabaseStore) insert(data ItemData, image []byte) (int, error) {
+	if data.Type != monitor.TypeImage {
+		id, err := database.InsertTextItem(models.ClipboardItem{
+			Content:     data.Content,
+			Type:        data.Type,
+			Source:      data.Source,
+			IsFavorite:  data.Favorite,
+			IsSensitive: data.Sensitive,
+			CreatedAt:   data.CreatedAt,
+		})
+		return int(id), err
+	}
+
+	format := monitor.DetectImageFormat(image)
+	if format == "" {
+		return 0, errors.New("synced image has an unknown format")
+	}
+	fullPath, thumbPath, err := imageutil.SaveImage(image, format)
+	if err != nil {
+		return 0, err
+	}
+	id, err := database.InsertImageItem(fullPath, thumbPath, monitor.ImageHash(image), monitor.TypeImage)
+	if err != nil {
+		imageutil.DeleteImage(fullPath, thumbPath)
+		return 0, err
+	}
+	item := models.ClipboardItem{ID: int(id), Type: monitor.TypeImage}
+	return int(id), DatabaseStore{}.update(item, data)
+}
+
+// update brings item's content and flags in line with data
+func (DatabaseStore) update(item models.ClipboardItem, data ItemData) error {
+	if item.Type != monitor.TypeImage && (item.Content != data.Content || item.Type != data.Type) {
+		if err := database.UpdateItemContent(item.ID, data.Content, data.Type); err != nil {
+			return err
+		}
+	}
+	if item.IsFavorite != data.Favorite {
+		if err := database.UpdateItemFavorite(item.ID, data.Favorite); err != nil {
+			return err
+		}
+	}
+	if item.IsSensitive != data.Sensitive {
+		if err := database.UpdateItemSensitivity(item.ID, data.Sensitive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (DatabaseStore) Delete(itemID int) error {
+	err := database.DeleteClipboardItem(itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// itemData reads the synced part of a local item, and its picture for image items
+func itemData(item models.ClipboardItem) (ItemData, []byte, error) {
+	data := ItemData{
+		Type:      item.Type,
+		Content:   item.Content,
+		Source:    item.Source,
+		Favorite:  item.IsFavorite,
+		Sensitive: item.IsSensitive,
+		CreatedAt: item.CreatedAt,
+	}
+	if item.Type != monitor.TypeImage {
+		return data, nil, nil
+	}
+	data.Content = ""
+	image, err := os.ReadFile(item.ImagePath)
+	if err != nil {
+		return data, nil, fmt.Errorf("reading image of item %d: %w", item.ID, err)
+	}
+	return data, image, nil
+}
@@ -0,0 +1,484 @@
+// Package foldersync keeps the history of several devices in step through a
+// folder they share, such as a Dropbox, Syncthing or network folder. Each
+// device appends encrypted, signed operation files to its own directory in
+// the folder and replays the ones written by the others; conflicting changes
+// to an item are settled by the newest Lamport clock.
+package foldersync
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Options configures a Syncer
+type Options struct {
+	Folder        string // The shared folder
+	Passphrase    string // Must be the same on every device
+	StatePath     string // Where this device keeps its identity and progress
+	FavoritesOnly bool   // Only share favorites
+	OnApplied     func() // Called after changes from other devices were applied, if set
+}
+
+// Syncer exports local changes to the shared folder and applies other devices' changes
+type Syncer struct {
+	store  Store
+	opts   Options
+	keys   *keys
+	events chan event
+
+	mu    sync.Mutex
+	state *state
+}
+
+type event struct {
+	item    *models.ClipboardItem // Added or updated
+	removed int                   // Otherwise, the deleted item's ID
+}
+
+// New opens the shared folder and loads or creates this device's identity
+func New(store Store, opts Options) (*Syncer, error) {
+	if opts.Folder == "" {
+		return nil, errors.New("no sync folder configured")
+	}
+	k, err := openFolder(opts.Folder, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState(opts.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Syncer{store: store, opts: opts, keys: k, state: st, events: make(chan event, 256)}
+	if err := os.MkdirAll(s.deviceDir(), 0700); err != nil {
+		return nil, err
+	}
+	return s, st.save(opts.StatePath)
+}
+
+// DeviceID returns the ID this device writes its operations under
+func (s *Syncer) DeviceID() string {
+	return s.state.DeviceID
+}
+
+func (s *Syncer) deviceDir() string {
+	return filepath.Join(s.opts.Folder, opsDir, s.state.DeviceID)
+}
+
+// Listener returns a database listener that queues local changes for Run
+func (s *Syncer) Listener() database.ItemListener {
+	queue := func(e event) {
+		select {
+		case s.events <- e:
+		default:
+			slog.Warn("Sync queue is full; the change will be exported on the next start")
+		}
+	}
+	changed := func(item models.ClipboardItem) { queue(event{item: &item}) }
+	return database.ItemListener{
+		Added:   changed,
+		Updated: changed,
+		Removed: func(id int, pruned bool) {
+			// Each device applies the history limit itself
+			if !pruned {
+				queue(event{removed: id})
+			}
+		},
+	}
+}
+
+// Run replays the folder and exports the whole history once, then exports
+// queued changes as they come and replays the folder when other devices'
+// operation files appear, and every interval for folders that report no file
+// changes, until ctx is done
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	s.replayAndLog()
+	if err := s.ExportAll(); err != nil {
+		slog.Error("Sync export failed", "err", err)
+	}
+
+	var fileChanges <-chan fsnotify.Event
+	var watchErrors <-chan error
+	watcher, err := s.watch()
+	if err != nil {
+		slog.Warn("Sync: not watching the folder, polling instead", "interval", interval, "err", err)
+	} else {
+		defer watcher.Close()
+		fileChanges, watchErrors = watcher.Events, watcher.Errors
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Files usually arrive in bursts, so replay once they stop for a moment
+	settle := time.NewTimer(0)
+	<-settle.C
+	for {
+		select {
+		case <-ctx.Done():
+			// Export what is already queued before stopping
+			for {
+				select {
+				case e := <-s.events:
+					s.handle(e)
+				default:
+					return
+				}
+			}
+		case e := <-s.events:
+			s.handle(e)
+		case change := <-fileChanges:
+			if change.Has(fsnotify.Create) && filepath.Dir(change.Name) == filepath.Join(s.opts.Folder, opsDir) {
+				watcher.Add(change.Name) // A new device's directory
+			}
+			settle.Reset(500 * time.Millisecond)
+		case err := <-watchErrors:
+			slog.Error("Sync: watching the folder", "err", err)
+		case <-settle.C:
+			s.replayAndLog()
+		case <-ticker.C:
+			s.replayAndLog()
+		}
+	}
+}
+
+// watch watches the operation directories of the folder
+func (s *Syncer) watch() (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Join(s.opts.Folder, opsDir)
+	dirs, err := os.ReadDir(root)
+	if err == nil {
+		err = watcher.Add(root)
+	}
+	for _, dir := range dirs {
+		if err == nil && dir.IsDir() && dir.Name() != s.state.DeviceID {
+			err = watcher.Add(filepath.Join(root, dir.Name()))
+		}
+	}
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+func (s *Syncer) replayAndLog() {
+	if err := s.Replay(); err != nil {
+		slog.Error("Sync replay failed", "err", err)
+	}
+}
+
+func (s *Syncer) handle(e event) {
+	var err error
+	if e.item != nil {
+		err = s.ItemChanged(*e.item)
+	} else {
+		err = s.ItemRemoved(e.removed)
+	}
+	if err != nil {
+		slog.Error("Sync export failed", "err", err)
+	}
+}
+
+// ExportAll exports every local item the folder doesn't have yet, or has in
+// an older version
+func (s *Syncer) ExportAll() error {
+	items, err := s.store.Items()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := s.ItemChanged(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ItemChanged exports a local insert or change. Changes that match what the
+// folder already holds, such as those made by Replay, are not exported again.
+func (s *Syncer) ItemChanged(item models.ClipboardItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.store.RecordsForItem(item.ID)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 && s.opts.FavoritesOnly && !item.IsFavorite {
+		return nil
+	}
+
+	data, image, err := itemData(item)
+	if err != nil {
+		return err
+	}
+	if image != nil {
+		if data.Blob, err = s.writeBlob(image); err != nil {
+			return err
+		}
+	}
+	digest := data.digest()
+
+	if len(records) == 0 {
+		r := database.SyncRecord{UID: randomID(16), ItemID: item.ID}
+		return s.export(r, KindInsert, &data, digest)
+	}
+	for _, r := range records {
+		kind := KindEdit
+		if r.Digest == digest {
+			if r.Favorite == data.Favorite {
+				continue
+			}
+			kind = KindFavorite
+		}
+		if err := s.export(r, kind, &data, digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ItemRemoved exports the deletion of a local item
+func (s *Syncer) ItemRemoved(itemID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.store.RecordsForItem(itemID)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := s.export(r, KindDelete, nil, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// export writes one operation to this device's directory and records it as
+// the item's current version. The caller holds s.mu.
+func (s *Syncer) export(r database.SyncRecord, kind string, data *ItemData, digest string) error {
+	op := Op{Kind: kind, UID: r.UID, Clock: s.state.tick(), Device: s.state.DeviceID, Item: data}
+	sealed, err := s.keys.sealOp(op, s.state.PrivateKey)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%016x-%s%s", op.Clock, randomID(4), opExt)
+	if err := writeFileAtomic(filepath.Join(s.deviceDir(), name), sealed); err != nil {
+		return err
+	}
+	if err := s.state.save(s.opts.StatePath); err != nil {
+		return err
+	}
+
+	r.Clock, r.Device, r.Digest = op.Clock, op.Device, digest
+	if kind == KindDelete {
+		r.ItemID, r.Deleted, r.Favorite = 0, true, false
+	} else {
+		r.Favorite = data.Favorite
+	}
+	return s.store.SaveRecord(r)
+}
+
+func (s *Syncer) writeBlob(image []byte) (string, error) {
+	name := s.keys.blobName(image)
+	path := filepath.Join(s.opts.Folder, blobsDir, name+blobExt)
+	if _, err := os.Stat(path); err == nil {
+		return name, nil
+	}
+	nonce, ciphertext := s.keys.seal(image, []byte(name))
+	if err := writeFileAtomic(path, append(nonce, ciphertext...)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *Syncer) readBlob(name string) ([]byte, error) {
+	if raw, err := hex.DecodeString(name); err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("invalid blob name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(s.opts.Folder, blobsDir, name+blobExt))
+	if err != nil {
+		return nil, err
+	}
+	size := s.keys.aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("blob is truncated")
+	}
+	image, err := s.keys.open(data[:size], data[size:], []byte(name))
+	if err != nil || s.keys.blobName(image) != name {
+		return nil, errors.New("blob does not match its name")
+	}
+	return image, nil
+}
+
+// pending is an operation file read from another device's directory
+type pending struct {
+	path string // Relative to ops/
+	op   Op
+}
+
+// Replay applies the operations other devices wrote since the last replay
+func (s *Syncer) Replay() error {
+	s.mu.Lock()
+	applied, err := s.replay()
+	s.mu.Unlock()
+
+	if applied && s.opts.OnApplied != nil {
+		s.opts.OnApplied()
+	}
+	return err
+}
+
+func (s *Syncer) replay() (applied bool, err error) {
+	ops, err := s.readOps()
+	if err != nil {
+		return false, err
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return newer(ops[j].op.Clock, ops[j].op.Device, ops[i].op.Clock, ops[i].op.Device)
+	})
+
+	for _, p := range ops {
+		changed, err := s.apply(p.op)
+		if errors.Is(err, os.ErrNotExist) {
+			// The image may not have reached this device yet; try again next time
+			slog.Debug("Sync: skipping until its image arrives", "path", p.path)
+			continue
+		}
+		if err != nil {
+			// Most likely the database was busy; try again next time
+			slog.Error("Sync: failed to apply", "path", p.path, "err", err)
+			continue
+		}
+		applied = applied || changed
+		s.state.observe(p.op.Clock)
+		s.state.Replayed[p.path] = true
+	}
+	return applied, s.state.save(s.opts.StatePath)
+}
+
+// readOps reads and verifies the operation files not replayed yet. Files that
+// fail verification are logged and never looked at again.
+func (s *Syncer) readOps() ([]pending, error) {
+	root := filepath.Join(s.opts.Folder, opsDir)
+	devices, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []pending
+	for _, dir := range devices {
+		if !dir.IsDir() || dir.Name() == s.state.DeviceID {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(root, dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			rel := dir.Name() + "/" + f.Name()
+			if f.IsDir() || !strings.HasSuffix(f.Name(), opExt) || s.state.Replayed[rel] {
+				continue
+			}
+			op, err := s.readOp(filepath.Join(root, dir.Name(), f.Name()), dir.Name())
+			if err != nil {
+				slog.Warn("Sync: ignoring file", "path", rel, "err", err)
+				s.state.Replayed[rel] = true
+				continue
+			}
+			ops = append(ops, pending{path: rel, op: op})
+		}
+	}
+	return ops, nil
+}
+
+func (s *Syncer) readOp(path, device string) (Op, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Op{}, err
+	}
+	op, pub, err := s.keys.openOp(data)
+	if err != nil {
+		return Op{}, err
+	}
+	if op.Device != device {
+		return Op{}, fmt.Errorf("written by device %s", op.Device)
+	}
+	if op.UID == "" || (op.Kind != KindDelete && op.Item == nil) {
+		return Op{}, errors.New("incomplete operation")
+	}
+
+	// The first key seen for a device is the only one accepted from then on
+	if pinned, ok := s.state.Peers[device]; !ok {
+		s.state.Peers[device] = pub
+	} else if !bytes.Equal(pinned, pub) {
+		return Op{}, errors.New("signed with a different key than earlier operations of the device")
+	}
+	return op, nil
+}
+
+// apply brings the local history in line with op unless a newer version of
+// the item is already known. It reports whether the history changed.
+func (s *Syncer) apply(op Op) (bool, error) {
+	r, known, err := s.store.Record(op.UID)
+	if err != nil {
+		return false, err
+	}
+	if known && !newer(op.Clock, op.Device, r.Clock, r.Device) {
+		return false, nil
+	}
+	if !known {
+		r = database.SyncRecord{UID: op.UID}
+	}
+
+	if op.Kind == KindDelete {
+		removed := r.ItemID
+		if removed != 0 {
+			if err := s.store.Delete(removed); err != nil {
+				return false, err
+			}
+		}
+		r.ItemID, r.Deleted, r.Favorite, r.Digest = 0, true, false, ""
+		r.Clock, r.Device = op.Clock, op.Device
+		return removed != 0, s.store.SaveRecord(r)
+	}
+
+	if r.Deleted || (!known && s.opts.FavoritesOnly && !op.Item.Favorite) {
+		// A deleted item stays deleted; only the record moves forward
+		r.Clock, r.Device = op.Clock, op.Device
+		return false, s.store.SaveRecord(r)
+	}
+
+	// The item may have been pruned here, so images are read even for known items
+	var image []byte
+	if op.Item.Type == monitor.TypeImage {
+		if image, err = s.readBlob(op.Item.Blob); err != nil {
+			return false, err
+		}
+	}
+	id, err := s.store.Put(r.ItemID, *op.Item, image)
+	if err != nil {
+		return false, err
+	}
+	r.ItemID, r.Clock, r.Device = id, op.Clock, op.Device
+	r.Digest, r.Favorite = op.Item.digest(), op.Item.Favorite
+	return true, s.store.SaveRecord(r)
+}
@@ -0,0 +1,421 @@
+package foldersync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+func init() {
+	// Keeps the tests fast; the real cost is only needed against guessing
+	kdfIterations = 1000
+}
+
+// memStore is a device's history held in memory. Like the database, it tells
+// the syncer about every change, including the ones made by Replay.
+type memStore struct {
+	dir     string // Where image items keep their pictures
+	items   map[int]models.ClipboardItem
+	records map[string]database.SyncRecord
+	nextID  int
+	syncer  *Syncer
+	queued  []func() error // Changes made by Replay, for the syncer to see afterwards
+	failPut error          // Returned by Put when set, like a locked database
+}
+
+func newMemStore(t *testing.T) *memStore {
+	return &memStore{dir: t.TempDir(), items: make(map[int]models.ClipboardItem), records: make(map[string]database.SyncRecord)}
+}
+
+func (m *memStore) Items() ([]models.ClipboardItem, error) {
+	var items []models.ClipboardItem
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (m *memStore) Record(uid string) (database.SyncRecord, bool, error) {
+	r, ok := m.records[uid]
+	return r, ok, nil
+}
+
+func (m *memStore) RecordsForItem(itemID int) ([]database.SyncRecord, error) {
+	var out []database.SyncRecord
+	for _, r := range m.records {
+		if r.ItemID == itemID && !r.Deleted {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) SaveRecord(r database.SyncRecord) error {
+	m.records[r.UID] = r
+	return nil
+}
+
+func (m *memStore) Put(itemID int, data ItemData, image []byte) (int, error) {
+	if m.failPut != nil {
+		return 0, m.failPut
+	}
+	item, ok := m.items[itemID]
+	if !ok {
+		m.nextID++
+		item = models.ClipboardItem{ID: m.nextID, Type: data.Type, CreatedAt: data.CreatedAt}
+		if image != nil {
+			item.ImagePath = filepath.Join(m.dir, strconv.Itoa(item.ID)+".png")
+			if err := os.WriteFile(item.ImagePath, image, 0600); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if item.Type != monitor.TypeImage {
+		item.Content, item.Type = data.Content, data.Type
+	}
+	item.Source, item.IsFavorite, item.IsSensitive = data.Source, data.Favorite, data.Sensitive
+	m.items[item.ID] = item
+	m.queued = append(m.queued, func() error { return m.syncer.ItemChanged(item) })
+	return item.ID, nil
+}
+
+func (m *memStore) Delete(itemID int) error {
+	delete(m.items, itemID)
+	m.queued = append(m.queued, func() error { return m.syncer.ItemRemoved(itemID) })
+	return nil
+}
+
+// add stores a new item as if it was captured on this device
+func (m *memStore) add(t *testing.T, item models.ClipboardItem) models.ClipboardItem {
+	t.Helper()
+	m.nextID++
+	item.ID = m.nextID
+	item.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	m.items[item.ID] = item
+	if err := m.syncer.ItemChanged(item); err != nil {
+		t.Fatalf("ItemChanged failed: %v", err)
+	}
+	return item
+}
+
+func (m *memStore) find(content string) (models.ClipboardItem, bool) {
+	for _, item := range m.items {
+		if item.Content == content {
+			return item, true
+		}
+	}
+	return models.ClipboardItem{}, false
+}
+
+type device struct {
+	store  *memStore
+	syncer *Syncer
+}
+
+func newDevice(t *testing.T, folder string, opts Options) *device {
+	t.Helper()
+	store := newMemStore(t)
+	opts.Folder = folder
+	if opts.Passphrase == "" {
+		opts.Passphrase = "correct horse battery staple"
+	}
+	opts.StatePath = filepath.Join(t.TempDir(), StateFileName)
+	s, err := New(store, opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	store.syncer = s
+	return &device{store: store, syncer: s}
+}
+
+// replay applies the other devices' operations, then hands the changes that
+// caused to the syncer like the database listener does in Run
+func (d *device) replay(t *testing.T) {
+	t.Helper()
+	if err := d.syncer.Replay(); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	queued := d.store.queued
+	d.store.queued = nil
+	for _, fn := range queued {
+		if err := fn(); err != nil {
+			t.Fatalf("handling a replayed change failed: %v", err)
+		}
+	}
+}
+
+// opCount returns how many operation files a device has written
+func opCount(t *testing.T, d *device) int {
+	t.Helper()
+	entries, err := os.ReadDir(d.syncer.deviceDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestSyncPropagatesChanges(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	applied := 0
+	b := newDevice(t, folder, Options{OnApplied: func() { applied++ }})
+
+	item := a.store.add(t, models.ClipboardItem{Content: "hello from a", Type: "text", Source: "clipboard"})
+	b.replay(t)
+	got, ok := b.store.find("hello from a")
+	if !ok || got.Type != "text" || got.Source != "clipboard" || applied != 1 {
+		t.Fatalf("insert not applied on b: %+v (applied %d)", b.store.items, applied)
+	}
+	// Applying the insert must not be exported back as a change of b's own
+	if n := opCount(t, b); n != 0 {
+		t.Errorf("b echoed %d operations", n)
+	}
+
+	item.IsFavorite = true
+	a.store.items[item.ID] = item
+	a.syncer.ItemChanged(item)
+	b.replay(t)
+	if got, _ := b.store.find("hello from a"); !got.IsFavorite {
+		t.Error("favorite not applied on b")
+	}
+
+	got.Content = "edited on b"
+	got.IsFavorite = true
+	b.store.items[got.ID] = got
+	b.syncer.ItemChanged(got)
+	a.replay(t)
+	if a.store.items[item.ID].Content != "edited on b" {
+		t.Errorf("edit not applied on a: %+v", a.store.items[item.ID])
+	}
+
+	// Nothing changed, so replaying again applies and exports nothing
+	before := opCount(t, a)
+	a.replay(t)
+	a.syncer.ExportAll()
+	if n := opCount(t, a); n != before {
+		t.Errorf("a wrote %d operations without local changes", n-before)
+	}
+
+	delete(a.store.items, item.ID)
+	a.syncer.ItemRemoved(item.ID)
+	b.replay(t)
+	if len(b.store.items) != 0 {
+		t.Errorf("delete not applied on b: %+v", b.store.items)
+	}
+
+	// A third device joining later catches up on the whole folder
+	c := newDevice(t, folder, Options{})
+	c.store.add(t, models.ClipboardItem{Content: "from c", Type: "text"})
+	c.replay(t)
+	if len(c.store.items) != 1 {
+		t.Errorf("deleted item should not reach a new device, got %+v", c.store.items)
+	}
+	a.replay(t)
+	b.replay(t)
+	if _, ok := a.store.find("from c"); !ok {
+		t.Error("a did not get c's item")
+	}
+	if _, ok := b.store.find("from c"); !ok {
+		t.Error("b did not get c's item")
+	}
+}
+
+func TestSyncConcurrentEditsConverge(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	b := newDevice(t, folder, Options{})
+
+	item := a.store.add(t, models.ClipboardItem{Content: "original", Type: "text"})
+	b.replay(t)
+	other, _ := b.store.find("original")
+
+	// Both devices edit the item before seeing the other's edit
+	item.Content = "edit on a"
+	a.store.items[item.ID] = item
+	a.syncer.ItemChanged(item)
+	other.Content = "edit on b"
+	b.store.items[other.ID] = other
+	b.syncer.ItemChanged(other)
+
+	a.replay(t)
+	b.replay(t)
+	contentA := a.store.items[item.ID].Content
+	contentB := b.store.items[other.ID].Content
+	if contentA != contentB {
+		t.Fatalf("devices did not converge: a has %q, b has %q", contentA, contentB)
+	}
+	// Equal clocks are settled by the device ID
+	want := "edit on a"
+	if b.syncer.DeviceID() > a.syncer.DeviceID() {
+		want = "edit on b"
+	}
+	if contentA != want {
+		t.Errorf("expected %q to win, got %q", want, contentA)
+	}
+}
+
+func TestSyncImages(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	b := newDevice(t, folder, Options{})
+
+	png := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, []byte("pixels")...)
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, png, 0600); err != nil {
+		t.Fatal(err)
+	}
+	a.store.add(t, models.ClipboardItem{Type: monitor.TypeImage, ImagePath: path})
+
+	blobs, _ := os.ReadDir(filepath.Join(folder, blobsDir))
+	if len(blobs) != 1 {
+		t.Fatalf("expected one blob, got %d", len(blobs))
+	}
+	blob, _ := os.ReadFile(filepath.Join(folder, blobsDir, blobs[0].Name()))
+	if bytes.Contains(blob, []byte("pixels")) {
+		t.Error("blob should be encrypted")
+	}
+
+	b.replay(t)
+	if len(b.store.items) != 1 {
+		t.Fatalf("image not applied on b: %+v", b.store.items)
+	}
+	for _, item := range b.store.items {
+		data, err := os.ReadFile(item.ImagePath)
+		if err != nil || string(data) != string(png) {
+			t.Errorf("b has the wrong image: %q (%v)", data, err)
+		}
+	}
+}
+
+func TestReplayRetriesFailedOps(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	b := newDevice(t, folder, Options{})
+
+	a.store.add(t, models.ClipboardItem{Content: "while b was busy", Type: "text"})
+	b.store.failPut = errors.New("database is locked")
+	b.replay(t)
+	if _, ok := b.store.find("while b was busy"); ok {
+		t.Fatal("the insert should have failed")
+	}
+
+	b.store.failPut = nil
+	b.replay(t)
+	if _, ok := b.store.find("while b was busy"); !ok {
+		t.Error("an op that failed to apply should be retried on the next replay")
+	}
+}
+
+func TestRunReplaysOnFileChange(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	applied := make(chan struct{}, 1)
+	b := newDevice(t, folder, Options{OnApplied: func() { applied <- struct{}{} }})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.syncer.Run(ctx, time.Hour) // Too long to be what picks the change up
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	time.Sleep(100 * time.Millisecond) // Let Run start watching
+	a.store.add(t, models.ClipboardItem{Content: "watched", Type: "text"})
+	select {
+	case <-applied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("b did not replay the new operation file")
+	}
+	if _, ok := b.store.find("watched"); !ok {
+		t.Errorf("expected the item on b, got %+v", b.store.items)
+	}
+}
+
+func TestSyncWrongPassphrase(t *testing.T) {
+	folder := t.TempDir()
+	newDevice(t, folder, Options{})
+
+	_, err := New(newMemStore(t), Options{
+		Folder:     folder,
+		Passphrase: "not the passphrase",
+		StatePath:  filepath.Join(t.TempDir(), StateFileName),
+	})
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestSyncRejectsTamperedOperations(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{})
+	b := newDevice(t, folder, Options{})
+	a.store.add(t, models.ClipboardItem{Content: "genuine", Type: "text"})
+
+	entries, _ := os.ReadDir(a.syncer.deviceDir())
+	genuine, _ := os.ReadFile(filepath.Join(a.syncer.deviceDir(), entries[0].Name()))
+
+	// A flipped byte breaks the signature
+	tampered := append([]byte(nil), genuine...)
+	tampered[len(tampered)/2] ^= 1
+	os.WriteFile(filepath.Join(a.syncer.deviceDir(), "tampered"+opExt), tampered, 0600)
+
+	// Someone knowing the passphrase but not a's key cannot write as a
+	forger, _ := newState()
+	forger.DeviceID = a.syncer.DeviceID()
+	forged, err := a.syncer.keys.sealOp(Op{
+		Kind: KindInsert, UID: "forged", Clock: 99, Device: forger.DeviceID,
+		Item: &ItemData{Type: "text", Content: "forged"},
+	}, forger.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.replay(t) // Pins a's key
+	os.WriteFile(filepath.Join(a.syncer.deviceDir(), "forged"+opExt), forged, 0600)
+	b.replay(t)
+
+	if len(b.store.items) != 1 {
+		t.Fatalf("expected only the genuine item, got %+v", b.store.items)
+	}
+	if _, ok := b.store.find("genuine"); !ok {
+		t.Errorf("genuine item missing: %+v", b.store.items)
+	}
+}
+
+func TestSyncFavoritesOnly(t *testing.T) {
+	folder := t.TempDir()
+	a := newDevice(t, folder, Options{FavoritesOnly: true})
+	b := newDevice(t, folder, Options{FavoritesOnly: true})
+
+	plain := a.store.add(t, models.ClipboardItem{Content: "plain", Type: "text"})
+	a.store.add(t, models.ClipboardItem{Content: "starred", Type: "text", IsFavorite: true})
+	b.replay(t)
+	if _, ok := b.store.find("plain"); ok {
+		t.Error("non-favorite item should not be synced")
+	}
+	if _, ok := b.store.find("starred"); !ok {
+		t.Error("favorite item should be synced")
+	}
+
+	// Starring an item later shares it
+	plain.IsFavorite = true
+	a.store.items[plain.ID] = plain
+	a.syncer.ItemChanged(plain)
+	b.replay(t)
+	if _, ok := b.store.find("plain"); !ok {
+		t.Error("item should be synced once it is a favorite")
+	}
+}
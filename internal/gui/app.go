@@ -2,8 +2,10 @@ package gui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -12,9 +14,12 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/autopaste"
 	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/linkmeta"
 	"github.com/Sirpyerre/pasteeclipboard/internal/models"
 	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/notify"
 )
 
 // Constants for UI strings and values
@@ -27,7 +32,7 @@ const (
 	nextBtnText        = "Next"
 	lastBtnText        = "Last"
 	confirmDeleteTitle = "Confirm Delete"
-	confirmDeleteMsg   = "Are you sure you want to delete all history?"
+	confirmDeleteMsg   = "Are you sure you want to delete all history?\nItems held in registers are kept."
 	noHistoryText      = "No clipboard history available."
 )
 
@@ -51,15 +56,29 @@ type PastyClipboard struct {
 	pageSizeSelect    *widget.Select
 	showFavoritesOnly bool
 	favToggle         *widget.Button
+	pauseBanner       *fyne.Container
+	pauseLabel        *widget.Label
+	scriptBanner      *fyne.Container
+	scriptErrorLabel  *widget.Label
+
+	searchEntry   *searchEntry
+	historyScroll *container.Scroll
+	query         string
+	filteredItems []models.ClipboardItem // Items matching query, across all pages
+	selected      int                    // Index into filteredItems of the highlighted row, -1 if none
+	selectedID    int                    // ID of the highlighted item when the list was last drawn
+	visible       bool                   // Whether the window is shown; see ShowWindow and HideWindow
+	bindHotkey    HotkeyBinder
 }
 
 func NewPastyClipboard(a fyne.App, icon fyne.Resource) *PastyClipboard {
 	_, needsMigration, err := database.InitDB()
 	if err != nil {
-		log.Fatal("error initializing database:", err)
+		slog.Error("error initializing database", "err", err)
+		os.Exit(1)
 	}
 
-	window := a.NewWindow("Pastee Clipboard")
+	window := a.NewWindow(windowTitle)
 	window.SetIcon(icon)
 
 	p := &PastyClipboard{
@@ -70,10 +89,10 @@ func NewPastyClipboard(a fyne.App, icon fyne.Resource) *PastyClipboard {
 	p.Win.Resize(fyne.NewSize(400, 500))
 
 	if needsMigration {
-		log.Println("Migration needed - showing dialog to user")
+		slog.Info("Migration needed - showing dialog to user")
 		// Set minimal content before showing dialog
 		p.Win.SetContent(widget.NewLabel("Initializing..."))
-		p.Win.Show()
+		p.ShowWindow()
 		p.showMigrationDialogAndInit()
 	} else {
 		p.initializeApp()
@@ -88,7 +107,7 @@ func (p *PastyClipboard) showMigrationDialogAndInit() {
 			p.performMigration()
 		},
 		func() {
-			log.Println("User chose to skip encryption")
+			slog.Info("User chose to skip encryption")
 			p.initializeApp()
 		},
 	)
@@ -98,22 +117,23 @@ func (p *PastyClipboard) performMigration() {
 	progressDialog := ShowMigrationProgressDialog(p.Win)
 
 	go func() {
-		log.Println("Starting database migration...")
+		slog.Info("Starting database migration")
 		err := database.PerformMigration()
 
 		fyne.Do(func() {
 			progressDialog.Hide()
 
 			if err != nil {
-				log.Printf("Migration failed: %v", err)
+				slog.Error("Migration failed", "err", err)
 				ShowMigrationErrorDialog(p.Win, err)
 				p.initializeApp()
 			} else {
-				log.Println("Migration completed successfully")
+				slog.Info("Migration completed successfully")
 				ShowMigrationSuccessDialog(p.Win, func() {
 					_, _, err := database.InitDB()
 					if err != nil {
-						log.Fatal("error re-initializing database after migration:", err)
+						slog.Error("error re-initializing database after migration", "err", err)
+						os.Exit(1)
 					}
 					p.initializeApp()
 				})
@@ -125,24 +145,36 @@ func (p *PastyClipboard) performMigration() {
 func (p *PastyClipboard) initializeApp() {
 	items, err := database.GetClipboardHistory(100)
 	if err != nil {
-		log.Fatal("error getting clipboard history:", err)
+		slog.Error("error getting clipboard history", "err", err)
+		os.Exit(1)
 	}
 
 	p.clipboardHistory = items
 	p.setupUI()
 
-	monitor.StartClipboardMonitor(func(newItem models.ClipboardItem) {
-		var notificationContent string
-		if newItem.Type == "image" {
-			notificationContent = "New image copied"
-		} else {
-			notificationContent = newItem.Content
-		}
+	monitor.OnPauseChange(func(state monitor.PauseState) {
+		fyne.Do(func() {
+			p.updatePauseIndicator(state)
+		})
+	})
 
+	// Items pruned by the history limit or their expiry time disappear from the window
+	database.AddItemListener(database.ItemListener{
+		Removed: func(id int, pruned bool) {
+			if pruned {
+				fyne.Do(p.ReloadHistory)
+			}
+		},
+	})
+
+	notifier := notify.New(func(msg notify.Message) {
 		fyne.CurrentApp().SendNotification(&fyne.Notification{
-			Title:   "New Clipboard Item",
-			Content: notificationContent,
+			Title:   msg.Title,
+			Content: msg.Content,
 		})
+	})
+	err = monitor.StartClipboardMonitor(func(newItem models.ClipboardItem, isNew bool) {
+		notifier.Captured(newItem, isNew)
 
 		fyne.Do(func() {
 			var newHistory []models.ClipboardItem
@@ -152,9 +184,18 @@ func (p *PastyClipboard) initializeApp() {
 				}
 			}
 			p.clipboardHistory = append([]models.ClipboardItem{newItem}, newHistory...)
-			p.updateHistoryUI("")
+			p.updateHistoryUI(p.query)
+		})
+
+		linkmeta.FetchInBackground(newItem, func(meta models.LinkMetadata) {
+			fyne.Do(func() {
+				p.setLinkTitle(meta)
+			})
 		})
 	})
+	if err != nil {
+		slog.Error("error starting clipboard monitor", "err", err)
+	}
 }
 
 func (p *PastyClipboard) setupUI() {
@@ -166,11 +207,14 @@ func (p *PastyClipboard) setupUI() {
 
 	scrollableHistory := container.NewScroll(p.historyContainer)
 	scrollableHistory.SetMinSize(fyne.NewSize(300, 400))
+	p.historyScroll = scrollableHistory
 
 	bottomBar := p.bottomBar()
 
+	top := container.NewVBox(p.pauseBar(), p.scriptBar(), searchBox)
+
 	content := container.NewBorder(
-		searchBox,         // Top
+		top,               // Top
 		bottomBar,         // Bottom
 		nil,               // Left
 		nil,               // Right
@@ -178,19 +222,31 @@ func (p *PastyClipboard) setupUI() {
 	)
 
 	p.Win.SetContent(content)
-	p.updateHistoryUI("")
+	p.setupKeyboard()
+	p.updateHistoryUI(p.query)
 }
 
 func (p *PastyClipboard) updateHistoryUI(query string) {
+	// Rows move when items are captured, copied or edited; the highlight stays
+	// on its item unless the caller just changed the query or the selection
+	current, ok := p.selectedItem()
+	follow := ok && current.ID == p.selectedID && query == p.query
+
 	var filteredItems []models.ClipboardItem
 	for _, item := range p.clipboardHistory {
 		if p.showFavoritesOnly && !item.IsFavorite {
 			continue
 		}
-		if query == "" || strings.Contains(strings.ToLower(item.Content), strings.ToLower(query)) {
+		if matchesQuery(item, query) {
 			filteredItems = append(filteredItems, item)
 		}
 	}
+	p.query = query
+	p.filteredItems = filteredItems
+	if index := indexOfID(filteredItems, p.selectedID); follow && index >= 0 {
+		p.selected = index
+		p.currentPage = pageOf(index, p.pageSize)
+	}
 
 	totalItems := len(filteredItems)
 	totalPages := int(math.Ceil(float64(totalItems) / float64(p.pageSize)))
@@ -207,34 +263,34 @@ func (p *PastyClipboard) updateHistoryUI(query string) {
 	startIndex := (p.currentPage - 1) * p.pageSize
 	endIndex := int(math.Min(float64(startIndex+p.pageSize), float64(totalItems)))
 
+	// Keep the selection on the visible page, e.g. after the page buttons were used
+	if totalItems == 0 {
+		p.selected = -1
+	} else if p.selected < startIndex || p.selected >= endIndex {
+		p.selected = startIndex
+	}
+	p.selectedID = 0
+	if item, ok := p.selectedItem(); ok {
+		p.selectedID = item.ID
+	}
+
 	p.historyContainer.RemoveAll()
 
 	if totalItems > 0 {
 		visibleItems := filteredItems[startIndex:endIndex]
 
 		for i, item := range visibleItems {
-			p.historyContainer.Add(CreateHistoryItemUI(item, i,
+			p.historyContainer.Add(CreateHistoryItemUI(item, i, startIndex+i == p.selected,
 				func(deletedItem models.ClipboardItem) {
-					_ = database.DeleteClipboardItem(item.ID)
-					var newHistory []models.ClipboardItem
-					for _, hItem := range p.clipboardHistory {
-						if hItem.ID != deletedItem.ID {
-							newHistory = append(newHistory, hItem)
-						}
-					}
-					p.clipboardHistory = newHistory
-					p.updateHistoryUI(query)
+					p.deleteItem(deletedItem)
 				},
 				func() {
-					items, err := database.GetClipboardHistory(100)
-					if err == nil {
-						p.clipboardHistory = items
-					}
-					p.updateHistoryUI(query)
+					p.ReloadHistory()
 				},
 				func() {
-					p.Win.Hide()
+					p.afterCopy(item, true)
 				},
+				p.HideWindow,
 				p.Win,
 			))
 		}
@@ -269,13 +325,66 @@ func (p *PastyClipboard) updateHistoryUI(query string) {
 	}
 
 	p.historyContainer.Refresh()
+	p.scrollToSelected(p.selected - startIndex)
+}
+
+// matchesQuery reports whether an item's content or link title contains query
+func matchesQuery(item models.ClipboardItem, query string) bool {
+	if query == "" {
+		return true
+	}
+	q := strings.ToLower(query)
+	if strings.Contains(strings.ToLower(item.Content), q) || strings.Contains(strings.ToLower(item.Title), q) {
+		return true
+	}
+	return slices.ContainsFunc(item.Tags, func(tag string) bool {
+		return strings.Contains("#"+strings.ToLower(tag), q)
+	})
+}
+
+// setLinkTitle shows a freshly fetched title on every item holding that link
+func (p *PastyClipboard) setLinkTitle(meta models.LinkMetadata) {
+	for i := range p.clipboardHistory {
+		if p.clipboardHistory[i].Content == meta.URL {
+			p.clipboardHistory[i].Title = meta.Title
+		}
+	}
+	p.updateHistoryUI(p.query)
+}
+
+// autoPaste returns focus to the window that was active before the hotkey
+// opened Pastee and pastes there. Windows opened another way paste nowhere.
+func (p *PastyClipboard) autoPaste() {
+	if !autopaste.Enabled() || !autopaste.Remembered() {
+		return
+	}
+	go func() {
+		if err := autopaste.PasteIntoPrevious(); err != nil {
+			slog.Error("Auto-paste failed", "err", err)
+		}
+	}()
+}
+
+// moveToTop mirrors the timestamp bump the monitor applies when Pastee re-copies an item
+func (p *PastyClipboard) moveToTop(id int) {
+	for i, item := range p.clipboardHistory {
+		if item.ID == id {
+			copy(p.clipboardHistory[1:i+1], p.clipboardHistory[:i])
+			p.clipboardHistory[0] = item
+			return
+		}
+	}
 }
 
 func (p *PastyClipboard) searchBox() *fyne.Container {
-	searchEntry := widget.NewEntry()
+	searchEntry := newSearchEntry(p)
 	searchEntry.SetPlaceHolder(placeholderText)
+	p.searchEntry = searchEntry
 
 	searchEntry.OnChanged = func(s string) {
+		// Each new query starts from its best match
+		p.currentPage = 1
+		p.selected = 0
 		p.updateHistoryUI(s)
 	}
 	searchIcon := widget.NewIcon(theme.SearchIcon())
@@ -342,11 +451,11 @@ func (p *PastyClipboard) clearAll() *widget.Button {
 		dialog.ShowConfirm(confirmDeleteTitle, confirmDeleteMsg, func(confirm bool) {
 			if confirm {
 				if err := database.DeleteAllClipboardItems(); err != nil {
-					log.Fatal("error deleting clipboard history:", err)
-					return
+					slog.Error("error deleting clipboard history", "err", err)
+					os.Exit(1)
 				}
-				p.clipboardHistory = []models.ClipboardItem{}
-				p.updateHistoryUI("")
+				// Registered items survive, so reload rather than emptying the list
+				p.ReloadHistory()
 			}
 		}, p.Win)
 
@@ -363,41 +472,47 @@ func (p *PastyClipboard) bottomBar() *fyne.Container {
 		widget.NewLabel(showLabelText),
 		pageSizeWrapper,
 	)
-	right := container.NewHBox(p.clearAll())
+	settingsButton := widget.NewButtonWithIcon("", theme.SettingsIcon(), p.ShowHotkeySettings)
+	settingsButton.Importance = widget.LowImportance
+	right := container.NewHBox(settingsButton, p.clearAll())
 
 	return container.NewBorder(nil, nil, left, right, center)
 }
 
 func (p *PastyClipboard) firstPage() {
-	p.currentPage = 1
-	p.updateHistoryUI("")
+	p.showPage(1)
 }
 
 func (p *PastyClipboard) lastPage() {
-	totalItems := len(p.clipboardHistory)
-	totalPages := int(math.Ceil(float64(totalItems) / float64(p.pageSize)))
-	if totalPages > 0 {
-		p.currentPage = totalPages
+	if totalPages := p.totalPages(); totalPages > 0 {
+		p.showPage(totalPages)
 	}
-	p.updateHistoryUI("")
 }
 
 func (p *PastyClipboard) prevPage() {
 	if p.currentPage > 1 {
-		p.currentPage--
-		p.updateHistoryUI("")
+		p.showPage(p.currentPage - 1)
 	}
 }
 
 func (p *PastyClipboard) nextPage() {
-	totalItems := len(p.clipboardHistory)
-	totalPages := int(math.Ceil(float64(totalItems) / float64(p.pageSize)))
-	if p.currentPage < totalPages {
-		p.currentPage++
-		p.updateHistoryUI("")
+	if p.currentPage < p.totalPages() {
+		p.showPage(p.currentPage + 1)
 	}
 }
 
+// showPage moves to page, keeping the query and highlighting its first row
+func (p *PastyClipboard) showPage(page int) {
+	p.currentPage = page
+	p.selected = (page - 1) * p.pageSize
+	p.updateHistoryUI(p.query)
+}
+
+// totalPages counts the pages of items matching the current query
+func (p *PastyClipboard) totalPages() int {
+	return int(math.Ceil(float64(len(p.filteredItems)) / float64(p.pageSize)))
+}
+
 type fixedWidthLayout struct {
 	width float32
 }
@@ -415,6 +530,6 @@ func (f *fixedWidthLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
 
 func (p *PastyClipboard) onPageSizeChange(size int) {
 	p.pageSize = size
-	p.currentPage = 1     // Resetear a la primera página es crucial
-	p.updateHistoryUI("") // Recargar la lista con el nuevo tamaño de página
+	p.currentPage = 1          // Resetear a la primera página es crucial
+	p.updateHistoryUI(p.query) // Recargar la lista con el nuevo tamaño de página
 }
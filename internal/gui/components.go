@@ -1,10 +1,9 @@
 package gui
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"image/color"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -15,15 +14,20 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/autopaste"
 	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hooks"
+	"github.com/Sirpyerre/pasteeclipboard/internal/logging"
 	"github.com/Sirpyerre/pasteeclipboard/internal/models"
 	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/secrets"
+	"github.com/Sirpyerre/pasteeclipboard/internal/transform"
 	"golang.design/x/clipboard"
 )
 
 var revealedItems = make(map[int]bool)
 
-func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(models.ClipboardItem), onRefresh func(), onCopy func(), win fyne.Window) fyne.CanvasObject {
+func CreateHistoryItemUI(item models.ClipboardItem, index int, selected bool, onDelete func(models.ClipboardItem), onRefresh func(), onCopy func(), onHide func(), win fyne.Window) fyne.CanvasObject {
 	var contentDisplay fyne.CanvasObject
 
 	if item.Type == "image" {
@@ -42,9 +46,16 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 		} else {
 			displayText = truncateToLines(item.Content, 2, 80)
 		}
-		contentLabel := widget.NewLabelWithStyle(displayText, fyne.TextAlignLeading, fyne.TextStyle{Monospace: looksLikeCode(item.Content)})
+		contentLabel := widget.NewLabelWithStyle(displayText, fyne.TextAlignLeading, fyne.TextStyle{Monospace: monitor.LooksLikeCode(item.Content)})
 		contentLabel.Wrapping = fyne.TextWrapWord
 
+		var textDisplay fyne.CanvasObject = contentLabel
+		if item.Title != "" && !item.IsSensitive {
+			titleLabel := widget.NewLabelWithStyle(truncateToLines(item.Title, 1, 80), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+			titleLabel.Importance = widget.LowImportance
+			textDisplay = container.NewVBox(contentLabel, titleLabel)
+		}
+
 		if item.IsSensitive {
 			contentButton := widget.NewButton("", func() {
 				revealedItems[item.ID] = !revealedItems[item.ID]
@@ -55,28 +66,28 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 			contentButton.Importance = widget.LowImportance
 			contentDisplay = container.NewStack(contentLabel, contentButton)
 		} else {
-			contentDisplay = contentLabel
+			contentDisplay = textDisplay
 		}
 	}
 
-	var typeIcon fyne.CanvasObject
-	switch item.Type {
-	case "text":
-		typeIcon = widget.NewIcon(theme.DocumentIcon())
-	case "link":
-		typeIcon = widget.NewIcon(theme.ComputerIcon())
-	case "email":
-		typeIcon = widget.NewIcon(theme.MailComposeIcon())
-	case "phone":
-		typeIcon = widget.NewIcon(theme.AccountIcon())
-	case "image":
-		typeIcon = widget.NewIcon(theme.MediaPhotoIcon())
-	default:
-		typeIcon = widget.NewIcon(theme.QuestionIcon())
-	}
+	typeIcon := widget.NewIcon(monitor.IconForType(item.Type))
 
 	actionButtons := container.NewHBox()
 
+	// Register badge, e.g. "1" or "a,3"
+	if len(item.Registers) > 0 {
+		badge := widget.NewLabelWithStyle(strings.Join(item.Registers, ","), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+		badge.Importance = widget.HighImportance
+		actionButtons.Add(badge)
+	}
+
+	// Tags added by capture rules
+	if len(item.Tags) > 0 {
+		tags := widget.NewLabel("#" + strings.Join(item.Tags, " #"))
+		tags.Importance = widget.LowImportance
+		actionButtons.Add(tags)
+	}
+
 	// Favorite toggle button (always visible)
 	var favLabel string
 	var favImportance widget.Importance
@@ -90,7 +101,7 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 	favButton := widget.NewButton(favLabel, func() {
 		newFavorite := !item.IsFavorite
 		if err := database.UpdateItemFavorite(item.ID, newFavorite); err != nil {
-			log.Printf("Failed to update favorite: %v", err)
+			slog.Error("Failed to update favorite", "err", err)
 			return
 		}
 		if onRefresh != nil {
@@ -127,7 +138,7 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 					}
 					newType := monitor.DetectContentType(newContent)
 					if err := database.UpdateItemContent(item.ID, newContent, newType); err != nil {
-						log.Printf("Failed to update item content: %v", err)
+						slog.Error("Failed to update item content", "err", err)
 						return
 					}
 					if onRefresh != nil {
@@ -138,6 +149,13 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 				dlg.Show()
 			}))
 
+			// Why the item was flagged; informational only
+			if item.DetectedSecret != "" {
+				detected := fyne.NewMenuItem("Detected: "+secrets.Label(item.DetectedSecret), nil)
+				detected.Disabled = true
+				menuItems = append(menuItems, detected)
+			}
+
 			var sensitiveLabel string
 			if item.IsSensitive {
 				sensitiveLabel = "Unmark Sensitive"
@@ -147,7 +165,7 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 			menuItems = append(menuItems, fyne.NewMenuItem(sensitiveLabel, func() {
 				newSensitivity := !item.IsSensitive
 				if err := database.UpdateItemSensitivity(item.ID, newSensitivity); err != nil {
-					log.Printf("Failed to update sensitivity: %v", err)
+					slog.Error("Failed to update sensitivity", "err", err)
 					return
 				}
 				delete(revealedItems, item.ID)
@@ -155,8 +173,45 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 					onRefresh()
 				}
 			}))
+
+			if item.OriginalContent != "" {
+				menuItems = append(menuItems, fyne.NewMenuItem("Copy Original Link", func() {
+					copyTextToClipboard(item.OriginalContent, item.IsSensitive)
+					slog.Debug("Copied original link", "id", item.ID)
+					onHide()
+				}))
+			}
+
+			plainLabel := "Copy as Plain Text"
+			if autopaste.Enabled() {
+				plainLabel = "Paste as Plain Text"
+			}
+			menuItems = append(menuItems, fyne.NewMenuItem(plainLabel, func() {
+				plain, err := transform.Apply("strip-formatting", item.Content)
+				if err != nil {
+					slog.Error("Failed to strip formatting", "err", err)
+					return
+				}
+				copyTextToClipboard(plain, item.IsSensitive)
+				slog.Debug("Copied item as plain text", "id", item.ID)
+				if onCopy != nil {
+					onCopy()
+				}
+			}))
+
+			copyAsItem := fyne.NewMenuItem("Copy as…", nil)
+			copyAsItem.ChildMenu = fyne.NewMenu("", transformMenuItems(item, false, onRefresh, onHide, win)...)
+			saveAsItem := fyne.NewMenuItem("Save as New Item…", nil)
+			saveAsItem.ChildMenu = fyne.NewMenu("", transformMenuItems(item, true, onRefresh, onHide, win)...)
+			menuItems = append(menuItems, copyAsItem, saveAsItem)
 		}
 
+		menuItems = append(menuItems, registerMenuItems(item, onRefresh, win)...)
+
+		menuItems = append(menuItems, fyne.NewMenuItem("Test Capture Rules…", func() {
+			showRulesPreview(item, win)
+		}))
+
 		menuItems = append(menuItems, fyne.NewMenuItem("Delete", func() {
 			if onDelete != nil {
 				onDelete(item)
@@ -176,26 +231,17 @@ func CreateHistoryItemUI(item models.ClipboardItem, index int, onDelete func(mod
 	)
 
 	background := canvas.NewRectangle(rowBackgroundColor(index))
+	if selected {
+		background.FillColor = theme.Color(theme.ColorNameSelection)
+	}
 
 	card := widget.NewButton("", func() {
-		if item.Type == "image" {
-			if err := copyImageToClipboard(item); err != nil {
-				log.Printf("error copying image to clipboard: %s\n", err)
-			} else {
-				monitor.IgnoreNextClipboardRead()
-				log.Println("Image copied to clipboard")
-				if onCopy != nil {
-					onCopy()
-				}
-			}
-		} else {
-			clipboard.Write(clipboard.FmtText, []byte(item.Content))
-			monitor.IgnoreNextClipboardRead()
-			monitor.SetLastClipboardContent(item.Content)
-			log.Printf("Contenido copiado: %s\n", item.Content)
-			if onCopy != nil {
-				onCopy()
-			}
+		if err := copyItemToClipboard(item); err != nil {
+			slog.Error("error copying item to clipboard", "err", err)
+			return
+		}
+		if onCopy != nil {
+			onCopy()
 		}
 	})
 	card.Importance = widget.LowImportance
@@ -233,27 +279,68 @@ func rowBackgroundColor(index int) color.Color {
 	return color.NRGBA{R: r8 - 10, G: g8 - 10, B: b8 - 10, A: uint8(a >> 8)}
 }
 
-func looksLikeCode(content string) bool {
-	codePatterns := []string{"func ", "class ", "import ", "def ", "var ", "const ", "#!/", "SELECT ", "INSERT ", "UPDATE ", "DELETE FROM"}
-	for _, pattern := range codePatterns {
-		if strings.Contains(content, pattern) {
-			return true
-		}
+// transformMenuItems builds one menu entry per text transform. Each copies the
+// transformed content; with save it is also stored as a new history item.
+func transformMenuItems(item models.ClipboardItem, save bool, onRefresh, onHide func(), win fyne.Window) []*fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, t := range transform.All() {
+		items = append(items, fyne.NewMenuItem(t.Label, func() {
+			result, err := t.Apply(item.Content)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s: %w", t.Label, err), win)
+				return
+			}
+
+			if save {
+				if _, _, err := monitor.StoreText(transformedItem(item, result)); err != nil {
+					slog.Error("Failed to save transformed item", "err", err)
+					return
+				}
+				if onRefresh != nil {
+					onRefresh()
+				}
+			}
+
+			copyTextToClipboard(result, item.IsSensitive)
+			slog.Debug("Copied transformed item", "id", item.ID, "transform", t.Name)
+			onHide()
+		}))
 	}
-	lines := strings.Split(content, "\n")
-	indentedLines := 0
-	for _, line := range lines {
-		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
-			indentedLines++
+	return items
+}
+
+// transformedItem is the new history item saved for item's transformed
+// content. It stays sensitive if item is.
+func transformedItem(item models.ClipboardItem, result string) models.ClipboardItem {
+	return models.ClipboardItem{Content: result, Source: models.SourceClipboard, IsSensitive: item.IsSensitive}
+}
+
+// copyItemToClipboard writes a text or image item to the clipboard
+func copyItemToClipboard(item models.ClipboardItem) error {
+	if item.Type == "image" {
+		if err := copyImageToClipboard(item); err != nil {
+			return err
 		}
+		slog.Debug("Copied image", "id", item.ID)
+		hooks.ItemCopied(item)
+		return nil
 	}
-	if len(lines) > 2 && indentedLines > len(lines)/2 {
-		return true
-	}
-	if strings.Contains(content, "{") && strings.Contains(content, "}") {
-		return true
+	copyTextToClipboard(item.Content, item.IsSensitive)
+	slog.Debug("Copied item", "id", item.ID, logging.Content(item.Content))
+	hooks.ItemCopied(item)
+	return nil
+}
+
+// copyTextToClipboard writes text and registers it as a self-write,
+// so the monitor does not capture it as a new item. Sensitive text is
+// cleared from the clipboard again after a while.
+func copyTextToClipboard(content string, sensitive bool) {
+	if sensitive {
+		monitor.WriteSecretText(content)
+		return
 	}
-	return false
+	monitor.MarkSelfWrittenText(content)
+	clipboard.Write(clipboard.FmtText, []byte(content))
 }
 
 func copyImageToClipboard(item models.ClipboardItem) error {
@@ -266,10 +353,7 @@ func copyImageToClipboard(item models.ClipboardItem) error {
 		return fmt.Errorf("failed to read image file: %w", err)
 	}
 
-	hash := sha256.Sum256(imageData)
-	hashStr := fmt.Sprintf("%x", hash[:8])
-	monitor.SetLastImageHash(hashStr)
-
+	monitor.MarkSelfWrittenImage(imageData)
 	clipboard.Write(clipboard.FmtImage, imageData)
 
 	return nil
@@ -0,0 +1,23 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestTransformedItem_KeepsSensitivity(t *testing.T) {
+	for _, sensitive := range []bool{false, true} {
+		item := models.ClipboardItem{ID: 7, Content: "hunter2", Type: "text", IsSensitive: sensitive}
+		got := transformedItem(item, "HUNTER2")
+		if got.Content != "HUNTER2" || got.ID != 0 {
+			t.Errorf("transformedItem = %+v, want new item with the transformed content", got)
+		}
+		if got.IsSensitive != sensitive {
+			t.Errorf("IsSensitive = %v, want %v", got.IsSensitive, sensitive)
+		}
+		if got.Source != models.SourceClipboard {
+			t.Errorf("Source = %q, want %q", got.Source, models.SourceClipboard)
+		}
+	}
+}
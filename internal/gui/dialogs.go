@@ -1,6 +1,7 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
 
 	"fyne.io/fyne/v2"
@@ -63,5 +64,5 @@ func ShowMigrationErrorDialog(win fyne.Window, err error) {
 		"Your original database is safe and unchanged.\n"+
 		"The app will continue using the unencrypted database.", err)
 
-	dialog.ShowError(fmt.Errorf(message), win)
+	dialog.ShowError(errors.New(message), win)
 }
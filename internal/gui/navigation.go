@@ -0,0 +1,302 @@
+package gui
+
+import (
+	"log/slog"
+	"slices"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// Shortcuts that work both in the search box and in the list
+var (
+	copyKeepOpenShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyReturn, Modifier: fyne.KeyModifierShift}
+	favoriteShortcut     = &desktop.CustomShortcut{KeyName: fyne.KeyF, Modifier: fyne.KeyModifierShortcutDefault}
+)
+
+// moveSelection returns the row index after moving by delta, clamped to the available rows
+func moveSelection(current, delta, total int) int {
+	if total == 0 {
+		return -1
+	}
+	next := current + delta
+	if next < 0 {
+		return 0
+	}
+	if next >= total {
+		return total - 1
+	}
+	return next
+}
+
+// pageOf returns the 1-based page holding the row at index
+func pageOf(index, pageSize int) int {
+	if index < 0 || pageSize <= 0 {
+		return 1
+	}
+	return index/pageSize + 1
+}
+
+// indexOfID returns the index of the item with id, or -1
+func indexOfID(items []models.ClipboardItem, id int) int {
+	return slices.IndexFunc(items, func(item models.ClipboardItem) bool { return id != 0 && item.ID == id })
+}
+
+// quickPickIndex maps number key n (1-9) to a row on the current page
+func quickPickIndex(n, page, pageSize, total int) (int, bool) {
+	if n < 1 || n > 9 || page < 1 {
+		return 0, false
+	}
+	index := (page-1)*pageSize + n - 1
+	if n > pageSize || index >= total {
+		return 0, false
+	}
+	return index, true
+}
+
+// searchEntry is the search box. Navigation keys move the row selection instead
+// of the text cursor, so the list can be driven without leaving the search box.
+type searchEntry struct {
+	widget.Entry
+	p *PastyClipboard
+}
+
+func newSearchEntry(p *PastyClipboard) *searchEntry {
+	e := &searchEntry{p: p}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *searchEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyDown, fyne.KeyPageDown, fyne.KeyPageUp, fyne.KeyUp:
+		// Arrow keys hand the keyboard over to the list
+		e.p.Win.Canvas().Unfocus()
+		e.p.handleListKey(key)
+	case fyne.KeyReturn, fyne.KeyEnter, fyne.KeyEscape:
+		e.p.handleListKey(key)
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
+
+func (e *searchEntry) TypedShortcut(s fyne.Shortcut) {
+	if e.p.handleShortcut(s) {
+		return
+	}
+	e.Entry.TypedShortcut(s)
+}
+
+// setupKeyboard routes keys typed while no widget has focus to the list
+func (p *PastyClipboard) setupKeyboard() {
+	canvas := p.Win.Canvas()
+	canvas.SetOnTypedKey(p.handleListKey)
+	canvas.SetOnTypedRune(p.handleListRune)
+	for _, s := range []*desktop.CustomShortcut{copyKeepOpenShortcut, favoriteShortcut} {
+		canvas.AddShortcut(s, func(sc fyne.Shortcut) {
+			p.handleShortcut(sc)
+		})
+	}
+}
+
+// ShowWindow shows and focuses the window with the search box ready
+func (p *PastyClipboard) ShowWindow() {
+	p.Win.Show()
+	p.Win.RequestFocus()
+	p.FocusSearch()
+	p.visible = true
+}
+
+// HideWindow hides the window. Everything that hides it goes through here,
+// so the toggle hotkey and the tray menu know whether it is shown.
+func (p *PastyClipboard) HideWindow() {
+	p.Win.Hide()
+	p.visible = false
+}
+
+// WindowVisible reports whether the window is shown
+func (p *PastyClipboard) WindowVisible() bool {
+	return p.visible
+}
+
+// FocusSearch selects the first row and puts the cursor in the search box.
+// ShowWindow calls it whenever the window is shown.
+func (p *PastyClipboard) FocusSearch() {
+	if p.searchEntry == nil {
+		return
+	}
+	p.selected = 0
+	p.currentPage = 1
+	p.updateHistoryUI(p.query)
+	p.Win.Canvas().Focus(p.searchEntry)
+}
+
+func (p *PastyClipboard) handleListKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyDown:
+		p.selectRow(moveSelection(p.selected, 1, len(p.filteredItems)))
+	case fyne.KeyUp:
+		if p.selected <= 0 {
+			// Moving up past the first row returns to the search box
+			p.Win.Canvas().Focus(p.searchEntry)
+			return
+		}
+		p.selectRow(moveSelection(p.selected, -1, len(p.filteredItems)))
+	case fyne.KeyPageDown:
+		p.selectRow(moveSelection(p.selected, p.pageSize, len(p.filteredItems)))
+	case fyne.KeyPageUp:
+		p.selectRow(moveSelection(p.selected, -p.pageSize, len(p.filteredItems)))
+	case fyne.KeyReturn, fyne.KeyEnter:
+		if item, ok := p.selectedItem(); ok {
+			p.copyItem(item, true)
+		}
+	case fyne.KeyDelete:
+		if item, ok := p.selectedItem(); ok {
+			p.deleteItem(item)
+		}
+	case fyne.KeyEscape:
+		p.HideWindow()
+	}
+}
+
+// handleListRune picks a row with 1-9 and sends any other text to the search box
+func (p *PastyClipboard) handleListRune(r rune) {
+	if r >= '1' && r <= '9' {
+		if index, ok := quickPickIndex(int(r-'0'), p.currentPage, p.pageSize, len(p.filteredItems)); ok {
+			p.selected = index
+			p.copyItem(p.filteredItems[index], true)
+		}
+		return
+	}
+	if !unicode.IsPrint(r) {
+		return
+	}
+	p.Win.Canvas().Focus(p.searchEntry)
+	p.searchEntry.TypedRune(r)
+}
+
+// handleShortcut runs the list shortcuts, reporting whether s was one of them
+func (p *PastyClipboard) handleShortcut(s fyne.Shortcut) bool {
+	switch s.ShortcutName() {
+	case copyKeepOpenShortcut.ShortcutName():
+		if item, ok := p.selectedItem(); ok {
+			p.copyItem(item, false)
+		}
+	case favoriteShortcut.ShortcutName():
+		if item, ok := p.selectedItem(); ok {
+			p.toggleFavorite(item)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// selectRow highlights the row at index, switching to its page if needed
+func (p *PastyClipboard) selectRow(index int) {
+	if index < 0 {
+		return
+	}
+	p.selected = index
+	p.currentPage = pageOf(index, p.pageSize)
+	p.updateHistoryUI(p.query)
+}
+
+func (p *PastyClipboard) selectedItem() (models.ClipboardItem, bool) {
+	if p.selected < 0 || p.selected >= len(p.filteredItems) {
+		return models.ClipboardItem{}, false
+	}
+	return p.filteredItems[p.selected], true
+}
+
+// copyItem puts an item on the clipboard and moves it to the top. With hide
+// the window closes and, if enabled, the item is pasted into the previous window.
+func (p *PastyClipboard) copyItem(item models.ClipboardItem, hide bool) {
+	if err := copyItemToClipboard(item); err != nil {
+		slog.Error("error copying item to clipboard", "err", err)
+		return
+	}
+	p.afterCopy(item, hide)
+}
+
+// CopyItem puts an item on the clipboard without showing the window. Must run on the Fyne thread.
+func (p *PastyClipboard) CopyItem(item models.ClipboardItem) error {
+	if err := copyItemToClipboard(item); err != nil {
+		return err
+	}
+	p.afterCopy(item, false)
+	return nil
+}
+
+// CopyText puts text that is not stored as-is, such as a transformed item, on the clipboard
+func (p *PastyClipboard) CopyText(text string, sensitive bool) {
+	copyTextToClipboard(text, sensitive)
+}
+
+func (p *PastyClipboard) afterCopy(item models.ClipboardItem, hide bool) {
+	p.moveToTop(item.ID)
+	p.selected = 0
+	p.currentPage = 1
+	p.updateHistoryUI(p.query)
+	if hide {
+		p.HideWindow()
+		p.autoPaste()
+	}
+}
+
+func (p *PastyClipboard) deleteItem(item models.ClipboardItem) {
+	if err := database.DeleteClipboardItem(item.ID); err != nil {
+		slog.Error("Failed to delete item", "err", err)
+	}
+	var newHistory []models.ClipboardItem
+	for _, hItem := range p.clipboardHistory {
+		if hItem.ID != item.ID {
+			newHistory = append(newHistory, hItem)
+		}
+	}
+	p.clipboardHistory = newHistory
+	p.updateHistoryUI(p.query)
+}
+
+func (p *PastyClipboard) toggleFavorite(item models.ClipboardItem) {
+	if err := database.UpdateItemFavorite(item.ID, !item.IsFavorite); err != nil {
+		slog.Error("Failed to update favorite", "err", err)
+		return
+	}
+	p.ReloadHistory()
+}
+
+// ReloadHistory re-reads the history from the database, e.g. after another
+// process changed it. Must run on the Fyne thread.
+func (p *PastyClipboard) ReloadHistory() {
+	items, err := database.GetClipboardHistory(100)
+	if err == nil {
+		p.clipboardHistory = items
+	}
+	p.updateHistoryUI(p.query)
+}
+
+// scrollToSelected keeps the highlighted row inside the visible part of the list
+func (p *PastyClipboard) scrollToSelected(rowOnPage int) {
+	if p.historyScroll == nil || rowOnPage < 0 || rowOnPage >= len(p.historyContainer.Objects) {
+		return
+	}
+	row := p.historyContainer.Objects[rowOnPage]
+	top := row.Position().Y
+	bottom := top + row.Size().Height
+	view := p.historyScroll.Size().Height
+
+	switch {
+	case top < p.historyScroll.Offset.Y:
+		p.historyScroll.Offset.Y = top
+	case bottom > p.historyScroll.Offset.Y+view:
+		p.historyScroll.Offset.Y = bottom - view
+	default:
+		return
+	}
+	p.historyScroll.Refresh()
+}
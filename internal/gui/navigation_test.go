@@ -0,0 +1,83 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestMoveSelection(t *testing.T) {
+	tests := []struct {
+		current, delta, total, expected int
+	}{
+		{0, 1, 5, 1},
+		{4, 1, 5, 4},
+		{0, -1, 5, 0},
+		{2, 10, 25, 12},
+		{20, 10, 25, 24},
+		{3, -10, 25, 0},
+		{0, 1, 0, -1},
+	}
+
+	for _, tt := range tests {
+		if result := moveSelection(tt.current, tt.delta, tt.total); result != tt.expected {
+			t.Errorf("moveSelection(%d, %d, %d) = %d, want %d", tt.current, tt.delta, tt.total, result, tt.expected)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	tests := []struct {
+		index, pageSize, expected int
+	}{
+		{0, 10, 1},
+		{9, 10, 1},
+		{10, 10, 2},
+		{25, 10, 3},
+		{-1, 10, 1},
+	}
+
+	for _, tt := range tests {
+		if result := pageOf(tt.index, tt.pageSize); result != tt.expected {
+			t.Errorf("pageOf(%d, %d) = %d, want %d", tt.index, tt.pageSize, result, tt.expected)
+		}
+	}
+}
+
+func TestQuickPickIndex(t *testing.T) {
+	tests := []struct {
+		n, page, pageSize, total int
+		expected                 int
+		ok                       bool
+	}{
+		{1, 1, 10, 5, 0, true},
+		{5, 1, 10, 5, 4, true},
+		{6, 1, 10, 5, 0, false},
+		{3, 2, 10, 25, 12, true},
+		{9, 3, 10, 25, 0, false},
+		{0, 1, 10, 5, 0, false},
+		{4, 1, 3, 10, 0, false},
+	}
+
+	for _, tt := range tests {
+		index, ok := quickPickIndex(tt.n, tt.page, tt.pageSize, tt.total)
+		if ok != tt.ok || (ok && index != tt.expected) {
+			t.Errorf("quickPickIndex(%d, %d, %d, %d) = %d, %v, want %d, %v", tt.n, tt.page, tt.pageSize, tt.total, index, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestIndexOfID(t *testing.T) {
+	items := []models.ClipboardItem{{ID: 5}, {ID: 3}, {ID: 9}}
+	tests := []struct{ id, expected int }{
+		{3, 1},
+		{9, 2},
+		{4, -1},
+		{0, -1},
+	}
+	for _, tt := range tests {
+		if result := indexOfID(items, tt.id); result != tt.expected {
+			t.Errorf("indexOfID(%d) = %d, want %d", tt.id, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+)
+
+const (
+	windowTitle       = "Pastee Clipboard"
+	pausedWindowTitle = "Pastee Clipboard (paused)"
+	resumeBtnText     = "Resume"
+)
+
+// PauseStatusText describes the pause state for the window banner and tray menu
+func PauseStatusText(state monitor.PauseState) string {
+	switch {
+	case !state.Paused:
+		return "Capturing clipboard"
+	case state.SessionLocked && state.Until.IsZero():
+		return "Capture paused while the session is locked"
+	case state.Until.IsZero():
+		return "Capture paused until resumed"
+	default:
+		return "Capture paused until " + state.Until.Format("15:04")
+	}
+}
+
+// pauseBar builds the banner shown above the search box while capture is paused
+func (p *PastyClipboard) pauseBar() *fyne.Container {
+	p.pauseLabel = widget.NewLabel("")
+	resumeButton := widget.NewButtonWithIcon(resumeBtnText, theme.MediaPlayIcon(), func() {
+		monitor.Resume()
+	})
+	resumeButton.Importance = widget.LowImportance
+
+	p.pauseBanner = container.NewBorder(nil, nil, widget.NewIcon(theme.MediaPauseIcon()), resumeButton, p.pauseLabel)
+	p.updatePauseIndicator(monitor.CurrentPauseState())
+	return p.pauseBanner
+}
+
+// updatePauseIndicator must be called on the Fyne goroutine
+func (p *PastyClipboard) updatePauseIndicator(state monitor.PauseState) {
+	if p.pauseBanner == nil {
+		return
+	}
+
+	if state.Paused {
+		p.pauseLabel.SetText(PauseStatusText(state))
+		p.pauseBanner.Show()
+		p.Win.SetTitle(pausedWindowTitle)
+	} else {
+		p.pauseBanner.Hide()
+		p.Win.SetTitle(windowTitle)
+	}
+}
@@ -0,0 +1,117 @@
+package gui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// CopyRegister puts the item held in a register on the clipboard without showing the window
+func (p *PastyClipboard) CopyRegister(name string) {
+	item, err := database.GetRegisterItem(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		p.App.SendNotification(&fyne.Notification{
+			Title:   "Register empty",
+			Content: fmt.Sprintf("Nothing is assigned to register %s", name),
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read register", "register", name, "err", err)
+		return
+	}
+	if err := p.CopyItem(*item); err != nil {
+		slog.Error("error copying item to clipboard", "err", err)
+		return
+	}
+	slog.Debug("Copied register", "register", name)
+}
+
+// registerMenuItems builds the ⋮ entries for assigning an item to a register
+// and for removing it from the registers that hold it
+func registerMenuItems(item models.ClipboardItem, onRefresh func(), win fyne.Window) []*fyne.MenuItem {
+	items := []*fyne.MenuItem{
+		fyne.NewMenuItem("Assign to Register…", func() {
+			showAssignRegisterDialog(item, onRefresh, win)
+		}),
+	}
+	if len(item.Registers) > 0 {
+		items = append(items, fyne.NewMenuItem("Remove from Register", func() {
+			for _, name := range item.Registers {
+				if err := database.ClearRegister(name); err != nil {
+					slog.Error("Failed to clear register", "register", name, "err", err)
+				}
+			}
+			if onRefresh != nil {
+				onRefresh()
+			}
+		}))
+	}
+	return items
+}
+
+// showAssignRegisterDialog asks which register should hold item, showing
+// what each occupied register currently contains
+func showAssignRegisterDialog(item models.ClipboardItem, onRefresh func(), win fyne.Window) {
+	occupied := make(map[string]string)
+	if registers, err := database.ListRegisters(); err == nil {
+		for _, r := range registers {
+			occupied[r.Name] = registerPreview(r.Item)
+		}
+	} else {
+		slog.Error("Failed to list registers", "err", err)
+	}
+
+	names := database.RegisterNames()
+	options := make([]string, len(names))
+	for i, name := range names {
+		options[i] = name
+		if preview, ok := occupied[name]; ok {
+			options[i] = fmt.Sprintf("%s — %s", name, preview)
+		}
+	}
+
+	choice := widget.NewSelect(options, nil)
+	for i, name := range names {
+		if _, ok := occupied[name]; !ok {
+			choice.SetSelectedIndex(i)
+			break
+		}
+	}
+
+	formItems := []*widget.FormItem{widget.NewFormItem("Register", choice)}
+	dlg := dialog.NewForm("Assign to Register", "Assign", "Cancel", formItems, func(confirmed bool) {
+		if !confirmed || choice.SelectedIndex() < 0 {
+			return
+		}
+		name := names[choice.SelectedIndex()]
+		if err := database.SetRegister(name, item.ID); err != nil {
+			slog.Error("Failed to assign register", "register", name, "err", err)
+			return
+		}
+		if onRefresh != nil {
+			onRefresh()
+		}
+	}, win)
+	dlg.Resize(fyne.NewSize(400, 180))
+	dlg.Show()
+}
+
+// registerPreview is a one-line description of a register's item
+func registerPreview(item models.ClipboardItem) string {
+	switch {
+	case item.Type == "image":
+		return "[Image]"
+	case item.IsSensitive:
+		return "••••••••"
+	}
+	return strings.ReplaceAll(truncateToLines(item.Content, 1, 40), "\n", " ")
+}
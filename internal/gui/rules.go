@@ -0,0 +1,105 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/monitor"
+	"github.com/Sirpyerre/pasteeclipboard/internal/rules"
+)
+
+var ruleSources = []string{models.SourceClipboard, models.SourcePrimary, models.SourceCLI, models.SourceLAN}
+
+// showRulesPreview opens a dialog showing what the capture rules would do to
+// item, or to its text as edited in the dialog, without storing anything.
+// The text of sensitive items is not shown.
+func showRulesPreview(item models.ClipboardItem, win fyne.Window) {
+	set := rules.Current()
+	path, _ := rules.Path()
+
+	entry := widget.NewMultiLineEntry()
+	entry.Wrapping = fyne.TextWrapWord
+	entry.SetMinRowsVisible(4)
+	switch {
+	case item.Type == monitor.TypeImage:
+		entry.SetText("(image)")
+		entry.Disable()
+	case item.IsSensitive:
+		// The list masks sensitive items, so don't reveal them here either
+		entry.SetPlaceHolder("Sensitive item hidden; type a text to test")
+	default:
+		entry.SetText(item.Content)
+	}
+	source := widget.NewSelect(ruleSources, nil)
+	result := widget.NewLabel("")
+	result.Wrapping = fyne.TextWrapWord
+
+	update := func() {
+		candidate := models.ClipboardItem{Type: item.Type, Source: source.Selected}
+		if item.Type != monitor.TypeImage {
+			candidate.Content = entry.Text
+			candidate.Type = monitor.DetectContentType(entry.Text)
+		}
+		result.SetText(describeOutcome(set, candidate))
+	}
+	entry.OnChanged = func(string) { update() }
+	source.OnChanged = func(string) { update() }
+	if item.Source != "" {
+		source.SetSelected(item.Source)
+	} else {
+		source.SetSelected(models.SourceClipboard)
+	}
+
+	content := container.NewVBox(
+		widget.NewLabel("Rules from "+path),
+		entry,
+		widget.NewForm(widget.NewFormItem("Copied from", source)),
+		widget.NewSeparator(),
+		result,
+	)
+	dlg := dialog.NewCustom("Test Capture Rules", "Close", content, win)
+	dlg.Resize(fyne.NewSize(420, 400))
+	dlg.Show()
+}
+
+// describeOutcome explains what the rules do to an item captured as given
+func describeOutcome(set *rules.Set, item models.ClipboardItem) string {
+	if set == nil || len(set.Rules) == 0 {
+		return "There are no capture rules."
+	}
+	out := set.Evaluate(item)
+	if len(out.Matched) == 0 {
+		return fmt.Sprintf("No rule matches this %s item; it is stored as copied.", item.Type)
+	}
+
+	lines := []string{"Matching rules: " + strings.Join(out.Matched, ", ")}
+	if out.Ignore {
+		return lines[0] + "\nThe item is ignored and not stored."
+	}
+	if out.Item.Type != item.Type {
+		lines = append(lines, fmt.Sprintf("Type: %s instead of %s", out.Item.Type, item.Type))
+	}
+	if out.Item.IsSensitive && !item.IsSensitive {
+		lines = append(lines, "Marked sensitive")
+	}
+	if len(out.Item.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(out.Item.Tags, ", "))
+	}
+	if !out.Item.ExpiresAt.IsZero() {
+		lines = append(lines, "Deleted after "+time.Until(out.Item.ExpiresAt).Round(time.Second).String())
+	}
+	if out.Item.Content != item.Content {
+		if out.Item.Content == "" {
+			lines = append(lines, "Stripped to nothing, so it is not stored")
+		} else {
+			lines = append(lines, "Stored as: "+truncateToLines(out.Item.Content, 5, 60))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
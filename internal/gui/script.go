@@ -0,0 +1,47 @@
+package gui
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/scripting"
+)
+
+// scriptBar builds the banner shown above the search box while the user script is failing
+func (p *PastyClipboard) scriptBar() *fyne.Container {
+	p.scriptErrorLabel = widget.NewLabel("")
+	p.scriptErrorLabel.Truncation = fyne.TextTruncateEllipsis
+	p.scriptBanner = container.NewBorder(nil, nil, widget.NewIcon(theme.ErrorIcon()), nil, p.scriptErrorLabel)
+	p.updateScriptError(scripting.LastError())
+
+	scripting.OnError(func(err error) {
+		if err != nil {
+			p.App.SendNotification(&fyne.Notification{
+				Title:   "Pastee script error",
+				Content: err.Error(),
+			})
+		}
+		fyne.Do(func() {
+			p.updateScriptError(err)
+		})
+	})
+	return p.scriptBanner
+}
+
+// updateScriptError must be called on the Fyne goroutine
+func (p *PastyClipboard) updateScriptError(err error) {
+	if p.scriptBanner == nil {
+		return
+	}
+	if err == nil {
+		p.scriptBanner.Hide()
+		return
+	}
+	// The full message, with the script's stack trace, is in the notification and the log
+	firstLine, _, _ := strings.Cut(err.Error(), "\n")
+	p.scriptErrorLabel.SetText("Script error: " + firstLine)
+	p.scriptBanner.Show()
+}
@@ -0,0 +1,177 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/Sirpyerre/pasteeclipboard/internal/autopaste"
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+)
+
+// HotkeyBinder registers spec for a hotkey action, replacing its current combination
+type HotkeyBinder func(action, spec string) error
+
+// hotkeySetting ties a settings form row to its config field
+type hotkeySetting struct {
+	action string
+	label  string
+	get    func(config.HotkeysConfig) string
+	set    func(*config.HotkeysConfig, string)
+}
+
+var hotkeySettings = []hotkeySetting{
+	{hotkeys.ActionToggleWindow, "Show/hide window",
+		func(h config.HotkeysConfig) string { return h.ToggleWindow },
+		func(h *config.HotkeysConfig, spec string) { h.ToggleWindow = spec }},
+	{hotkeys.ActionPauseCapture, "Pause/resume capture",
+		func(h config.HotkeysConfig) string { return h.PauseCapture },
+		func(h *config.HotkeysConfig, spec string) { h.PauseCapture = spec }},
+	{hotkeys.ActionPastePrevious, "Paste previous item",
+		func(h config.HotkeysConfig) string { return h.PastePrevious },
+		func(h *config.HotkeysConfig, spec string) { h.PastePrevious = spec }},
+}
+
+// registerHotkeySettings returns a row for each register that holds an item
+// or already has a hotkey, in register order. Listing all 35 would bury the
+// rest of the dialog.
+func registerHotkeySettings(current config.HotkeysConfig, filled []string) []hotkeySetting {
+	var settings []hotkeySetting
+	for _, name := range database.RegisterNames() {
+		if current.Registers[name] == "" && !slices.Contains(filled, name) {
+			continue
+		}
+		settings = append(settings, hotkeySetting{
+			action: hotkeys.RegisterAction(name),
+			label:  "Copy register " + name,
+			get:    func(h config.HotkeysConfig) string { return h.Registers[name] },
+			set: func(h *config.HotkeysConfig, spec string) {
+				if spec == "" {
+					delete(h.Registers, name)
+					return
+				}
+				if h.Registers == nil {
+					h.Registers = make(map[string]string)
+				}
+				h.Registers[name] = spec
+			},
+		})
+	}
+	return settings
+}
+
+// SetHotkeyBinder lets the settings dialog re-bind hotkeys at runtime
+func (p *PastyClipboard) SetHotkeyBinder(b HotkeyBinder) {
+	p.bindHotkey = b
+}
+
+// ShowHotkeySettings opens a dialog for changing the global hotkeys
+func (p *PastyClipboard) ShowHotkeySettings() {
+	if p.bindHotkey == nil {
+		return
+	}
+
+	current := config.Get().Hotkeys
+	var filled []string
+	if registers, err := database.ListRegisters(); err == nil {
+		for _, r := range registers {
+			filled = append(filled, r.Name)
+		}
+	} else {
+		slog.Error("Failed to list registers", "err", err)
+	}
+	settings := append(slices.Clone(hotkeySettings), registerHotkeySettings(current, filled)...)
+
+	entries := make([]*widget.Entry, len(settings))
+	var items []*widget.FormItem
+	for i, s := range settings {
+		entries[i] = widget.NewEntry()
+		entries[i].SetText(s.get(current))
+		entries[i].SetPlaceHolder("e.g. ctrl+alt+v, empty to disable")
+		items = append(items, widget.NewFormItem(s.label, entries[i]))
+	}
+
+	dlg := dialog.NewForm("Hotkeys", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		specs := make([]string, len(entries))
+		for i, e := range entries {
+			specs[i] = strings.TrimSpace(e.Text)
+		}
+		// Registration may need the main thread on some platforms, so keep it off the UI goroutine
+		go p.applyHotkeys(current, settings, specs)
+	}, p.Win)
+	dlg.Resize(fyne.NewSize(420, float32(120+40*len(settings))))
+	dlg.Show()
+}
+
+// applyHotkeys binds every changed hotkey and saves the ones that succeeded
+func (p *PastyClipboard) applyHotkeys(previous config.HotkeysConfig, settings []hotkeySetting, specs []string) {
+	updated := previous
+	updated.Registers = maps.Clone(previous.Registers)
+	changed := false
+	var errs []error
+	for i, s := range settings {
+		if specs[i] == s.get(previous) {
+			continue
+		}
+		if err := p.bindHotkey(s.action, specs[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.label, err))
+			continue
+		}
+		s.set(&updated, specs[i])
+		changed = true
+	}
+
+	if changed {
+		if err := config.Update(func(cfg *config.Config) { cfg.Hotkeys = updated }); err != nil {
+			slog.Error("Failed to save hotkey settings", "err", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		fyne.Do(func() {
+			dialog.ShowError(errors.Join(errs...), p.Win)
+		})
+	}
+}
+
+// ShowHotkeyError tells the user a hotkey could not be registered. Safe to call from any goroutine.
+func (p *PastyClipboard) ShowHotkeyError(err error) {
+	fyne.Do(func() {
+		p.App.SendNotification(&fyne.Notification{
+			Title:   "Hotkey unavailable",
+			Content: err.Error(),
+		})
+		dialog.ShowError(fmt.Errorf("hotkey unavailable: %w\nChange it from the tray menu under Hotkeys…", err), p.Win)
+	})
+}
+
+// PastePrevious copies the item before the current clipboard entry and
+// pastes it into the focused window
+func (p *PastyClipboard) PastePrevious() {
+	if len(p.clipboardHistory) < 2 {
+		return
+	}
+	item := p.clipboardHistory[1]
+	if err := copyItemToClipboard(item); err != nil {
+		slog.Error("error copying item to clipboard", "err", err)
+		return
+	}
+	p.afterCopy(item, false)
+
+	go func() {
+		if err := autopaste.PasteIntoActive(); err != nil {
+			slog.Error("Paste previous failed", "err", err)
+		}
+	}()
+}
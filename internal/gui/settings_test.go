@@ -0,0 +1,37 @@
+package gui
+
+import (
+	"testing"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hotkeys"
+)
+
+func TestRegisterHotkeySettings(t *testing.T) {
+	current := config.HotkeysConfig{Registers: map[string]string{"c": "ctrl+alt+c"}}
+	settings := registerHotkeySettings(current, []string{"1", "a"})
+
+	var actions []string
+	for _, s := range settings {
+		actions = append(actions, s.action)
+	}
+	want := []string{hotkeys.RegisterAction("a"), hotkeys.RegisterAction("c"), hotkeys.RegisterAction("1")}
+	if len(actions) != len(want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("actions = %v, want %v", actions, want)
+		}
+	}
+
+	var updated config.HotkeysConfig
+	settings[0].set(&updated, "ctrl+alt+a")
+	if got := settings[0].get(updated); got != "ctrl+alt+a" {
+		t.Errorf("get after set = %q, want %q", got, "ctrl+alt+a")
+	}
+	settings[0].set(&updated, "")
+	if _, ok := updated.Registers["a"]; ok {
+		t.Error("clearing a register hotkey should remove it from the config")
+	}
+}
@@ -0,0 +1,254 @@
+// Package hooks runs user commands on clipboard events. A command gets the
+// event and the item as JSON on stdin; transform commands print the content
+// to store instead of what was captured.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// Events a command can run on
+const (
+	EventItemAdded   = "item_added"   // A new item was stored
+	EventItemCopied  = "item_copied"  // An item was put back on the clipboard from the history
+	EventItemDeleted = "item_deleted" // An item was deleted or pruned; only its id is known
+	EventTransform   = "transform"    // Captured text is about to be stored
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	// maxQueued bounds the event commands waiting for a free slot; later events are dropped
+	maxQueued = 64
+	// maxStderr is how much of a failed command's error output is logged
+	maxStderr = 512
+)
+
+var (
+	mu     sync.Mutex
+	slots  chan struct{} // Sized by max_concurrent on first use
+	queued int
+
+	pending sync.WaitGroup
+)
+
+// payload is what a command reads on stdin
+type payload struct {
+	Event  string   `json:"event"`
+	Item   itemJSON `json:"item"`
+	Pruned bool     `json:"pruned,omitempty"` // item_deleted: removed by the history limit
+}
+
+type itemJSON struct {
+	ID        int       `json:"id,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	ImagePath string    `json:"image_path,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Favorite  bool      `json:"favorite"`
+	Sensitive bool      `json:"sensitive"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+func newPayload(event string, item models.ClipboardItem) payload {
+	return payload{Event: event, Item: itemJSON{
+		ID:        item.ID,
+		Type:      item.Type,
+		Content:   item.Content,
+		ImagePath: item.ImagePath,
+		Title:     item.Title,
+		Source:    item.Source,
+		Favorite:  item.IsFavorite,
+		Sensitive: item.IsSensitive,
+		CreatedAt: item.CreatedAt,
+	}}
+}
+
+// Start runs the item_added and item_deleted commands for changes to the
+// history when hooks are enabled. Call the returned function on exit; it
+// waits for commands already started.
+func Start() func() {
+	if !config.Get().Hooks.Enabled {
+		return func() {}
+	}
+	remove := database.AddItemListener(database.ItemListener{
+		Added: func(item models.ClipboardItem) {
+			dispatch(newPayload(EventItemAdded, item), item.IsSensitive)
+		},
+		Removed: func(id int, pruned bool) {
+			dispatch(payload{Event: EventItemDeleted, Item: itemJSON{ID: id}, Pruned: pruned}, false)
+		},
+	})
+	return func() {
+		remove()
+		pending.Wait()
+	}
+}
+
+// ItemCopied runs the item_copied commands for an item put on the clipboard
+func ItemCopied(item models.ClipboardItem) {
+	dispatch(newPayload(EventItemCopied, item), item.IsSensitive)
+}
+
+// Transform passes item's content through the transform commands in order and
+// returns what the last one printed. A command that fails, times out or
+// prints nothing leaves the content as it was.
+func Transform(item models.ClipboardItem) string {
+	cfg := config.Get().Hooks
+	content := item.Content
+	if !cfg.Enabled {
+		return content
+	}
+	for _, h := range cfg.Commands {
+		if !matches(h, EventTransform, item.Type, item.IsSensitive) {
+			continue
+		}
+		item.Content = content
+		out, err := run(h, newPayload(EventTransform, item), timeout(cfg), database.MaxTextLength+1)
+		if err != nil {
+			slog.Error("Transform hook failed, keeping content", "hook", h.Command[0], "err", err)
+			continue
+		}
+		if out := trimNewline(string(out), content); out != "" {
+			content = out
+		}
+	}
+	return content
+}
+
+// dispatch starts the commands configured for p's event in the background
+func dispatch(p payload, sensitive bool) {
+	cfg := config.Get().Hooks
+	if !cfg.Enabled {
+		return
+	}
+	for _, h := range cfg.Commands {
+		if matches(h, p.Event, p.Item.Type, sensitive) {
+			enqueue(cfg, h, p)
+		}
+	}
+}
+
+func enqueue(cfg config.HooksConfig, h config.HookCommand, p payload) {
+	mu.Lock()
+	if slots == nil {
+		slots = make(chan struct{}, max(cfg.MaxConcurrent, 1))
+	}
+	if queued >= maxQueued {
+		mu.Unlock()
+		slog.Warn("Too many hooks waiting, skipped hook", "hook", h.Command[0], "event", p.Event)
+		return
+	}
+	queued++
+	sem := slots
+	mu.Unlock()
+
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		sem <- struct{}{}
+		defer func() { <-sem }()
+		mu.Lock()
+		queued--
+		mu.Unlock()
+
+		if _, err := run(h, p, timeout(cfg), 0); err != nil {
+			slog.Error("Hook failed", "hook", h.Command[0], "event", p.Event, "err", err)
+		}
+	}()
+}
+
+// matches reports whether h runs for an event on an item of the given type.
+// Sensitive items only reach commands that allow them.
+func matches(h config.HookCommand, event, itemType string, sensitive bool) bool {
+	if h.Event != event || len(h.Command) == 0 {
+		return false
+	}
+	if sensitive && !h.AllowSensitive {
+		return false
+	}
+	// Deleted items have no known type, so the filter does not apply to them
+	return itemType == "" || len(h.Types) == 0 || slices.Contains(h.Types, itemType)
+}
+
+func timeout(cfg config.HooksConfig) time.Duration {
+	if cfg.TimeoutMs <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(cfg.TimeoutMs) * time.Millisecond
+}
+
+// run executes h with p on stdin and returns up to maxOut bytes of its output
+func run(h config.HookCommand, p payload, timeout time.Duration, maxOut int) ([]byte, error) {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"PASTEE_EVENT="+p.Event,
+		"PASTEE_ITEM_ID="+strconv.Itoa(p.Item.ID),
+		"PASTEE_ITEM_TYPE="+p.Item.Type,
+	)
+	stdout := &cappedBuffer{max: maxOut}
+	stderr := &cappedBuffer{max: maxStderr}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Children left holding the output pipes must not keep the hook running
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// trimNewline drops the line ending commands print after their output,
+// unless the original content ended with one too
+func trimNewline(out, original string) string {
+	if strings.HasSuffix(original, "\n") {
+		return out
+	}
+	out = strings.TrimSuffix(out, "\n")
+	return strings.TrimSuffix(out, "\r")
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
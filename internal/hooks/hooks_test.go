@@ -0,0 +1,164 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// useHooks makes cmds the configured hooks for the rest of the test
+func useHooks(t *testing.T, cmds ...config.HookCommand) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use shell scripts")
+	}
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Hooks.Enabled = true
+	cfg.Hooks.TimeoutMs = 2000
+	cfg.Hooks.Commands = cmds
+	if err := config.Save(filepath.Join(dir, config.FileName), cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(dir, "missing.json")) })
+}
+
+func sh(event, script string) config.HookCommand {
+	return config.HookCommand{Event: event, Command: []string{"/bin/sh", "-c", script}}
+}
+
+// contentOf prints the content of the item read from stdin
+const contentOf = `sed 's/.*"content":"\([^"]*\)".*/\1/'`
+
+func TestTransform(t *testing.T) {
+	useHooks(t,
+		sh(EventTransform, contentOf+` | tr a-z A-Z`),
+		sh(EventTransform, `echo "$(`+contentOf+`)!"`),
+	)
+
+	got := Transform(models.ClipboardItem{Content: "hello", Type: "text"})
+	if got != "HELLO!" {
+		t.Errorf("Transform = %q, want the output of both commands with the newline trimmed", got)
+	}
+}
+
+func TestTransform_KeepsContentOnFailure(t *testing.T) {
+	useHooks(t,
+		sh(EventTransform, "echo broken; exit 1"),
+		sh(EventTransform, "true"),
+		sh(EventTransform, "sleep 5"),
+	)
+	cfg := *config.Get()
+	cfg.Hooks.TimeoutMs = 100
+	config.Save(filepath.Join(t.TempDir(), config.FileName), &cfg)
+
+	start := time.Now()
+	if got := Transform(models.ClipboardItem{Content: "keep me", Type: "text"}); got != "keep me" {
+		t.Errorf("Transform = %q, want the content unchanged", got)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("a hanging transform should be stopped by the timeout")
+	}
+}
+
+func TestTransform_Filters(t *testing.T) {
+	upper := sh(EventTransform, "echo changed")
+	upper.Types = []string{"link"}
+	useHooks(t, upper)
+
+	if got := Transform(models.ClipboardItem{Content: "plain", Type: "text"}); got != "plain" {
+		t.Errorf("a link-only transform ran for text: %q", got)
+	}
+	if got := Transform(models.ClipboardItem{Content: "https://x", Type: "link", IsSensitive: true}); got != "https://x" {
+		t.Errorf("a transform ran for a sensitive item: %q", got)
+	}
+	if got := Transform(models.ClipboardItem{Content: "https://x", Type: "link"}); got != "changed" {
+		t.Errorf("Transform = %q, want changed", got)
+	}
+}
+
+func TestItemCopied(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "copied.json")
+	secret := filepath.Join(t.TempDir(), "secret.json")
+	allowed := sh(EventItemCopied, `cat > `+secret)
+	allowed.AllowSensitive = true
+	useHooks(t, sh(EventItemCopied, `{ cat; echo; echo "$PASTEE_EVENT $PASTEE_ITEM_ID"; } > `+out), allowed)
+
+	ItemCopied(models.ClipboardItem{ID: 7, Type: "text", Content: "hunter2", IsSensitive: true})
+	pending.Wait()
+	if _, err := os.Stat(out); err == nil {
+		t.Error("a sensitive item was given to a hook that does not allow it")
+	}
+	if _, err := os.Stat(secret); err != nil {
+		t.Error("a hook allowing sensitive items should run for them")
+	}
+
+	ItemCopied(models.ClipboardItem{ID: 8, Type: "text", Content: "hello", Source: models.SourceClipboard})
+	pending.Wait()
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	input, env, _ := strings.Cut(string(data), "\n")
+	var p payload
+	if err := json.Unmarshal([]byte(input), &p); err != nil {
+		t.Fatalf("hook stdin is not JSON: %v", err)
+	}
+	if p.Event != EventItemCopied || p.Item.ID != 8 || p.Item.Content != "hello" || p.Item.Source != models.SourceClipboard {
+		t.Errorf("unexpected payload %+v", p)
+	}
+	if strings.TrimSpace(env) != "item_copied 8" {
+		t.Errorf("unexpected environment %q", env)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	dir := t.TempDir()
+	// Each run marks itself as running and notes an overlap if another one is
+	script := `if ls ` + dir + `/running-* >/dev/null 2>&1; then touch ` + dir + `/overlap; fi
+touch ` + dir + `/running-$$; sleep 0.1; rm ` + dir + `/running-$$; touch ` + dir + `/done-$$`
+	useHooks(t, sh(EventItemCopied, script))
+	cfg := *config.Get()
+	cfg.Hooks.MaxConcurrent = 1
+	config.Save(filepath.Join(t.TempDir(), config.FileName), &cfg)
+	mu.Lock()
+	slots = nil
+	mu.Unlock()
+
+	for i := range 4 {
+		ItemCopied(models.ClipboardItem{ID: i + 1, Type: "text", Content: "x"})
+	}
+	pending.Wait()
+
+	done, _ := filepath.Glob(filepath.Join(dir, "done-*"))
+	if len(done) != 4 {
+		t.Errorf("%d of 4 hooks ran", len(done))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "overlap")); err == nil {
+		t.Error("hooks ran concurrently with max_concurrent 1")
+	}
+}
+
+func TestDisabledHooksDoNotRun(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "ran")
+	useHooks(t, sh(EventItemCopied, "touch "+out), sh(EventTransform, "echo changed"))
+	cfg := *config.Get()
+	cfg.Hooks.Enabled = false
+	config.Save(filepath.Join(t.TempDir(), config.FileName), &cfg)
+
+	ItemCopied(models.ClipboardItem{ID: 1, Type: "text", Content: "x"})
+	pending.Wait()
+	if _, err := os.Stat(out); err == nil {
+		t.Error("a hook ran while hooks are disabled")
+	}
+	if got := Transform(models.ClipboardItem{Content: "x", Type: "text"}); got != "x" {
+		t.Errorf("a transform ran while hooks are disabled: %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build linux
+
+package hotkeys
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/x11"
+)
+
+// lockMasks are grabbed alongside each combination so hotkeys keep working
+// while Caps Lock or Num Lock (Mod2) is on
+var lockMasks = []uint16{0, xproto.ModMaskLock, xproto.ModMask2, xproto.ModMaskLock | xproto.ModMask2}
+
+type grabKey struct {
+	mods uint16
+	code xproto.Keycode
+}
+
+// x11Backend grabs keys on the root window over its own X connection. Unlike
+// the hotkey package it reports combinations held by other clients as errors
+// instead of aborting, and releases grabs immediately.
+type x11Backend struct {
+	mu       sync.Mutex
+	conn     *xgb.Conn
+	root     xproto.Window
+	handlers map[grabKey]func()
+}
+
+func newBackend() backend {
+	return &x11Backend{handlers: make(map[grabKey]func())}
+}
+
+func (x *x11Backend) connectLocked() error {
+	if x.conn != nil {
+		return nil
+	}
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	x.conn = conn
+	x.root = xproto.Setup(conn).DefaultScreen(conn).Root
+	go x.listen(conn)
+	return nil
+}
+
+func (x *x11Backend) register(b Binding, fn func()) (func(), error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := x.connectLocked(); err != nil {
+		return nil, err
+	}
+	gk, err := x.grabKeyFor(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := x.grabLocked(gk); err != nil {
+		return nil, err
+	}
+	x.handlers[gk] = fn
+
+	return func() {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		x.ungrabLocked(gk)
+		delete(x.handlers, gk)
+	}, nil
+}
+
+func (x *x11Backend) available(b Binding) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := x.connectLocked(); err != nil {
+		return false
+	}
+	gk, err := x.grabKeyFor(b)
+	if err != nil {
+		return false
+	}
+	if _, ours := x.handlers[gk]; ours {
+		return false
+	}
+	if err := x.grabLocked(gk); err != nil {
+		return false
+	}
+	x.ungrabLocked(gk)
+	return true
+}
+
+func (x *x11Backend) grabKeyFor(b Binding) (grabKey, error) {
+	code, err := x11.KeycodeFor(x.conn, xproto.Keysym(b.Key))
+	if err != nil {
+		return grabKey{}, err
+	}
+	var mods uint16
+	for _, m := range b.Mods {
+		mods |= uint16(m)
+	}
+	return grabKey{mods: mods, code: code}, nil
+}
+
+// grabLocked grabs every lock-key variant, undoing partial grabs on failure
+func (x *x11Backend) grabLocked(gk grabKey) error {
+	for i, lock := range lockMasks {
+		err := xproto.GrabKeyChecked(x.conn, true, x.root, gk.mods|lock, gk.code, xproto.GrabModeAsync, xproto.GrabModeAsync).Check()
+		if err == nil {
+			continue
+		}
+		for _, grabbed := range lockMasks[:i] {
+			_ = xproto.UngrabKeyChecked(x.conn, gk.code, x.root, gk.mods|grabbed).Check()
+		}
+		var access xproto.AccessError
+		if errors.As(err, &access) {
+			return errTaken
+		}
+		return err
+	}
+	return nil
+}
+
+func (x *x11Backend) ungrabLocked(gk grabKey) {
+	for _, lock := range lockMasks {
+		if err := xproto.UngrabKeyChecked(x.conn, gk.code, x.root, gk.mods|lock).Check(); err != nil {
+			slog.Error("Error releasing hotkey", "err", err)
+		}
+	}
+}
+
+// listen dispatches key presses for grabbed combinations until the connection closes
+func (x *x11Backend) listen(conn *xgb.Conn) {
+	const lockBits = xproto.ModMaskLock | xproto.ModMask2
+	for {
+		ev, err := conn.WaitForEvent()
+		if ev == nil && err == nil {
+			return
+		}
+		press, ok := ev.(xproto.KeyPressEvent)
+		if !ok {
+			continue
+		}
+
+		gk := grabKey{mods: press.State &^ lockBits & 0xff, code: press.Detail}
+		x.mu.Lock()
+		fn := x.handlers[gk]
+		x.mu.Unlock()
+		if fn != nil {
+			go fn()
+		}
+	}
+}
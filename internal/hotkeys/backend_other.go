@@ -0,0 +1,51 @@
+//go:build !linux
+
+package hotkeys
+
+import (
+	"fmt"
+
+	"golang.design/x/hotkey"
+)
+
+// libBackend registers hotkeys through the OS APIs wrapped by golang.design/x/hotkey
+type libBackend struct{}
+
+func newBackend() backend {
+	return libBackend{}
+}
+
+func newHotkey(b Binding) *hotkey.Hotkey {
+	mods := make([]hotkey.Modifier, len(b.Mods))
+	for i, m := range b.Mods {
+		mods[i] = hotkey.Modifier(m)
+	}
+	return hotkey.New(mods, hotkey.Key(b.Key))
+}
+
+func (libBackend) register(b Binding, fn func()) (func(), error) {
+	hk := newHotkey(b)
+	if err := hk.Register(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errTaken, err)
+	}
+
+	keydown := hk.Keydown()
+	go func() {
+		for range keydown {
+			fn()
+		}
+	}()
+
+	return func() {
+		_ = hk.Unregister()
+	}, nil
+}
+
+func (libBackend) available(b Binding) bool {
+	hk := newHotkey(b)
+	if err := hk.Register(); err != nil {
+		return false
+	}
+	_ = hk.Unregister()
+	return true
+}
@@ -0,0 +1,168 @@
+package hotkeys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Actions that can be bound to a global hotkey
+const (
+	ActionToggleWindow  = "toggle_window"
+	ActionPauseCapture  = "pause_capture"
+	ActionPastePrevious = "paste_previous"
+
+	// actionRegisterPrefix is followed by the register name, e.g. "register_1"
+	actionRegisterPrefix = "register_"
+)
+
+// RegisterAction returns the action that copies the item held in a named register
+func RegisterAction(name string) string {
+	return actionRegisterPrefix + name
+}
+
+// errTaken is wrapped by backends when the OS refuses a combination
+var errTaken = errors.New("key combination is taken")
+
+// Modifier names one modifier key as written in hotkey settings
+type Modifier struct {
+	Name    string   // Canonical name used when formatting, e.g. "ctrl"
+	Aliases []string // Other accepted spellings, e.g. "control"
+	Mod     ModMask
+}
+
+// Modifiers lists the modifiers of one platform in display order
+type Modifiers []Modifier
+
+func (ms Modifiers) lookup(name string) (int, bool) {
+	for i, m := range ms {
+		if m.Name == name {
+			return i, true
+		}
+		for _, alias := range m.Aliases {
+			if alias == name {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (ms Modifiers) names() string {
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Binding is a parsed key combination
+type Binding struct {
+	Mods    []ModMask
+	Key     Key
+	modIdx  []int // Positions in Modifiers, in display order
+	keyName string
+}
+
+// String formats the binding in canonical form, e.g. "ctrl+shift+v"
+func (b Binding) String(ms Modifiers) string {
+	var parts []string
+	for _, i := range b.modIdx {
+		parts = append(parts, ms[i].Name)
+	}
+	return strings.Join(append(parts, b.keyName), "+")
+}
+
+func (b Binding) equal(o Binding) bool {
+	if b.Key != o.Key || len(b.modIdx) != len(o.modIdx) {
+		return false
+	}
+	for i := range b.modIdx {
+		if b.modIdx[i] != o.modIdx[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b Binding) has(idx int) bool {
+	for _, i := range b.modIdx {
+		if i == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// with returns a copy of b with the modifier at idx added
+func (b Binding) with(ms Modifiers, idx int) Binding {
+	out := Binding{Key: b.Key, keyName: b.keyName}
+	for i := range ms {
+		if i == idx || b.has(i) {
+			out.modIdx = append(out.modIdx, i)
+			out.Mods = append(out.Mods, ms[i].Mod)
+		}
+	}
+	return out
+}
+
+// Parse reads a combination such as "ctrl+shift+v" using the platform's
+// modifier names. Letters, digits and other typing keys need a modifier.
+func Parse(spec string, ms Modifiers) (Binding, error) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(spec), ""))
+	if normalized == "" {
+		return Binding{}, errors.New("empty hotkey")
+	}
+
+	parts := strings.Split(normalized, "+")
+	keyName := parts[len(parts)-1]
+	key, ok := keyNames()[keyName]
+	if !ok {
+		return Binding{}, fmt.Errorf("unknown key %q in %q", keyName, spec)
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range parts[:len(parts)-1] {
+		idx, ok := ms.lookup(part)
+		if !ok {
+			return Binding{}, fmt.Errorf("unknown modifier %q in %q (use %s)", part, spec, ms.names())
+		}
+		if seen[idx] {
+			return Binding{}, fmt.Errorf("modifier %q repeated in %q", part, spec)
+		}
+		seen[idx] = true
+	}
+
+	if len(seen) == 0 && !isFunctionKey(keyName) {
+		return Binding{}, fmt.Errorf("%q needs at least one modifier", spec)
+	}
+
+	b := Binding{Key: key, keyName: canonicalKeyName(keyName)}
+	for i := range ms {
+		if seen[i] {
+			b.modIdx = append(b.modIdx, i)
+			b.Mods = append(b.Mods, ms[i].Mod)
+		}
+	}
+	return b, nil
+}
+
+// ConflictError reports a combination that is already in use
+type ConflictError struct {
+	Spec       string // The requested combination
+	Owner      string // Pastee action already using it; empty if another application holds it
+	Suggestion string // A free combination close to Spec, if one was found
+}
+
+func (e *ConflictError) Error() string {
+	var msg string
+	if e.Owner != "" {
+		msg = fmt.Sprintf("%s is already used for %s", e.Spec, strings.ReplaceAll(e.Owner, "_", " "))
+	} else {
+		msg = fmt.Sprintf("%s is already taken by another application", e.Spec)
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("; try %s instead", e.Suggestion)
+	}
+	return msg
+}
@@ -0,0 +1,158 @@
+package hotkeys
+
+import (
+	"errors"
+	"testing"
+)
+
+var testModifiers = Modifiers{
+	{Name: "ctrl", Aliases: []string{"control"}, Mod: 1},
+	{Name: "alt", Aliases: []string{"option"}, Mod: 2},
+	{Name: "shift", Mod: 4},
+	{Name: "super", Aliases: []string{"win", "cmd"}, Mod: 8},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+		mods     []ModMask
+	}{
+		{"ctrl+shift+v", "ctrl+shift+v", []ModMask{1, 4}},
+		{"Shift + Control + V", "ctrl+shift+v", []ModMask{1, 4}},
+		{"option+cmd+1", "alt+super+1", []ModMask{2, 8}},
+		{"ctrl+alt+Return", "ctrl+alt+enter", []ModMask{1, 2}},
+		{"f9", "f9", nil},
+	}
+
+	for _, tt := range tests {
+		b, err := Parse(tt.spec, testModifiers)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.spec, err)
+			continue
+		}
+		if got := b.String(testModifiers); got != tt.expected {
+			t.Errorf("Parse(%q) formats as %q, want %q", tt.spec, got, tt.expected)
+		}
+		if len(b.Mods) != len(tt.mods) {
+			t.Errorf("Parse(%q) mods = %v, want %v", tt.spec, b.Mods, tt.mods)
+			continue
+		}
+		for i := range b.Mods {
+			if b.Mods[i] != tt.mods[i] {
+				t.Errorf("Parse(%q) mods = %v, want %v", tt.spec, b.Mods, tt.mods)
+			}
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{"", "ctrl+", "ctrl+hyper+v", "ctrl+ctrl+v", "ctrl+shift+nokey", "v", "shift"} {
+		if _, err := Parse(spec, testModifiers); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
+
+// fakeBackend registers everything except combinations listed in taken
+type fakeBackend struct {
+	taken  map[string]bool
+	active map[string]func()
+}
+
+func newFakeBackend(taken ...string) *fakeBackend {
+	f := &fakeBackend{taken: map[string]bool{}, active: map[string]func(){}}
+	for _, spec := range taken {
+		f.taken[spec] = true
+	}
+	return f
+}
+
+func (f *fakeBackend) register(b Binding, fn func()) (func(), error) {
+	spec := b.String(testModifiers)
+	if f.taken[spec] || f.active[spec] != nil {
+		return nil, errTaken
+	}
+	f.active[spec] = fn
+	return func() { delete(f.active, spec) }, nil
+}
+
+func (f *fakeBackend) available(b Binding) bool {
+	spec := b.String(testModifiers)
+	return !f.taken[spec] && f.active[spec] == nil
+}
+
+func TestManager_Rebind(t *testing.T) {
+	be := newFakeBackend()
+	m := newManager(testModifiers, be)
+
+	pressed := 0
+	if err := m.Bind(ActionToggleWindow, "ctrl+alt+p", func() { pressed++ }); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if err := m.Bind(ActionToggleWindow, "ctrl+alt+o", func() { pressed++ }); err != nil {
+		t.Fatalf("rebind failed: %v", err)
+	}
+
+	if be.active["ctrl+alt+p"] != nil {
+		t.Error("old combination should be released")
+	}
+	be.active["ctrl+alt+o"]()
+	if pressed != 1 {
+		t.Errorf("expected handler to run once, ran %d times", pressed)
+	}
+	if got := m.Binding(ActionToggleWindow); got != "ctrl+alt+o" {
+		t.Errorf("Binding = %q", got)
+	}
+
+	if err := m.Bind(ActionToggleWindow, "", nil); err != nil {
+		t.Fatalf("unbind failed: %v", err)
+	}
+	if len(be.active) != 0 || m.Binding(ActionToggleWindow) != "" {
+		t.Error("empty spec should unbind the action")
+	}
+}
+
+func TestManager_ConflictWithOtherApplication(t *testing.T) {
+	be := newFakeBackend("ctrl+alt+v", "ctrl+alt+shift+v")
+	m := newManager(testModifiers, be)
+
+	if err := m.Bind(ActionPastePrevious, "ctrl+alt+x", func() {}); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	err := m.Bind(ActionPastePrevious, "ctrl+alt+v", func() {})
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if conflict.Owner != "" {
+		t.Errorf("expected no Pastee owner, got %q", conflict.Owner)
+	}
+	if conflict.Suggestion != "ctrl+alt+super+v" {
+		t.Errorf("Suggestion = %q, want a free combination", conflict.Suggestion)
+	}
+	if got := m.Binding(ActionPastePrevious); got != "ctrl+alt+x" {
+		t.Errorf("previous binding should stay active, got %q", got)
+	}
+}
+
+func TestManager_ConflictWithOtherAction(t *testing.T) {
+	m := newManager(testModifiers, newFakeBackend())
+
+	if err := m.Bind(ActionToggleWindow, "ctrl+alt+p", func() {}); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	err := m.Bind(ActionPauseCapture, "control+option+P", func() {})
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if conflict.Owner != ActionToggleWindow || conflict.Suggestion != "ctrl+alt+shift+p" {
+		t.Errorf("unexpected conflict: %+v", conflict)
+	}
+	if conflict.Error() != "ctrl+alt+p is already used for toggle window; try ctrl+alt+shift+p instead" {
+		t.Errorf("unexpected message: %s", conflict.Error())
+	}
+}
@@ -0,0 +1,30 @@
+package hotkeys
+
+// Key is a platform key code: an X keysym on Linux, otherwise a golang.design/x/hotkey key
+type Key uint32
+
+// ModMask is a platform modifier mask, as in golang.design/x/hotkey
+type ModMask uint32
+
+// keyNames maps the key part of a hotkey spec to a key code
+func keyNames() map[string]Key {
+	keys := platformKeys()
+	keys["return"] = keys["enter"]
+	keys["esc"] = keys["escape"]
+	return keys
+}
+
+func isFunctionKey(name string) bool {
+	return len(name) > 1 && name[0] == 'f'
+}
+
+// canonicalKeyName folds aliases so equal bindings format the same way
+func canonicalKeyName(name string) string {
+	switch name {
+	case "return":
+		return "enter"
+	case "esc":
+		return "escape"
+	}
+	return name
+}
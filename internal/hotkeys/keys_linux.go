@@ -0,0 +1,31 @@
+//go:build linux
+
+package hotkeys
+
+import "fmt"
+
+// platformKeys returns X11 keysyms. The hotkey package is not used on Linux:
+// it aborts at init without a display, and some of its key constants are wrong.
+func platformKeys() map[string]Key {
+	keys := map[string]Key{
+		"space":  0x0020,
+		"enter":  0xff0d,
+		"escape": 0xff1b,
+		"delete": 0xffff,
+		"tab":    0xff09,
+		"left":   0xff51,
+		"up":     0xff52,
+		"right":  0xff53,
+		"down":   0xff54,
+	}
+	for r := 'a'; r <= 'z'; r++ {
+		keys[string(r)] = Key(r) // Latin-1 keysyms equal their character codes
+	}
+	for r := '0'; r <= '9'; r++ {
+		keys[string(r)] = Key(r)
+	}
+	for i := 0; i < 12; i++ {
+		keys[fmt.Sprintf("f%d", i+1)] = Key(0xffbe + i)
+	}
+	return keys
+}
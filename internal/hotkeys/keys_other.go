@@ -0,0 +1,47 @@
+//go:build !linux
+
+package hotkeys
+
+import (
+	"fmt"
+
+	"golang.design/x/hotkey"
+)
+
+func platformKeys() map[string]Key {
+	keys := map[string]Key{
+		"space":  Key(hotkey.KeySpace),
+		"enter":  Key(hotkey.KeyReturn),
+		"escape": Key(hotkey.KeyEscape),
+		"delete": Key(hotkey.KeyDelete),
+		"tab":    Key(hotkey.KeyTab),
+		"left":   Key(hotkey.KeyLeft),
+		"up":     Key(hotkey.KeyUp),
+		"right":  Key(hotkey.KeyRight),
+		"down":   Key(hotkey.KeyDown),
+	}
+	letters := []hotkey.Key{
+		hotkey.KeyA, hotkey.KeyB, hotkey.KeyC, hotkey.KeyD, hotkey.KeyE, hotkey.KeyF, hotkey.KeyG,
+		hotkey.KeyH, hotkey.KeyI, hotkey.KeyJ, hotkey.KeyK, hotkey.KeyL, hotkey.KeyM, hotkey.KeyN,
+		hotkey.KeyO, hotkey.KeyP, hotkey.KeyQ, hotkey.KeyR, hotkey.KeyS, hotkey.KeyT, hotkey.KeyU,
+		hotkey.KeyV, hotkey.KeyW, hotkey.KeyX, hotkey.KeyY, hotkey.KeyZ,
+	}
+	for i, k := range letters {
+		keys[string(rune('a'+i))] = Key(k)
+	}
+	digits := []hotkey.Key{
+		hotkey.Key0, hotkey.Key1, hotkey.Key2, hotkey.Key3, hotkey.Key4,
+		hotkey.Key5, hotkey.Key6, hotkey.Key7, hotkey.Key8, hotkey.Key9,
+	}
+	for i, k := range digits {
+		keys[string(rune('0'+i))] = Key(k)
+	}
+	functionKeys := []hotkey.Key{
+		hotkey.KeyF1, hotkey.KeyF2, hotkey.KeyF3, hotkey.KeyF4, hotkey.KeyF5, hotkey.KeyF6,
+		hotkey.KeyF7, hotkey.KeyF8, hotkey.KeyF9, hotkey.KeyF10, hotkey.KeyF11, hotkey.KeyF12,
+	}
+	for i, k := range functionKeys {
+		keys[fmt.Sprintf("f%d", i+1)] = Key(k)
+	}
+	return keys
+}
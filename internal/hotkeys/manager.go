@@ -0,0 +1,138 @@
+package hotkeys
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// backend grabs key combinations from the OS
+type backend interface {
+	// register starts calling fn when b is pressed; the returned func releases it.
+	// A combination held by another application yields an error wrapping errTaken.
+	register(b Binding, fn func()) (func(), error)
+	// available reports whether b could be registered right now
+	available(b Binding) bool
+}
+
+type boundKey struct {
+	binding Binding
+	release func()
+}
+
+// Manager owns the global hotkeys of all actions and supports rebinding at runtime
+type Manager struct {
+	modifiers Modifiers
+	backend   backend
+
+	mu    sync.Mutex
+	bound map[string]*boundKey
+}
+
+// NewManager returns a Manager that parses hotkeys with the given platform modifiers
+func NewManager(ms Modifiers) *Manager {
+	return newManager(ms, newBackend())
+}
+
+func newManager(ms Modifiers, be backend) *Manager {
+	return &Manager{modifiers: ms, backend: be, bound: make(map[string]*boundKey)}
+}
+
+// Bind assigns spec to action, replacing its previous hotkey. An empty spec
+// unbinds the action. On failure the previous hotkey stays active and the
+// error is a *ConflictError when the combination is already in use.
+func (m *Manager) Bind(action, spec string, fn func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if strings.TrimSpace(spec) == "" {
+		m.unbindLocked(action)
+		return nil
+	}
+
+	b, err := Parse(spec, m.modifiers)
+	if err != nil {
+		return err
+	}
+
+	old := m.bound[action]
+	if old != nil && old.binding.equal(b) {
+		return nil
+	}
+	for other, bk := range m.bound {
+		if other != action && bk.binding.equal(b) {
+			return &ConflictError{Spec: b.String(m.modifiers), Owner: other, Suggestion: m.suggestLocked(b)}
+		}
+	}
+
+	// Free the old combination first so an action can move to a similar one
+	m.unbindLocked(action)
+
+	release, err := m.backend.register(b, fn)
+	if err != nil {
+		if old != nil {
+			if restored, rerr := m.backend.register(old.binding, fn); rerr == nil {
+				m.bound[action] = &boundKey{binding: old.binding, release: restored}
+			}
+		}
+		if errors.Is(err, errTaken) {
+			return &ConflictError{Spec: b.String(m.modifiers), Suggestion: m.suggestLocked(b)}
+		}
+		return err
+	}
+
+	m.bound[action] = &boundKey{binding: b, release: release}
+	return nil
+}
+
+// Binding returns the canonical spec bound to action, or "" if unbound
+func (m *Manager) Binding(action string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if bk, ok := m.bound[action]; ok {
+		return bk.binding.String(m.modifiers)
+	}
+	return ""
+}
+
+// UnbindAll releases every hotkey
+func (m *Manager) UnbindAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for action := range m.bound {
+		m.unbindLocked(action)
+	}
+}
+
+func (m *Manager) unbindLocked(action string) {
+	if bk, ok := m.bound[action]; ok {
+		bk.release()
+		delete(m.bound, action)
+	}
+}
+
+// suggestLocked finds a free combination by adding one more modifier
+func (m *Manager) suggestLocked(b Binding) string {
+	for i := range m.modifiers {
+		if b.has(i) {
+			continue
+		}
+		candidate := b.with(m.modifiers, i)
+		if m.usedLocked(candidate) || !m.backend.available(candidate) {
+			continue
+		}
+		return candidate.String(m.modifiers)
+	}
+	return ""
+}
+
+func (m *Manager) usedLocked(b Binding) bool {
+	for _, bk := range m.bound {
+		if bk.binding.equal(b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+// Package httpapi serves the history as JSON over HTTP on 127.0.0.1, for tools
+// that cannot reach the local socket. Every request needs the bearer token.
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLimit is the number of items listed when no limit is given
+const defaultLimit = 20
+
+// maxBodySize bounds a request body; history items are at most 50 KB of text
+const maxBodySize = 1 << 20
+
+// keepAliveInterval is how often an idle event stream sends a comment, so
+// proxies and clients notice dead connections
+const keepAliveInterval = 30 * time.Second
+
+// Item is a history item as returned by the API
+type Item struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	Content   string    `json:"content,omitempty"`
+	ImagePath string    `json:"image_path,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Source    string    `json:"source"`
+	Favorite  bool      `json:"favorite"`
+	Sensitive bool      `json:"sensitive"`
+	Registers []string  `json:"registers,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListOptions selects the items returned by Backend.List
+type ListOptions struct {
+	Limit     int // Zero or less means no limit
+	Favorites bool
+	Type      string
+	Query     string // Matches content and link titles
+	Reveal    bool   // Include the content of sensitive items
+}
+
+// NewItem is the body of POST /v1/items
+type NewItem struct {
+	Content   string `json:"content"`
+	Favorite  bool   `json:"favorite"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// Backend does the work behind each endpoint; the running instance provides it
+type Backend interface {
+	List(opts ListOptions) ([]Item, error)
+	Get(id int) (Item, error)
+	Add(item NewItem) (Item, error)
+	Delete(id int) error
+	SetFavorite(id int, favorite bool) error
+}
+
+// notFoundError marks a backend error about a missing item
+type notFoundError struct{ error }
+
+func (e notFoundError) Unwrap() error { return e.error }
+
+// NotFound marks err as being about a missing item, which is answered with 404
+func NotFound(err error) error {
+	return notFoundError{err}
+}
+
+// Server answers API requests and streams new items to event subscribers
+type Server struct {
+	backend Backend
+	token   string
+	mux     *http.ServeMux
+
+	mu          sync.Mutex
+	subscribers map[chan Item]bool
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewServer creates a server that accepts requests carrying token
+func NewServer(backend Backend, token string) *Server {
+	s := &Server{
+		backend:     backend,
+		token:       token,
+		mux:         http.NewServeMux(),
+		subscribers: make(map[chan Item]bool),
+		done:        make(chan struct{}),
+	}
+	s.mux.HandleFunc("GET /v1/items", s.handleList)
+	s.mux.HandleFunc("GET /v1/search", s.handleSearch)
+	s.mux.HandleFunc("GET /v1/items/{id}", s.handleGet)
+	s.mux.HandleFunc("POST /v1/items", s.handleAdd)
+	s.mux.HandleFunc("DELETE /v1/items/{id}", s.handleDelete)
+	s.mux.HandleFunc("PUT /v1/items/{id}/favorite", s.handleFavorite(true))
+	s.mux.HandleFunc("DELETE /v1/items/{id}/favorite", s.handleFavorite(false))
+	s.mux.HandleFunc("GET /v1/events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP checks the token and dispatches to the endpoint. Browsers may
+// call the API from any origin, since nothing is reachable without the token.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pastee"`)
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized accepts the token as a bearer token, or for the event stream as
+// a token query parameter, since browsers cannot set headers on EventSource
+func (s *Server) authorized(r *http.Request) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.URL.Path == "/v1/events" {
+		given = r.URL.Query().Get("token")
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1
+}
+
+// ItemAdded sends item to every event subscriber. Subscribers that are not
+// keeping up miss it rather than holding up the caller.
+func (s *Server) ItemAdded(item Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- item:
+		default:
+		}
+	}
+}
+
+// Close ends open event streams, so http.Server.Shutdown does not wait on them
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.list(w, opts)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if opts.Query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing q parameter"))
+		return
+	}
+	s.list(w, opts)
+}
+
+func (s *Server) list(w http.ResponseWriter, opts ListOptions) {
+	items, err := s.backend.List(opts)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	if items == nil {
+		items = []Item{}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// listOptions reads limit, q, type, favorites and reveal from the query string
+func listOptions(r *http.Request) (ListOptions, error) {
+	query := r.URL.Query()
+	opts := ListOptions{Limit: defaultLimit, Query: query.Get("q"), Type: query.Get("type")}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+		opts.Limit = n
+	}
+	for name, flag := range map[string]*bool{"favorites": &opts.Favorites, "reveal": &opts.Reveal} {
+		if v := query.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*flag = b
+		}
+	}
+	return opts, nil
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := itemID(w, r)
+	if !ok {
+		return
+	}
+	item, err := s.backend.Get(id)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req NewItem
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("content is empty"))
+		return
+	}
+	item, err := s.backend.Add(req)
+	if err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, item)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := itemID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.backend.Delete(id); err != nil {
+		writeBackendError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleFavorite(favorite bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := itemID(w, r)
+		if !ok {
+			return
+		}
+		if err := s.backend.SetFavorite(id, favorite); err != nil {
+			writeBackendError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleEvents streams an item_added event for every new item until the
+// client disconnects or the server is closed
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	ch := make(chan Item, 16)
+	s.mu.Lock()
+	s.subscribers[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case item := <-ch:
+			data, err := json.Marshal(item)
+			if err != nil {
+				slog.Error("error encoding event", "err", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: item_added\nid: %d\ndata: %s\n\n", item.ID, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// itemID parses the {id} path segment, answering 400 when it is not a number
+func itemID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid item id %q", r.PathValue("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("error writing response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeBackendError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var nf notFoundError
+	if errors.As(err, &nf) {
+		status = http.StatusNotFound
+	}
+	writeError(w, status, err)
+}
@@ -0,0 +1,340 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "secret-token"
+
+// fakeBackend keeps items in memory, newest first
+type fakeBackend struct {
+	items  []Item
+	nextID int
+}
+
+func (f *fakeBackend) List(opts ListOptions) ([]Item, error) {
+	var out []Item
+	for _, item := range f.items {
+		if (opts.Favorites && !item.Favorite) || (opts.Type != "" && item.Type != opts.Type) ||
+			(opts.Query != "" && !strings.Contains(item.Content, opts.Query)) {
+			continue
+		}
+		if opts.Limit > 0 && len(out) == opts.Limit {
+			break
+		}
+		if item.Sensitive && !opts.Reveal {
+			item.Content = ""
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func (f *fakeBackend) find(id int) (int, error) {
+	for i, item := range f.items {
+		if item.ID == id {
+			return i, nil
+		}
+	}
+	return 0, NotFound(fmt.Errorf("no item with id %d", id))
+}
+
+func (f *fakeBackend) Get(id int) (Item, error) {
+	i, err := f.find(id)
+	if err != nil {
+		return Item{}, err
+	}
+	return f.items[i], nil
+}
+
+func (f *fakeBackend) Add(req NewItem) (Item, error) {
+	f.nextID++
+	item := Item{ID: f.nextID, Type: "text", Content: req.Content, Source: "cli", Favorite: req.Favorite, Sensitive: req.Sensitive}
+	f.items = append([]Item{item}, f.items...)
+	return item, nil
+}
+
+func (f *fakeBackend) Delete(id int) error {
+	i, err := f.find(id)
+	if err != nil {
+		return err
+	}
+	f.items = append(f.items[:i], f.items[i+1:]...)
+	return nil
+}
+
+func (f *fakeBackend) SetFavorite(id int, favorite bool) error {
+	i, err := f.find(id)
+	if err != nil {
+		return err
+	}
+	f.items[i].Favorite = favorite
+	return nil
+}
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server, *fakeBackend) {
+	t.Helper()
+	backend := &fakeBackend{}
+	for _, content := range []string{"first note", "https://example.com", "password123"} {
+		backend.Add(NewItem{Content: content})
+	}
+	backend.items[1].Type = "link"
+	backend.items[0].Sensitive = true
+
+	s := NewServer(backend, testToken)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		s.Close()
+		ts.Close()
+	})
+	return s, ts, backend
+}
+
+// do sends an authenticated request and decodes a JSON response into out, if given
+func do(t *testing.T, ts *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuthentication(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+
+	for name, header := range map[string]string{
+		"missing": "",
+		"wrong":   "Bearer not-the-token",
+		"scheme":  "Basic " + testToken,
+	} {
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/items", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s token: expected 401, got %d", name, resp.StatusCode)
+		}
+	}
+
+	// The token query parameter is only accepted for the event stream
+	resp, err := http.Get(ts.URL + "/v1/items?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token parameter on /v1/items: expected 401, got %d", resp.StatusCode)
+	}
+
+	// CORS preflight requests carry no credentials
+	req, _ := http.NewRequest("OPTIONS", ts.URL+"/v1/items", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("preflight: got %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestListAndSearch(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+
+	var items []Item
+	if status := do(t, ts, "GET", "/v1/items", "", &items); status != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d", status)
+	}
+	if len(items) != 3 || items[0].Content != "" || items[2].Content != "first note" {
+		t.Errorf("list should return all items with sensitive content hidden, got %+v", items)
+	}
+
+	do(t, ts, "GET", "/v1/items?limit=1&reveal=true", "", &items)
+	if len(items) != 1 || items[0].Content != "password123" {
+		t.Errorf("limit and reveal: got %+v", items)
+	}
+
+	do(t, ts, "GET", "/v1/items?type=link", "", &items)
+	if len(items) != 1 || items[0].Type != "link" {
+		t.Errorf("type filter: got %+v", items)
+	}
+
+	do(t, ts, "GET", "/v1/search?q=note", "", &items)
+	if len(items) != 1 || items[0].Content != "first note" {
+		t.Errorf("search: got %+v", items)
+	}
+
+	do(t, ts, "GET", "/v1/search?q=nothing-matches", "", &items)
+	if items == nil || len(items) != 0 {
+		t.Errorf("search without matches should return an empty array, got %v", items)
+	}
+
+	var errBody map[string]string
+	if status := do(t, ts, "GET", "/v1/search", "", &errBody); status != http.StatusBadRequest || errBody["error"] == "" {
+		t.Errorf("search without q: got %d %v", status, errBody)
+	}
+	if status := do(t, ts, "GET", "/v1/items?limit=many", "", nil); status != http.StatusBadRequest {
+		t.Errorf("invalid limit: expected 400, got %d", status)
+	}
+}
+
+func TestItemEndpoints(t *testing.T) {
+	_, ts, backend := newTestServer(t)
+
+	var item Item
+	if status := do(t, ts, "POST", "/v1/items", `{"content": "added over http", "favorite": true}`, &item); status != http.StatusCreated {
+		t.Fatalf("add: expected 201, got %d", status)
+	}
+	if item.ID != 4 || item.Content != "added over http" || !item.Favorite {
+		t.Errorf("add returned %+v", item)
+	}
+	if status := do(t, ts, "POST", "/v1/items", `{"content": "  "}`, nil); status != http.StatusBadRequest {
+		t.Errorf("add with empty content: expected 400, got %d", status)
+	}
+	if status := do(t, ts, "POST", "/v1/items", `not json`, nil); status != http.StatusBadRequest {
+		t.Errorf("add with invalid body: expected 400, got %d", status)
+	}
+
+	if status := do(t, ts, "GET", "/v1/items/3", "", &item); status != http.StatusOK || item.Content != "password123" {
+		t.Errorf("get should include sensitive content, got %d %+v", status, item)
+	}
+	var errBody map[string]string
+	if status := do(t, ts, "GET", "/v1/items/99", "", &errBody); status != http.StatusNotFound || errBody["error"] != "no item with id 99" {
+		t.Errorf("get missing item: got %d %v", status, errBody)
+	}
+	if status := do(t, ts, "GET", "/v1/items/abc", "", nil); status != http.StatusBadRequest {
+		t.Errorf("get with invalid id: expected 400, got %d", status)
+	}
+
+	if status := do(t, ts, "PUT", "/v1/items/1/favorite", "", nil); status != http.StatusNoContent {
+		t.Errorf("favorite: expected 204, got %d", status)
+	}
+	if item, _ := backend.Get(1); !item.Favorite {
+		t.Error("PUT favorite should mark the item")
+	}
+	if status := do(t, ts, "DELETE", "/v1/items/1/favorite", "", nil); status != http.StatusNoContent {
+		t.Errorf("unfavorite: expected 204, got %d", status)
+	}
+	if item, _ := backend.Get(1); item.Favorite {
+		t.Error("DELETE favorite should unmark the item")
+	}
+
+	if status := do(t, ts, "DELETE", "/v1/items/1", "", nil); status != http.StatusNoContent {
+		t.Errorf("delete: expected 204, got %d", status)
+	}
+	if status := do(t, ts, "DELETE", "/v1/items/1", "", nil); status != http.StatusNotFound {
+		t.Errorf("deleting twice: expected 404, got %d", status)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	s, ts, _ := newTestServer(t)
+
+	// Browsers' EventSource cannot send headers, so the token may be a query parameter
+	resp, err := http.Get(ts.URL + "/v1/events?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("events: got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("expected the connected comment, got %q", line)
+	}
+	reader.ReadString('\n')
+
+	s.ItemAdded(Item{ID: 42, Type: "text", Content: "fresh", Source: "clipboard"})
+
+	lines := make(chan string)
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+	var event []string
+	for len(event) < 3 {
+		select {
+		case line := <-lines:
+			event = append(event, strings.TrimSuffix(line, "\n"))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the event, got %q", event)
+		}
+	}
+	if event[0] != "event: item_added" || event[1] != "id: 42" {
+		t.Errorf("unexpected event header %q", event)
+	}
+	var item Item
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(event[2], "data: ")), &item); err != nil || item.Content != "fresh" {
+		t.Errorf("unexpected event data %q (%v)", event[2], err)
+	}
+
+	// Closing the server ends the stream
+	s.Close()
+	select {
+	case _, open := <-lines:
+		for open {
+			_, open = <-lines
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the event stream should end when the server is closed")
+	}
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), TokenFileName)
+
+	token, err := LoadOrCreateToken(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateToken failed: %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("expected a 64 character token, got %q", token)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm != 0600 {
+		t.Errorf("token file should be private, got %v", perm)
+	}
+
+	again, err := LoadOrCreateToken(path)
+	if err != nil || again != token {
+		t.Errorf("the stored token should be reused, got %q (%v)", again, err)
+	}
+}
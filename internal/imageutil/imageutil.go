@@ -1,9 +1,11 @@
 package imageutil
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"image"
+	"image/color"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
@@ -181,3 +183,28 @@ func DeleteImage(imagePath, previewPath string) error {
 
 	return nil
 }
+
+// GrayscalePNG returns a desaturated copy of PNG data, preserving transparency.
+// It is used to derive the "paused" tray icon from the regular one.
+func GrayscalePNG(data []byte) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode png: %w", err)
+	}
+
+	bounds := img.Bounds()
+	gray := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			lum := uint8((299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000)
+			gray.SetNRGBA(x, y, color.NRGBA{R: lum, G: lum, B: lum, A: c.A / 2})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
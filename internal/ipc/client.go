@@ -0,0 +1,99 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotRunning is returned by Dial when no instance is listening
+	ErrNotRunning = errors.New("pastee is not running")
+	// ErrAlreadyRunning is returned by Listen when another instance is listening
+	ErrAlreadyRunning = errors.New("pastee is already running")
+)
+
+// dialTimeout bounds connecting to an instance that may be hung
+const dialTimeout = 2 * time.Second
+
+// Client calls methods on a running instance over one connection
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID uint64
+	server string
+}
+
+// Dial connects to the instance listening at addr and performs the version handshake
+func Dial(addr string) (*Client, error) {
+	conn, err := dial(addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	c := &Client{conn: conn, reader: bufio.NewReader(conn)}
+
+	var hello Hello
+	if err := c.Call(MethodHello, Hello{Version: ProtocolVersion}, &hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	c.server = hello.Server
+	return c, nil
+}
+
+// Server returns the name the instance gave in the handshake
+func (c *Client) Server() string {
+	return c.server
+}
+
+// Call invokes method with params and decodes the result into result, which may be nil
+func (c *Client) Call(method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := Request{JSONRPC: "2.0", ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = data
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("connection closed: %w", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("response for request %d, expected %d", resp.ID, req.ID)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
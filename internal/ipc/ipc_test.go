@@ -0,0 +1,161 @@
+//go:build !windows
+
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func startServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	// Unix socket paths are length-limited, so avoid the long t.TempDir() names
+	dir, err := os.MkdirTemp("", "pastee-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	addr := filepath.Join(dir, "test.sock")
+
+	ln, err := Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := NewServer("test")
+	s.Handle("echo", func(params json.RawMessage) (any, error) {
+		var p struct{ Text string }
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.Text == "" {
+			return nil, errors.New("empty text")
+		}
+		return p, nil
+	})
+	go s.Serve(ln)
+	t.Cleanup(func() { s.Close() })
+	return s, addr
+}
+
+func TestClientServer(t *testing.T) {
+	_, addr := startServer(t)
+
+	c, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+	if c.Server() != "test" {
+		t.Errorf("Server() = %q", c.Server())
+	}
+
+	var out struct{ Text string }
+	if err := c.Call("echo", map[string]string{"text": "hi"}, &out); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if out.Text != "hi" {
+		t.Errorf("echo returned %q", out.Text)
+	}
+
+	var rpcErr *Error
+	if err := c.Call("echo", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInternalError {
+		t.Errorf("expected internal error, got %v", err)
+	}
+	if err := c.Call("echo", "not an object", nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("expected invalid params, got %v", err)
+	}
+	if err := c.Call("missing", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	_, addr := startServer(t)
+
+	exchange := func(conn net.Conn, r *bufio.Reader, req string) Response {
+		t.Helper()
+		fmt.Fprintln(conn, req)
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("no response to %s: %v", req, err)
+		}
+		var resp Response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	conn, err := dial(addr, dialTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	resp := exchange(conn, r, `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"x"}}`)
+	if resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+		t.Errorf("calls before the handshake should be refused, got %+v", resp)
+	}
+
+	resp = exchange(conn, r, fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"hello","params":{"version":%d}}`, ProtocolVersion+1))
+	if resp.Error == nil || resp.Error.Code != CodeVersionMismatch {
+		t.Fatalf("expected version mismatch, got %+v", resp)
+	}
+	if !strings.Contains(resp.Error.Message, "version") {
+		t.Errorf("unhelpful mismatch message %q", resp.Error.Message)
+	}
+	// The server hangs up after a mismatch
+	if _, err := r.ReadBytes('\n'); err == nil {
+		t.Error("expected connection to be closed after a version mismatch")
+	}
+}
+
+func TestListen_SocketPermissionsAndSingleInstance(t *testing.T) {
+	_, addr := startServer(t)
+
+	info, err := os.Stat(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+
+	if _, err := Listen(addr); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("expected ErrAlreadyRunning for a live socket, got %v", err)
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pastee-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "stale.sock")
+
+	// A listener whose file survives it, as after a crash
+	ln, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	if _, err := Dial(addr); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning for a stale socket, got %v", err)
+	}
+	ln, err = Listen(addr)
+	if err != nil {
+		t.Fatalf("stale socket was not replaced: %v", err)
+	}
+	ln.Close()
+}
@@ -0,0 +1,55 @@
+package ipc
+
+import "encoding/json"
+
+// ProtocolVersion is bumped whenever a method changes incompatibly. Client and
+// server must agree on it in the handshake before any other call.
+const ProtocolVersion = 1
+
+// MethodHello is the handshake every connection starts with
+const MethodHello = "hello"
+
+// JSON-RPC 2.0 error codes, plus one of our own for a failed handshake
+const (
+	CodeParseError      = -32700
+	CodeInvalidRequest  = -32600
+	CodeMethodNotFound  = -32601
+	CodeInvalidParams   = -32602
+	CodeInternalError   = -32603
+	CodeVersionMismatch = -32000
+)
+
+// Request is a JSON-RPC 2.0 request; messages are separated by newlines
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response carries either a result or an error for the request with the same ID
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the message alone, so a failure reads the same whether a
+// command ran locally or in the running instance
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Hello is exchanged in the handshake: the client sends its version and the
+// server answers with its own
+type Hello struct {
+	Version int    `json:"version"`
+	Server  string `json:"server,omitempty"` // What is serving, e.g. "gui" or "daemon"
+}
@@ -0,0 +1,176 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// maxMessageSize bounds one request line; history items are at most 50 KB of text
+const maxMessageSize = 4 << 20
+
+// HandlerFunc answers one method call. Returning an *Error controls the code
+// sent to the client; any other error is reported as an internal error.
+type HandlerFunc func(params json.RawMessage) (any, error)
+
+// Server dispatches JSON-RPC calls from local clients to registered handlers
+type Server struct {
+	name     string
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	listener net.Listener
+	conns    map[net.Conn]bool
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a server that introduces itself as name in the handshake
+func NewServer(name string) *Server {
+	return &Server{
+		name:     name,
+		handlers: make(map[string]HandlerFunc),
+		conns:    make(map[net.Conn]bool),
+	}
+}
+
+// Handle registers the handler for a method
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	s.handlers[method] = h
+	s.mu.Unlock()
+}
+
+// Serve accepts connections on ln until Close is called
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops accepting connections, closes open ones and waits for their
+// handlers to return
+func (s *Server) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(conn)
+
+	greeted := false
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			enc.Encode(Response{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: "invalid JSON"}})
+			return
+		}
+
+		resp := Response{JSONRPC: "2.0", ID: req.ID}
+		switch {
+		case req.JSONRPC != "2.0" || req.Method == "":
+			resp.Error = &Error{Code: CodeInvalidRequest, Message: "invalid request"}
+		case !greeted && req.Method != MethodHello:
+			resp.Error = &Error{Code: CodeInvalidRequest, Message: "handshake required before " + req.Method}
+		case req.Method == MethodHello:
+			var hello Hello
+			if err := json.Unmarshal(req.Params, &hello); err != nil || hello.Version != ProtocolVersion {
+				resp.Error = &Error{
+					Code:    CodeVersionMismatch,
+					Message: fmt.Sprintf("protocol version %d is not supported; this instance speaks version %d", hello.Version, ProtocolVersion),
+				}
+				enc.Encode(resp)
+				return
+			}
+			greeted = true
+			resp.Result, _ = json.Marshal(Hello{Version: ProtocolVersion, Server: s.name})
+		default:
+			resp.Result, resp.Error = s.call(req)
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// call runs the handler for req and encodes its outcome
+func (s *Server) call(req Request) (json.RawMessage, *Error) {
+	s.mu.Lock()
+	h, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: "unknown method " + req.Method}
+	}
+
+	result, err := h(req.Params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		slog.Error("Failed to encode result", "method", req.Method, "err", err)
+		return nil, &Error{Code: CodeInternalError, Message: "failed to encode result"}
+	}
+	return data, nil
+}
+
+// InvalidParams wraps a parameter decoding problem for the client
+func InvalidParams(err error) error {
+	return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+}
+
+// DecodeParams unmarshals params into v, reporting failures as invalid params.
+// Missing params leave v unchanged.
+func DecodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return InvalidParams(err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build !windows
+
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DefaultAddress is the socket of the instance for the current user. It lives
+// in XDG_RUNTIME_DIR when set, otherwise in the data directory.
+func DefaultAddress(dataDir string) string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "pastee.sock")
+	}
+	return filepath.Join(dataDir, "pastee.sock")
+}
+
+// Listen creates the socket at addr, readable and writable only by the current
+// user. A socket left behind by a crashed instance is replaced; one that still
+// answers yields ErrAlreadyRunning.
+func Listen(addr string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(addr), 0700); err != nil {
+		return nil, err
+	}
+
+	// The socket must never be reachable by other users, not even briefly
+	oldMask := syscall.Umask(0077)
+	defer syscall.Umask(oldMask)
+
+	ln, err := net.Listen("unix", addr)
+	if err != nil && errors.Is(err, syscall.EADDRINUSE) {
+		if conn, dialErr := dial(addr, dialTimeout); dialErr == nil {
+			conn.Close()
+			return nil, ErrAlreadyRunning
+		}
+		if err := os.Remove(addr); err != nil {
+			return nil, err
+		}
+		ln, err = net.Listen("unix", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(addr, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+func dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", addr, timeout)
+}
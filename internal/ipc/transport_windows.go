@@ -0,0 +1,45 @@
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// DefaultAddress is the named pipe of the instance for the current user
+func DefaultAddress(string) string {
+	name := "pastee"
+	if u, err := user.Current(); err == nil {
+		name += "-" + strings.ReplaceAll(u.Username, `\`, "-")
+	}
+	return `\\.\pipe\` + name
+}
+
+// Listen creates the named pipe at addr, accessible only by the current user.
+// If another instance already owns it, ErrAlreadyRunning is returned.
+func Listen(addr string) (net.Listener, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	// Protected DACL granting full access to the current user's SID only
+	cfg := &winio.PipeConfig{SecurityDescriptor: fmt.Sprintf("D:P(A;;GA;;;%s)", u.Uid)}
+
+	ln, err := winio.ListenPipe(addr, cfg)
+	if err != nil {
+		if conn, dialErr := dial(addr, dialTimeout); dialErr == nil {
+			conn.Close()
+			return nil, ErrAlreadyRunning
+		}
+		return nil, err
+	}
+	return ln, nil
+}
+
+func dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return winio.DialPipe(addr, &timeout)
+}
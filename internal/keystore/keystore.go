@@ -5,8 +5,17 @@ import "errors"
 const (
 	KeychainService = "com.pastee.clipboard"
 	KeychainAccount = "database-encryption-key"
+
+	// SyncPassphraseAccount holds the passphrase of the shared sync folder
+	SyncPassphraseAccount = "sync-passphrase"
 )
 
+// descriptions name each secret in the system's password manager
+var descriptions = map[string]string{
+	KeychainAccount:       "Pastee Clipboard Encryption Key",
+	SyncPassphraseAccount: "Pastee Sync Passphrase",
+}
+
 var (
 	ErrKeyNotFound    = errors.New("encryption key not found in keychain")
 	ErrKeyStoreFailed = errors.New("failed to store key in keychain")
@@ -20,5 +29,10 @@ type KeyStore interface {
 }
 
 func NewKeyStore() KeyStore {
-	return newPlatformKeyStore()
+	return newPlatformKeyStore(KeychainAccount)
+}
+
+// NewSyncPassphraseStore returns the store holding the sync passphrase
+func NewSyncPassphraseStore() KeyStore {
+	return newPlatformKeyStore(SyncPassphraseAccount)
 }
@@ -6,17 +6,19 @@ import (
 	"github.com/keybase/go-keychain"
 )
 
-type darwinKeyStore struct{}
+type darwinKeyStore struct {
+	account string
+}
 
-func newPlatformKeyStore() KeyStore {
-	return &darwinKeyStore{}
+func newPlatformKeyStore(account string) KeyStore {
+	return &darwinKeyStore{account: account}
 }
 
 func (k *darwinKeyStore) Get() ([]byte, error) {
 	query := keychain.NewItem()
 	query.SetSecClass(keychain.SecClassGenericPassword)
 	query.SetService(KeychainService)
-	query.SetAccount(KeychainAccount)
+	query.SetAccount(k.account)
 	query.SetMatchLimit(keychain.MatchLimitOne)
 	query.SetReturnData(true)
 
@@ -41,8 +43,8 @@ func (k *darwinKeyStore) Set(key []byte) error {
 	item := keychain.NewItem()
 	item.SetSecClass(keychain.SecClassGenericPassword)
 	item.SetService(KeychainService)
-	item.SetAccount(KeychainAccount)
-	item.SetLabel("Pastee Clipboard Encryption Key")
+	item.SetAccount(k.account)
+	item.SetLabel(descriptions[k.account])
 	item.SetData(key)
 	item.SetSynchronizable(keychain.SynchronizableNo)
 	item.SetAccessible(keychain.AccessibleWhenUnlocked)
@@ -59,7 +61,7 @@ func (k *darwinKeyStore) Delete() error {
 	item := keychain.NewItem()
 	item.SetSecClass(keychain.SecClassGenericPassword)
 	item.SetService(KeychainService)
-	item.SetAccount(KeychainAccount)
+	item.SetAccount(k.account)
 
 	return keychain.DeleteItem(item)
 }
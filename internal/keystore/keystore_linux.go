@@ -6,14 +6,16 @@ import (
 	"github.com/zalando/go-keyring"
 )
 
-type linuxKeyStore struct{}
+type linuxKeyStore struct {
+	account string
+}
 
-func newPlatformKeyStore() KeyStore {
-	return &linuxKeyStore{}
+func newPlatformKeyStore(account string) KeyStore {
+	return &linuxKeyStore{account: account}
 }
 
 func (k *linuxKeyStore) Get() ([]byte, error) {
-	secret, err := keyring.Get(KeychainService, KeychainAccount)
+	secret, err := keyring.Get(KeychainService, k.account)
 	if err == keyring.ErrNotFound {
 		return nil, ErrKeyNotFound
 	}
@@ -24,7 +26,7 @@ func (k *linuxKeyStore) Get() ([]byte, error) {
 }
 
 func (k *linuxKeyStore) Set(key []byte) error {
-	err := keyring.Set(KeychainService, KeychainAccount, string(key))
+	err := keyring.Set(KeychainService, k.account, string(key))
 	if err != nil {
 		return ErrKeyStoreFailed
 	}
@@ -32,11 +34,11 @@ func (k *linuxKeyStore) Set(key []byte) error {
 }
 
 func (k *linuxKeyStore) Delete() error {
-	return keyring.Delete(KeychainService, KeychainAccount)
+	return keyring.Delete(KeychainService, k.account)
 }
 
 func (k *linuxKeyStore) Exists() (bool, error) {
-	_, err := keyring.Get(KeychainService, KeychainAccount)
+	_, err := keyring.Get(KeychainService, k.account)
 	if err == keyring.ErrNotFound {
 		return false, nil
 	}
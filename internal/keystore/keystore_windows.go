@@ -6,14 +6,23 @@ import (
 	"github.com/danieljoos/wincred"
 )
 
-type windowsKeyStore struct{}
+// windowsKeyStore keeps each secret in its own generic credential. The
+// database key keeps the bare service name it has always been stored under.
+type windowsKeyStore struct {
+	account string
+	target  string
+}
 
-func newPlatformKeyStore() KeyStore {
-	return &windowsKeyStore{}
+func newPlatformKeyStore(account string) KeyStore {
+	target := KeychainService
+	if account != KeychainAccount {
+		target += "/" + account
+	}
+	return &windowsKeyStore{account: account, target: target}
 }
 
 func (k *windowsKeyStore) Get() ([]byte, error) {
-	cred, err := wincred.GetGenericCredential(KeychainService)
+	cred, err := wincred.GetGenericCredential(k.target)
 	if err != nil {
 		return nil, ErrKeyNotFound
 	}
@@ -21,10 +30,10 @@ func (k *windowsKeyStore) Get() ([]byte, error) {
 }
 
 func (k *windowsKeyStore) Set(key []byte) error {
-	cred := wincred.NewGenericCredential(KeychainService)
-	cred.UserName = KeychainAccount
+	cred := wincred.NewGenericCredential(k.target)
+	cred.UserName = k.account
 	cred.CredentialBlob = key
-	cred.Comment = "Pastee Clipboard Database Encryption Key"
+	cred.Comment = descriptions[k.account]
 
 	err := cred.Write()
 	if err != nil {
@@ -34,7 +43,7 @@ func (k *windowsKeyStore) Set(key []byte) error {
 }
 
 func (k *windowsKeyStore) Delete() error {
-	cred, err := wincred.GetGenericCredential(KeychainService)
+	cred, err := wincred.GetGenericCredential(k.target)
 	if err != nil {
 		return nil
 	}
@@ -42,7 +51,7 @@ func (k *windowsKeyStore) Delete() error {
 }
 
 func (k *windowsKeyStore) Exists() (bool, error) {
-	_, err := wincred.GetGenericCredential(KeychainService)
+	_, err := wincred.GetGenericCredential(k.target)
 	if err != nil {
 		return false, nil
 	}
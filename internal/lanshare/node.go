@@ -0,0 +1,372 @@
+// Package lanshare pushes new clipboard items between paired devices on the
+// local network. Devices talk TLS 1.3 with self-signed certificates and trust
+// each other only by the certificate fingerprints exchanged when pairing.
+package lanshare
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMessageSize bounds one message, which may carry an image
+	maxMessageSize = 64 << 20
+	// maxPairingMessageSize bounds a message from an unpaired device, which may only pair
+	maxPairingMessageSize = 4 << 10
+	// ioTimeout bounds a whole exchange with a peer
+	ioTimeout = 30 * time.Second
+	// loopWindow is how long received content is kept from being pushed back out
+	loopWindow = 2 * time.Minute
+)
+
+// Peer is a paired device and what is shared with it
+type Peer struct {
+	Name           string   `json:"name"`
+	Fingerprint    string   `json:"fingerprint"` // Of the device's certificate
+	Address        string   `json:"address"`     // host:port the device listens on
+	Send           bool     `json:"send"`        // Push new items to the device
+	Receive        bool     `json:"receive"`     // Accept items pushed by the device
+	BlockTypes     []string `json:"block_types,omitempty"`
+	ShareSensitive bool     `json:"share_sensitive"`
+}
+
+// allows reports whether item may be sent to or accepted from p
+func (p Peer) allows(item Item) bool {
+	return !slices.Contains(p.BlockTypes, item.Type) && (!item.Sensitive || p.ShareSensitive)
+}
+
+// Item is a clipboard item as pushed between devices
+type Item struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	Image     []byte `json:"image,omitempty"` // The picture of image items
+	Sensitive bool   `json:"sensitive,omitempty"`
+}
+
+func (i Item) key() [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(i.Type + "\x00" + i.Content + "\x00"))
+	h.Write(i.Image)
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// Options configures a Node
+type Options struct {
+	Name     string          // Shown to the devices this one pairs with
+	Identity tls.Certificate // From LoadOrCreateIdentity
+	Port     int             // Where this device listens, told to devices while pairing
+
+	Peers   func() []Peer              // The paired devices
+	AddPeer func(p Peer) error         // Saves a newly paired device, replacing one with the same fingerprint
+	OnItem  func(from Peer, item Item) // Handles an item pushed by a peer
+}
+
+// Node serves pushes and pairing requests from other devices and pushes items to them
+type Node struct {
+	opts        Options
+	fingerprint string
+
+	mu       sync.Mutex
+	pairing  *pairing
+	received map[[sha256.Size]byte]time.Time // Content received from peers, to prevent loops
+	closed   bool
+	lns      []net.Listener
+}
+
+type pairing struct {
+	secret  []byte
+	expires time.Time
+}
+
+// message is the one request a connection carries
+type message struct {
+	Type   string `json:"type"` // "pair" or "item"
+	Name   string `json:"name"`
+	Port   int    `json:"port,omitempty"`   // For pairing
+	Secret []byte `json:"secret,omitempty"` // For pairing
+	Item   *Item  `json:"item,omitempty"`
+}
+
+type reply struct {
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewNode creates a node for the device with opts.Identity
+func NewNode(opts Options) (*Node, error) {
+	if len(opts.Identity.Certificate) == 0 {
+		return nil, errors.New("no identity certificate")
+	}
+	return &Node{
+		opts:        opts,
+		fingerprint: Fingerprint(opts.Identity.Certificate[0]),
+		received:    make(map[[sha256.Size]byte]time.Time),
+	}, nil
+}
+
+// Fingerprint returns this device's certificate fingerprint
+func (n *Node) Fingerprint() string {
+	return n.fingerprint
+}
+
+// Serve answers connections on ln until Close is called
+func (n *Node) Serve(ln net.Listener) error {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return net.ErrClosed
+	}
+	n.lns = append(n.lns, ln)
+	n.mu.Unlock()
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{n.opts.Identity},
+		MinVersion:   tls.VersionTLS13,
+		// Any certificate completes the handshake; handle decides what its owner may do
+		ClientAuth: tls.RequireAnyClientCert,
+	}
+	tlsLn := tls.NewListener(ln, config)
+	for {
+		conn, err := tlsLn.Accept()
+		if err != nil {
+			n.mu.Lock()
+			closed := n.closed
+			n.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go n.handle(conn.(*tls.Conn))
+	}
+}
+
+// Close stops serving
+func (n *Node) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closed = true
+	for _, ln := range n.lns {
+		ln.Close()
+	}
+	return nil
+}
+
+func (n *Node) handle(conn *tls.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	fingerprint := Fingerprint(conn.ConnectionState().PeerCertificates[0].Raw)
+
+	limit, err := n.messageLimit(fingerprint)
+	if err != nil {
+		slog.Warn("LAN share: rejected connection", "from", conn.RemoteAddr(), "err", err)
+		json.NewEncoder(conn).Encode(reply{Error: err.Error()})
+		return
+	}
+	var msg message
+	if err := json.NewDecoder(io.LimitReader(conn, limit)).Decode(&msg); err != nil {
+		return
+	}
+
+	var resp reply
+	switch msg.Type {
+	case "pair":
+		err = n.acceptPairing(msg, fingerprint, conn.RemoteAddr())
+		resp.Name = n.opts.Name
+	case "item":
+		err = n.acceptItem(msg, fingerprint)
+	default:
+		err = fmt.Errorf("unknown message type %q", msg.Type)
+	}
+	if err != nil {
+		slog.Warn("LAN share: rejected message", "type", msg.Type, "from", conn.RemoteAddr(), "err", err)
+		resp.Error = err.Error()
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// messageLimit returns how much of a message from the device with
+// fingerprint is read before it is decoded. Unpaired devices are turned away
+// unless a pairing code is valid, and may then only send a pairing request.
+func (n *Node) messageLimit(fingerprint string) (int64, error) {
+	if _, ok := n.peer(fingerprint); ok {
+		return maxMessageSize, nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pairing == nil || !time.Now().Before(n.pairing.expires) {
+		return 0, errors.New("not paired")
+	}
+	return maxPairingMessageSize, nil
+}
+
+func (n *Node) acceptItem(msg message, fingerprint string) error {
+	peer, ok := n.peer(fingerprint)
+	if !ok {
+		return errors.New("not paired")
+	}
+	if !peer.Receive {
+		return errors.New("not accepting items from this device")
+	}
+	if msg.Item == nil {
+		return errors.New("no item")
+	}
+	if !peer.allows(*msg.Item) {
+		return fmt.Errorf("%s items are not accepted from this device", describe(*msg.Item))
+	}
+
+	n.mu.Lock()
+	n.received[msg.Item.key()] = time.Now()
+	n.mu.Unlock()
+	if n.opts.OnItem != nil {
+		n.opts.OnItem(peer, *msg.Item)
+	}
+	return nil
+}
+
+func describe(item Item) string {
+	if item.Sensitive {
+		return "sensitive"
+	}
+	return item.Type
+}
+
+func (n *Node) peer(fingerprint string) (Peer, bool) {
+	if n.opts.Peers == nil {
+		return Peer{}, false
+	}
+	for _, p := range n.opts.Peers() {
+		if p.Fingerprint == fingerprint {
+			return p, true
+		}
+	}
+	return Peer{}, false
+}
+
+// Push sends item to every paired device it may go to, and returns the errors
+// of the devices that could not be reached. Items just received from a peer
+// are not pushed again, so devices never send an item back and forth.
+func (n *Node) Push(item Item) []error {
+	n.mu.Lock()
+	for key, at := range n.received {
+		if time.Since(at) > loopWindow {
+			delete(n.received, key)
+		}
+	}
+	_, echo := n.received[item.key()]
+	n.mu.Unlock()
+	if echo || n.opts.Peers == nil {
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, p := range n.opts.Peers() {
+		if !p.Send || !p.allows(item) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := n.send(p.Address, func(fp string) bool { return fp == p.Fingerprint },
+				message{Type: "item", Name: n.opts.Name, Item: &item})
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// send delivers msg to the device at addr whose certificate fingerprint
+// passes trusted, and returns its reply
+func (n *Node) send(addr string, trusted func(fingerprint string) bool, msg message) (reply, error) {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{n.opts.Identity},
+		MinVersion:   tls.VersionTLS13,
+		// The certificate is self-signed; it is checked against the pinned fingerprint instead
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			if len(raw) == 0 || !trusted(Fingerprint(raw[0])) {
+				return errors.New("device certificate does not match the paired one")
+			}
+			return nil
+		},
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 5 * time.Second}, Config: config}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return reply{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	if err := json.NewEncoder(conn).Encode(msg); err != nil {
+		return reply{}, err
+	}
+	var resp reply
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		return reply{}, fmt.Errorf("no reply: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// acceptPairing completes a pairing started with StartPairing when msg
+// carries its secret, trusting the certificate the device connected with
+func (n *Node) acceptPairing(msg message, fingerprint string, remote net.Addr) error {
+	n.mu.Lock()
+	session := n.pairing
+	valid := session != nil && time.Now().Before(session.expires) &&
+		subtle.ConstantTimeCompare(session.secret, msg.Secret) == 1
+	if valid {
+		n.pairing = nil // A code pairs one device
+	}
+	n.mu.Unlock()
+	if !valid {
+		return errors.New("invalid or expired pairing code")
+	}
+
+	host, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		return err
+	}
+	peer := newPeer(msg.Name, fingerprint, net.JoinHostPort(host, strconv.Itoa(msg.Port)))
+	if err := n.opts.AddPeer(peer); err != nil {
+		return err
+	}
+	slog.Info("LAN share: paired", "peer", peer.Name, "address", peer.Address)
+	return nil
+}
+
+// newPeer shares everything but sensitive items in both directions
+func newPeer(name, fingerprint, address string) Peer {
+	if name == "" {
+		name = address
+	}
+	return Peer{Name: name, Fingerprint: fingerprint, Address: address, Send: true, Receive: true}
+}
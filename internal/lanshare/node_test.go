@@ -0,0 +1,304 @@
+package lanshare
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testDevice is one side of a pairing, with its peers and received items in memory
+type testDevice struct {
+	node *Node
+
+	mu       sync.Mutex
+	peers    []Peer
+	received []Item
+}
+
+func newTestDevice(t *testing.T, name string) *testDevice {
+	t.Helper()
+	identity, err := LoadOrCreateIdentity(filepath.Join(t.TempDir(), IdentityFileName))
+	if err != nil {
+		t.Fatalf("LoadOrCreateIdentity failed: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &testDevice{}
+	d.node, err = NewNode(Options{
+		Name:     name,
+		Identity: identity,
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+		Peers: func() []Peer {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			return append([]Peer(nil), d.peers...)
+		},
+		AddPeer: func(p Peer) error {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.peers = append(d.peers, p)
+			return nil
+		},
+		OnItem: func(from Peer, item Item) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.received = append(d.received, item)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.node.Serve(ln)
+	t.Cleanup(func() { d.node.Close() })
+	return d
+}
+
+func (d *testDevice) items() []Item {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Item(nil), d.received...)
+}
+
+func (d *testDevice) editPeers(fn func(p *Peer)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.peers {
+		fn(&d.peers[i])
+	}
+}
+
+// pair pairs b with a through a pairing code shown by a
+func pair(t *testing.T, a, b *testDevice) {
+	t.Helper()
+	code, err := a.node.StartPairing("127.0.0.1")
+	if err != nil {
+		t.Fatalf("StartPairing failed: %v", err)
+	}
+	peer, err := b.node.Pair(code)
+	if err != nil {
+		t.Fatalf("Pair failed: %v", err)
+	}
+	if peer.Fingerprint != a.node.Fingerprint() || peer.Name != a.node.opts.Name {
+		t.Fatalf("b saved the wrong peer: %+v", peer)
+	}
+}
+
+func TestPairAndPush(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	pair(t, a, b)
+
+	if len(a.peers) != 1 || a.peers[0].Fingerprint != b.node.Fingerprint() || a.peers[0].Name != "b" {
+		t.Fatalf("a saved the wrong peer: %+v", a.peers)
+	}
+	if a.peers[0].Address != net.JoinHostPort("127.0.0.1", portString(b)) {
+		t.Errorf("a should reach b at the port b listens on, got %s", a.peers[0].Address)
+	}
+
+	if errs := a.node.Push(Item{Type: "text", Content: "from a"}); errs != nil {
+		t.Fatalf("push to b failed: %v", errs)
+	}
+	if errs := b.node.Push(Item{Type: "link", Content: "https://example.com"}); errs != nil {
+		t.Fatalf("push to a failed: %v", errs)
+	}
+	if got := b.items(); len(got) != 1 || got[0].Content != "from a" {
+		t.Errorf("b received %+v", got)
+	}
+	if got := a.items(); len(got) != 1 || got[0].Type != "link" {
+		t.Errorf("a received %+v", got)
+	}
+}
+
+func portString(d *testDevice) string {
+	return strconv.Itoa(d.node.opts.Port)
+}
+
+func TestPairingCodeIsSingleUse(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	c := newTestDevice(t, "c")
+
+	code, _ := a.node.StartPairing("127.0.0.1")
+	if _, err := b.node.Pair(code); err != nil {
+		t.Fatalf("Pair failed: %v", err)
+	}
+	if _, err := c.node.Pair(code); err == nil {
+		t.Error("a used pairing code should be rejected")
+	}
+
+	// A code with another secret is rejected too
+	code, _ = a.node.StartPairing("127.0.0.1")
+	addr, encoded, _ := strings.Cut(code, "/")
+	last := encoded[len(encoded)-1]
+	flipped := byte('A')
+	if last == 'A' {
+		flipped = 'B'
+	}
+	if _, err := c.node.Pair(addr + "/" + encoded[:len(encoded)-1] + string(flipped)); err == nil {
+		t.Error("a wrong secret should be rejected")
+	}
+	if len(a.peers) != 1 || len(c.peers) != 0 {
+		t.Errorf("only b should be paired, a has %+v and c has %+v", a.peers, c.peers)
+	}
+}
+
+func TestPairingChecksFingerprint(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	impostor := newTestDevice(t, "impostor")
+
+	// A code from a pointing at another device's address must not pair with it
+	code, _ := a.node.StartPairing("127.0.0.1")
+	_, encoded, _ := strings.Cut(code, "/")
+	wrongAddr := net.JoinHostPort("127.0.0.1", portString(impostor))
+	if _, err := b.node.Pair(wrongAddr + "/" + encoded); err == nil {
+		t.Error("pairing with a device whose certificate does not match the code should fail")
+	}
+	if len(b.peers) != 0 || len(impostor.peers) != 0 {
+		t.Errorf("nothing should be paired, b has %+v and the impostor has %+v", b.peers, impostor.peers)
+	}
+}
+
+func TestUnpairedAndImpersonatingDevicesAreRejected(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	stranger := newTestDevice(t, "stranger")
+	pair(t, a, b)
+
+	// The stranger knows a's address and fingerprint but has no certificate a trusts
+	stranger.peers = []Peer{{Name: "a", Fingerprint: a.node.Fingerprint(), Address: b.peers[0].Address, Send: true}}
+	if errs := stranger.node.Push(Item{Type: "text", Content: "spoofed"}); len(errs) != 1 {
+		t.Errorf("push from an unpaired device should fail, got %v", errs)
+	}
+	if len(a.items()) != 0 {
+		t.Errorf("a accepted items from an unpaired device: %+v", a.items())
+	}
+
+	// b, pointed at the stranger as if it was a, refuses to talk to it
+	b.editPeers(func(p *Peer) { p.Address = net.JoinHostPort("127.0.0.1", portString(stranger)) })
+	if errs := b.node.Push(Item{Type: "text", Content: "secret"}); len(errs) != 1 {
+		t.Errorf("push to a device with another certificate should fail, got %v", errs)
+	}
+	if len(stranger.items()) != 0 {
+		t.Errorf("the stranger received %+v", stranger.items())
+	}
+}
+
+func TestMessageLimit(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	stranger := newTestDevice(t, "stranger")
+
+	if _, err := a.node.messageLimit(stranger.node.Fingerprint()); err == nil {
+		t.Error("an unpaired device should be turned away while no pairing code is valid")
+	}
+	if _, err := a.node.StartPairing("127.0.0.1"); err != nil {
+		t.Fatalf("StartPairing failed: %v", err)
+	}
+	if limit, err := a.node.messageLimit(stranger.node.Fingerprint()); err != nil || limit != maxPairingMessageSize {
+		t.Errorf("messageLimit while pairing = %d, %v, want %d", limit, err, maxPairingMessageSize)
+	}
+
+	pair(t, a, b)
+	if limit, err := a.node.messageLimit(b.node.Fingerprint()); err != nil || limit != maxMessageSize {
+		t.Errorf("messageLimit for a paired device = %d, %v, want %d", limit, err, maxMessageSize)
+	}
+	if _, err := a.node.messageLimit(stranger.node.Fingerprint()); err == nil {
+		t.Error("the pairing code was used, so the stranger should be turned away again")
+	}
+}
+
+func TestFiltersAndAllowlists(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	pair(t, a, b)
+
+	// Sensitive items are kept back by default
+	a.node.Push(Item{Type: "text", Content: "hunter2", Sensitive: true})
+	if len(b.items()) != 0 {
+		t.Errorf("sensitive item was sent by default: %+v", b.items())
+	}
+
+	// Sending side type filter
+	a.editPeers(func(p *Peer) { p.BlockTypes = []string{"image"} })
+	a.node.Push(Item{Type: "image", Image: []byte("png")})
+	if len(b.items()) != 0 {
+		t.Errorf("blocked type was sent: %+v", b.items())
+	}
+
+	// Receiving side filters are enforced even if the sender allows the item
+	a.editPeers(func(p *Peer) { p.BlockTypes, p.ShareSensitive = nil, true })
+	b.editPeers(func(p *Peer) { p.BlockTypes = []string{"image"} })
+	if errs := a.node.Push(Item{Type: "image", Image: []byte("png")}); len(errs) != 1 {
+		t.Errorf("b should refuse the image, got %v", errs)
+	}
+	if errs := a.node.Push(Item{Type: "text", Content: "hunter2", Sensitive: true}); len(errs) != 1 {
+		t.Errorf("b should refuse the sensitive item, got %v", errs)
+	}
+
+	// Allowlists: b stops accepting from a, a stops sending to b
+	b.editPeers(func(p *Peer) { p.BlockTypes, p.Receive = nil, false })
+	if errs := a.node.Push(Item{Type: "text", Content: "refused"}); len(errs) != 1 {
+		t.Errorf("b should refuse items from a, got %v", errs)
+	}
+	b.editPeers(func(p *Peer) { p.Receive = true })
+	a.editPeers(func(p *Peer) { p.Send = false })
+	a.node.Push(Item{Type: "text", Content: "not sent"})
+	if len(b.items()) != 0 {
+		t.Errorf("b received %+v", b.items())
+	}
+
+	a.editPeers(func(p *Peer) { p.Send = true })
+	a.node.Push(Item{Type: "image", Image: []byte("png")})
+	if got := b.items(); len(got) != 1 || string(got[0].Image) != "png" {
+		t.Errorf("b should receive the image, got %+v", got)
+	}
+}
+
+func TestReceivedItemsAreNotPushedBack(t *testing.T) {
+	a := newTestDevice(t, "a")
+	b := newTestDevice(t, "b")
+	c := newTestDevice(t, "c")
+	pair(t, a, b)
+	pair(t, c, b)
+
+	item := Item{Type: "text", Content: "ping"}
+	a.node.Push(item)
+
+	// b captures what it received, like the app does, and pushes it on
+	for _, received := range b.items() {
+		if errs := b.node.Push(received); errs != nil {
+			t.Fatal(errs)
+		}
+	}
+	if len(a.items()) != 0 || len(c.items()) != 0 {
+		t.Errorf("b pushed a received item again: a got %+v, c got %+v", a.items(), c.items())
+	}
+
+	// Something b copies itself still goes out
+	b.node.Push(Item{Type: "text", Content: "pong"})
+	if len(a.items()) != 1 || len(c.items()) != 1 {
+		t.Errorf("b's own item should reach a and c: a got %+v, c got %+v", a.items(), c.items())
+	}
+}
+
+func TestLoadOrCreateIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), IdentityFileName)
+	first, err := LoadOrCreateIdentity(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := LoadOrCreateIdentity(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(first.Certificate[0]) != Fingerprint(again.Certificate[0]) {
+		t.Error("the stored identity should be reused")
+	}
+}
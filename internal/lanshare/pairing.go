@@ -0,0 +1,88 @@
+package lanshare
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// A pairing code is "host:port/" followed by the first fingerprintBytes of the
+// device's certificate fingerprint and a one-time secret, base32 encoded in
+// groups of four. The fingerprint lets the joining device check it reached the
+// right device; the secret, sent over the resulting TLS connection, proves to
+// that device that the joining one was shown the code.
+const (
+	fingerprintBytes = 16
+	secretBytes      = 10
+)
+
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// PairingTimeout is how long a pairing code stays valid
+const PairingTimeout = 5 * time.Minute
+
+// StartPairing returns a code that lets one device pair with this one within
+// PairingTimeout. host is the address the other device reaches this one at.
+// A new code replaces any earlier one.
+func (n *Node) StartPairing(host string) (string, error) {
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	fingerprint, err := hex.DecodeString(n.fingerprint)
+	if err != nil {
+		return "", err
+	}
+
+	n.mu.Lock()
+	n.pairing = &pairing{secret: secret, expires: time.Now().Add(PairingTimeout)}
+	n.mu.Unlock()
+
+	encoded := codeEncoding.EncodeToString(append(fingerprint[:fingerprintBytes], secret...))
+	var groups []string
+	for len(encoded) > 4 {
+		groups = append(groups, encoded[:4])
+		encoded = encoded[4:]
+	}
+	groups = append(groups, encoded)
+	return fmt.Sprintf("%s/%s", net.JoinHostPort(host, fmt.Sprint(n.opts.Port)), strings.Join(groups, "-")), nil
+}
+
+// Pair pairs with the device that showed code, saving it with AddPeer on both sides
+func (n *Node) Pair(code string) (Peer, error) {
+	addr, encoded, ok := strings.Cut(strings.TrimSpace(code), "/")
+	if !ok {
+		return Peer{}, errors.New("invalid pairing code: expected host:port/code")
+	}
+	raw, err := codeEncoding.DecodeString(strings.ToUpper(strings.ReplaceAll(encoded, "-", "")))
+	if err != nil || len(raw) != fingerprintBytes+secretBytes {
+		return Peer{}, errors.New("invalid pairing code")
+	}
+	prefix, secret := raw[:fingerprintBytes], raw[fingerprintBytes:]
+
+	var fingerprint string
+	trusted := func(fp string) bool {
+		full, err := hex.DecodeString(fp)
+		if err != nil || !bytes.Equal(full[:fingerprintBytes], prefix) {
+			return false
+		}
+		fingerprint = fp
+		return true
+	}
+	resp, err := n.send(addr, trusted, message{Type: "pair", Name: n.opts.Name, Port: n.opts.Port, Secret: secret})
+	if err != nil {
+		return Peer{}, fmt.Errorf("pairing failed: %w", err)
+	}
+
+	peer := newPeer(resp.Name, fingerprint, addr)
+	if err := n.opts.AddPeer(peer); err != nil {
+		return Peer{}, err
+	}
+	return peer, nil
+}
@@ -0,0 +1,136 @@
+package linkmeta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+const (
+	maxConcurrentFetches = 2
+	retryFailedAfter     = time.Hour // Failed lookups, e.g. while offline, are retried after this
+)
+
+var (
+	fetcherMu sync.Mutex
+	fetcher   *Fetcher
+
+	inFlightMu sync.Mutex
+	inFlight   = make(map[string]bool)
+
+	slots   = make(chan struct{}, maxConcurrentFetches)
+	pending sync.WaitGroup
+)
+
+// SetFetcher replaces the fetcher used for background lookups.
+// Passing nil restores the default built from the config.
+func SetFetcher(f *Fetcher) {
+	fetcherMu.Lock()
+	defer fetcherMu.Unlock()
+	fetcher = f
+}
+
+func currentFetcher() *Fetcher {
+	fetcherMu.Lock()
+	defer fetcherMu.Unlock()
+	if fetcher == nil {
+		cfg := config.Get().LinkTitles
+		fetcher = NewFetcher(nil, time.Duration(cfg.TimeoutMs)*time.Millisecond, cfg.MaxBytes)
+	}
+	return fetcher
+}
+
+// ShouldFetch reports whether metadata may be fetched for item
+func ShouldFetch(item models.ClipboardItem) bool {
+	return config.Get().LinkTitles.Enabled && item.Type == "link" && !item.IsSensitive
+}
+
+// FetchInBackground looks up the title of a link item unless fetching is
+// disabled, the item is sensitive, or the result is already cached.
+// onFetched runs on the fetching goroutine after a title has been stored.
+func FetchInBackground(item models.ClipboardItem, onFetched func(models.LinkMetadata)) {
+	if !ShouldFetch(item) {
+		return
+	}
+
+	inFlightMu.Lock()
+	if inFlight[item.Content] {
+		inFlightMu.Unlock()
+		return
+	}
+	inFlight[item.Content] = true
+	inFlightMu.Unlock()
+
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		defer func() {
+			inFlightMu.Lock()
+			delete(inFlight, item.Content)
+			inFlightMu.Unlock()
+		}()
+
+		slots <- struct{}{}
+		defer func() { <-slots }()
+
+		meta, err := fetchUncached(item.Content)
+		if err != nil {
+			slog.Warn("Failed to fetch link metadata", "id", item.ID, "err", withoutURL(err))
+			return
+		}
+		if meta.Title != "" && onFetched != nil {
+			onFetched(meta)
+		}
+	}()
+}
+
+// withoutURL drops the link that HTTP client errors repeat, so it stays out of
+// the log like other clipboard content
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// Wait blocks until fetches already started have finished, so the database
+// can be closed without cutting them off
+func Wait() {
+	pending.Wait()
+}
+
+// fetchUncached fetches and stores metadata for link, returning an empty
+// result without fetching when a recent entry is cached
+func fetchUncached(link string) (models.LinkMetadata, error) {
+	cached, err := database.GetLinkMetadata(link)
+	switch {
+	case err == nil:
+		if cached.Error == "" || time.Since(cached.FetchedAt) < retryFailedAfter {
+			return models.LinkMetadata{}, nil
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return models.LinkMetadata{}, err
+	}
+
+	f := currentFetcher()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*DefaultTimeout)
+	defer cancel()
+
+	meta, fetchErr := f.Fetch(ctx, link)
+	if fetchErr != nil {
+		meta = models.LinkMetadata{URL: link, FetchedAt: time.Now(), Error: fetchErr.Error()}
+	}
+	if err := database.SaveLinkMetadata(meta); err != nil {
+		return models.LinkMetadata{}, err
+	}
+	return meta, fetchErr
+}
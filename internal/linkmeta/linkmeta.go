@@ -0,0 +1,200 @@
+package linkmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultTimeout  = 5 * time.Second
+	DefaultMaxBytes = 512 * 1024 // Only the head of a page is needed
+	maxRedirects    = 5
+	maxTitleLength  = 300
+	userAgent       = "Pastee-Clipboard/1.0 (link preview)"
+)
+
+var errNotHTML = errors.New("response is not an HTML page")
+
+// Fetcher retrieves page titles and canonical URLs
+type Fetcher struct {
+	Client   *http.Client
+	MaxBytes int64 // Bytes of the response body read before giving up on finding a title
+}
+
+// NewFetcher returns a Fetcher using client, or a client with strict
+// timeouts derived from timeout when client is nil
+func NewFetcher(client *http.Client, timeout time.Duration, maxBytes int64) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if client == nil {
+		client = newClient(timeout)
+	}
+	return &Fetcher{Client: client, MaxBytes: maxBytes}
+}
+
+func newClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                  http.ProxyFromEnvironment,
+			DialContext:            (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSHandshakeTimeout:    timeout,
+			ResponseHeaderTimeout:  timeout,
+			MaxResponseHeaderBytes: 64 * 1024,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// Fetch downloads the start of the page at link and extracts its metadata
+func (f *Fetcher) Fetch(ctx context.Context, link string) (models.LinkMetadata, error) {
+	target, err := normalizeURL(link)
+	if err != nil {
+		return models.LinkMetadata{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return models.LinkMetadata{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return models.LinkMetadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return models.LinkMetadata{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+			return models.LinkMetadata{}, errNotHTML
+		}
+	}
+
+	title, canonical := parseHead(io.LimitReader(resp.Body, f.MaxBytes))
+	if canonical != "" {
+		if ref, err := url.Parse(canonical); err == nil {
+			canonical = resp.Request.URL.ResolveReference(ref).String()
+		} else {
+			canonical = ""
+		}
+	}
+
+	return models.LinkMetadata{
+		URL:          link,
+		Title:        title,
+		CanonicalURL: canonical,
+		FetchedAt:    time.Now(),
+	}, nil
+}
+
+// errNotHTTP leaves the link out, as links may carry tokens
+var errNotHTTP = errors.New("not an http(s) link")
+
+// normalizeURL accepts the same http(s) and bare "www." links the link detector does
+func normalizeURL(link string) (string, error) {
+	trimmed := strings.TrimSpace(link)
+	if strings.HasPrefix(strings.ToLower(trimmed), "www.") {
+		trimmed = "https://" + trimmed
+	}
+	u, err := url.Parse(trimmed)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errNotHTTP
+	}
+	return u.String(), nil
+}
+
+// parseHead scans the document head for <title>, falling back to og:title,
+// and for <link rel="canonical">. It stops at <body> or end of input.
+func parseHead(r io.Reader) (title, canonical string) {
+	var ogTitle string
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return finishTitle(title, ogTitle), canonical
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return finishTitle(title, ogTitle), canonical
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+			switch string(name) {
+			case "title":
+				inTitle = title == ""
+			case "meta":
+				if attrs["property"] == "og:title" && ogTitle == "" {
+					ogTitle = attrs["content"]
+				}
+			case "link":
+				if hasToken(attrs["rel"], "canonical") && canonical == "" {
+					canonical = strings.TrimSpace(attrs["href"])
+				}
+			case "body":
+				return finishTitle(title, ogTitle), canonical
+			}
+		}
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(strings.ToLower(list)) {
+		if f == token {
+			return true
+		}
+	}
+	return false
+}
+
+// finishTitle collapses whitespace and caps the length of the chosen title
+func finishTitle(title, fallback string) string {
+	t := strings.Join(strings.Fields(title), " ")
+	if t == "" {
+		t = strings.Join(strings.Fields(fallback), " ")
+	}
+	if runes := []rune(t); len(runes) > maxTitleLength {
+		t = string(runes[:maxTitleLength]) + "…"
+	}
+	return t
+}
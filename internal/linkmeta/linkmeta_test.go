@@ -0,0 +1,148 @@
+package linkmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+func TestFetch_TitleAndCanonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head>
+			<meta property="og:title" content="OG Title">
+			<title>
+				Example   &amp; Co
+			</title>
+			<link rel="alternate canonical" href="/articles/1">
+		</head><body><title>Not this</title></body></html>`)
+	}))
+	defer server.Close()
+
+	f := NewFetcher(server.Client(), time.Second, 0)
+	meta, err := f.Fetch(context.Background(), server.URL+"/a?x=1")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Title != "Example & Co" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+	if meta.CanonicalURL != server.URL+"/articles/1" {
+		t.Errorf("CanonicalURL = %q", meta.CanonicalURL)
+	}
+	if meta.URL != server.URL+"/a?x=1" {
+		t.Errorf("URL = %q, want the link as given", meta.URL)
+	}
+}
+
+func TestFetch_OpenGraphFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><meta property="og:title" content="Shared Page"></head></html>`)
+	}))
+	defer server.Close()
+
+	meta, err := NewFetcher(server.Client(), time.Second, 0).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Title != "Shared Page" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+}
+
+func TestFetch_SizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>"+strings.Repeat("<meta name=x>", 1000)+"<title>Too late</title></head></html>")
+	}))
+	defer server.Close()
+
+	meta, err := NewFetcher(server.Client(), time.Second, 1024).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Title != "" {
+		t.Errorf("expected no title past the size limit, got %q", meta.Title)
+	}
+}
+
+func TestFetch_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"title":"no"}`)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, "<title>slow</title>")
+		}
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+	f := NewFetcher(client, 0, 0)
+
+	for _, link := range []string{server.URL + "/missing", server.URL + "/json", server.URL + "/slow", "ftp://example.com", "not a link"} {
+		if _, err := f.Fetch(context.Background(), link); err == nil {
+			t.Errorf("Fetch(%q) expected error", link)
+		}
+	}
+}
+
+func TestWithoutURL_KeepsLinkOutOfErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Refuse connections
+
+	f := NewFetcher(nil, time.Second, 0)
+	for _, link := range []string{server.URL + "/reset?token=s3cret", "ftp://example.com/?token=s3cret"} {
+		_, err := f.Fetch(context.Background(), link)
+		if err == nil {
+			t.Fatalf("Fetch(%q) expected error", link)
+		}
+		if msg := withoutURL(err).Error(); strings.Contains(msg, "s3cret") {
+			t.Errorf("error for %q reveals the link: %s", link, msg)
+		}
+	}
+}
+
+func TestShouldFetch_DisabledByDefault(t *testing.T) {
+	item := models.ClipboardItem{Type: "link", Content: "https://example.com"}
+	if ShouldFetch(item) {
+		t.Error("fetching should be disabled by default")
+	}
+}
+
+func TestShouldFetch_SkipsSensitiveItems(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.LinkTitles.Enabled = true
+	if err := config.Save(filepath.Join(dir, config.FileName), cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer config.Load(filepath.Join(dir, "missing.json"))
+
+	tests := []struct {
+		item     models.ClipboardItem
+		expected bool
+	}{
+		{models.ClipboardItem{Type: "link", Content: "https://example.com"}, true},
+		{models.ClipboardItem{Type: "link", Content: "https://example.com", IsSensitive: true}, false},
+		{models.ClipboardItem{Type: "text", Content: "https://example.com"}, false},
+	}
+	for _, tt := range tests {
+		if result := ShouldFetch(tt.item); result != tt.expected {
+			t.Errorf("ShouldFetch(%+v) = %v, want %v", tt.item, result, tt.expected)
+		}
+	}
+}
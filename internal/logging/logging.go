@@ -0,0 +1,91 @@
+// Package logging sets up the shared slog logger. Log records go to stderr
+// and a rotating file in the data directory. Clipboard contents are never
+// written as they are unless the user opts in for debugging; packages log
+// them with Content, which records only their length and a hash.
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+// FileName is the log file inside the data directory
+const FileName = "pastee.log"
+
+var (
+	logContent atomic.Bool
+
+	// hashKey makes content hashes differ between runs, so short secrets such
+	// as PINs cannot be found by hashing guesses
+	hashKey = func() []byte {
+		key := make([]byte, 32)
+		rand.Read(key)
+		return key
+	}()
+)
+
+// Start makes the default slog logger, and with it the log package, write to
+// w and to the rotating file at path, at the level set in the config. Either
+// may be left out with nil or "". Call the returned function on exit.
+func Start(w io.Writer, path string) (func(), error) {
+	cfg := config.Get().Logging
+
+	var level slog.Level
+	levelErr := level.UnmarshalText([]byte(cfg.Level))
+	if levelErr != nil {
+		level = slog.LevelInfo
+	}
+
+	writers := []io.Writer{}
+	if w != nil {
+		writers = append(writers, w)
+	}
+	var file *rotatingFile
+	if path != "" {
+		var err error
+		file, err = openRotating(path, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxFiles)
+		if err != nil {
+			return func() {}, fmt.Errorf("error opening log file: %w", err)
+		}
+		writers = append(writers, file)
+	}
+
+	handler := slog.NewTextHandler(io.MultiWriter(writers...), &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+	logContent.Store(cfg.LogContent)
+
+	if levelErr != nil && cfg.Level != "" {
+		slog.Warn("Unknown log level, using info", "level", cfg.Level)
+	}
+	if cfg.LogContent {
+		slog.Warn("Clipboard contents are written to the log; turn log_content off when done debugging")
+	}
+	return func() {
+		if file != nil {
+			file.Close()
+		}
+	}, nil
+}
+
+// Content returns a log attribute describing clipboard text without revealing
+// it: its length and a short hash, enough to tell items apart within a run.
+// With log_content on, the text itself is logged.
+func Content(text string) slog.Attr {
+	if logContent.Load() {
+		return slog.String("content", text)
+	}
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(text))
+	return slog.Group("content",
+		slog.Int("len", len(text)),
+		slog.String("hash", hex.EncodeToString(mac.Sum(nil)[:6])),
+	)
+}
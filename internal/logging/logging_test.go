@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+// useConfig makes cfg the logging settings and restores the default logger afterwards
+func useConfig(t *testing.T, cfg config.LoggingConfig) {
+	t.Helper()
+	dir := t.TempDir()
+	full := config.Default()
+	full.Logging = cfg
+	if err := config.Save(filepath.Join(dir, config.FileName), full); err != nil {
+		t.Fatal(err)
+	}
+	logger, flags := slog.Default(), log.Flags()
+	t.Cleanup(func() {
+		config.Load(filepath.Join(dir, "missing.json"))
+		slog.SetDefault(logger)
+		log.SetFlags(flags)
+		logContent.Store(false)
+	})
+}
+
+func TestContentIsRedacted(t *testing.T) {
+	useConfig(t, config.LoggingConfig{Level: "info"})
+	var buf bytes.Buffer
+	stop, err := Start(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	slog.Info("Captured", Content("hunter2"))
+	slog.Info("Captured", Content("hunter2"))
+	slog.Debug("Not shown at info level")
+	log.Println("Through the log package")
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "content.len=7") {
+		t.Errorf("content should be logged as its length and hash, got:\n%s", out)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || lines[0][strings.Index(lines[0], "content"):] != lines[1][strings.Index(lines[1], "content"):] {
+		t.Errorf("the same content should hash the same, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Through the log package") {
+		t.Error("the log package should write to the shared logger")
+	}
+}
+
+func TestContentOptIn(t *testing.T) {
+	useConfig(t, config.LoggingConfig{Level: "debug", LogContent: true})
+	var buf bytes.Buffer
+	stop, err := Start(&buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	slog.Debug("Captured", Content("hunter2"))
+	if !strings.Contains(buf.String(), "content=hunter2") {
+		t.Errorf("content should be logged when opted in, got:\n%s", buf.String())
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	f, err := openRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	for name, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		if data, _ := os.ReadFile(name); string(data) != want {
+			t.Errorf("%s holds %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("only max_files rotated files should be kept")
+	}
+}
+
+func TestRotatingFile_KeepsExistingSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	os.WriteFile(path, []byte("12345678"), 0600)
+
+	f, err := openRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("abc"))
+	f.Close()
+	if data, _ := os.ReadFile(path); string(data) != "abc" {
+		t.Errorf("a file already near its limit should be rotated, got %q", data)
+	}
+}
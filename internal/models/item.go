@@ -1,5 +1,15 @@
 package models
 
+import "time"
+
+// Sources a clipboard item can be captured from
+const (
+	SourceClipboard = "clipboard" // The regular system clipboard
+	SourcePrimary   = "primary"   // The X11 PRIMARY (mouse selection) buffer
+	SourceCLI       = "cli"       // Added with the pastee add command
+	SourceLAN       = "lan"       // Pushed by a paired device on the local network
+)
+
 type ClipboardItem struct {
 	ID          int
 	Content     string
@@ -8,4 +18,28 @@ type ClipboardItem struct {
 	PreviewPath string // Full path to the thumbnail preview
 	IsSensitive bool   // Whether content should be hidden by default
 	IsFavorite  bool   // Whether item is marked as favorite
+	Source      string // One of the Source constants above
+
+	OriginalContent string    // Content as captured, when it was cleaned before storing
+	Title           string    // Page title of a link, when one has been fetched
+	Registers       []string  // Names of the registers holding this item
+	Tags            []string  // Added by capture rules
+	ExpiresAt       time.Time // When the item is deleted automatically; zero for never
+	DetectedSecret  string    // Name of the secret detector that flagged the item, if any
+	CreatedAt       time.Time // When the item was captured or last copied
+}
+
+// Register is a named slot (a-z or 1-9) holding a history item
+type Register struct {
+	Name string
+	Item ClipboardItem
+}
+
+// LinkMetadata is the cached result of looking up a link item's page
+type LinkMetadata struct {
+	URL          string // Link as stored in the item's content
+	Title        string
+	CanonicalURL string
+	FetchedAt    time.Time
+	Error        string // Set when the last lookup failed
 }
@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"golang.design/x/clipboard"
+)
+
+// ClearState describes a pending clear of a sensitive item from the clipboard
+type ClearState struct {
+	Pending   bool
+	Remaining time.Duration // Time left until the clipboard is cleared
+}
+
+var (
+	clearMu        sync.Mutex
+	clearSecret    string        // Text to remove from the clipboard
+	clearPrevious  string        // Text to restore in its place, or "" to empty the clipboard
+	clearAt        time.Time     // When the clipboard is cleared
+	clearStop      chan struct{} // Closed when the pending clear is cancelled or done
+	clearListeners []func(ClearState)
+
+	// clearTick is how often listeners are told the time left
+	clearTick = time.Second
+
+	// readClipboardText and writeClipboardText are replaced in tests
+	readClipboardText  = func() string { return string(clipboard.Read(clipboard.FmtText)) }
+	writeClipboardText = func(content string) { clipboard.Write(clipboard.FmtText, []byte(content)) }
+)
+
+// WriteSecretText puts a sensitive text on the clipboard like a self-write.
+// If enabled, the clipboard is cleared again after the configured delay,
+// restoring what it held before, unless something else was copied meanwhile.
+func WriteSecretText(secret string) {
+	cfg := config.Get().ClipboardClear
+	var previous string
+	if cfg.Enabled && cfg.RestorePrevious {
+		previous = readClipboardText()
+	}
+	MarkSelfWrittenText(secret)
+	writeClipboardText(secret)
+	if cfg.Enabled && cfg.AfterSeconds > 0 {
+		scheduleClear(secret, previous, time.Duration(cfg.AfterSeconds)*time.Second)
+	}
+}
+
+// scheduleClear replaces any pending clear with one removing secret after d
+func scheduleClear(secret, previous string, d time.Duration) {
+	clearMu.Lock()
+	if clearStop != nil {
+		// The earlier secret was on the clipboard in between; restore what came before it
+		previous = clearPrevious
+		close(clearStop)
+	}
+	stop := make(chan struct{})
+	clearSecret, clearPrevious, clearAt, clearStop = secret, previous, now().Add(d), stop
+	clearMu.Unlock()
+
+	slog.Info("Clipboard will be cleared", "after", d)
+	notifyClearChange()
+	go runClearCountdown(stop, clearTick)
+}
+
+// runClearCountdown reports the time left every tick and clears the
+// clipboard once it runs out, unless stop is closed first
+func runClearCountdown(stop chan struct{}, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if CurrentClearState().Remaining <= 0 {
+				finishClear(stop)
+				return
+			}
+			notifyClearChange()
+		}
+	}
+}
+
+// ClearNow clears a pending secret from the clipboard without waiting
+func ClearNow() {
+	clearMu.Lock()
+	stop := clearStop
+	clearMu.Unlock()
+	if stop != nil {
+		finishClear(stop)
+	}
+}
+
+// finishClear clears the secret scheduled with stop, if it is still the
+// pending one and still on the clipboard
+func finishClear(stop chan struct{}) {
+	clearMu.Lock()
+	if clearStop != stop {
+		clearMu.Unlock()
+		return
+	}
+	secret, previous := clearSecret, clearPrevious
+	resetClearLocked()
+	clearMu.Unlock()
+
+	if readClipboardText() == secret {
+		if previous != "" {
+			MarkSelfWrittenText(previous)
+			slog.Info("Cleared sensitive item from the clipboard, restoring the previous content")
+		} else {
+			slog.Info("Cleared sensitive item from the clipboard")
+		}
+		writeClipboardText(previous)
+	}
+	notifyClearChange()
+}
+
+// cancelClearUnless drops a pending clear once the clipboard holds something
+// other than the secret, as the user copied something else. Images pass "".
+func cancelClearUnless(content string) {
+	clearMu.Lock()
+	if clearStop == nil || content == clearSecret {
+		clearMu.Unlock()
+		return
+	}
+	resetClearLocked()
+	clearMu.Unlock()
+
+	slog.Info("Something else was copied, the clipboard will not be cleared")
+	notifyClearChange()
+}
+
+// resetClearLocked forgets the pending clear. Callers must hold clearMu.
+func resetClearLocked() {
+	close(clearStop)
+	clearSecret, clearPrevious, clearAt, clearStop = "", "", time.Time{}, nil
+}
+
+// CurrentClearState returns a snapshot of the pending clear
+func CurrentClearState() ClearState {
+	clearMu.Lock()
+	defer clearMu.Unlock()
+	return clearStateLocked()
+}
+
+// OnClearChange registers a callback invoked when a clear is scheduled, done
+// or cancelled, and every second in between. Callbacks run on the goroutine
+// that caused the change.
+func OnClearChange(fn func(ClearState)) {
+	clearMu.Lock()
+	defer clearMu.Unlock()
+	clearListeners = append(clearListeners, fn)
+}
+
+// clearStateLocked builds the current state. Callers must hold clearMu.
+func clearStateLocked() ClearState {
+	if clearStop == nil {
+		return ClearState{}
+	}
+	return ClearState{Pending: true, Remaining: max(clearAt.Sub(now()), 0)}
+}
+
+func notifyClearChange() {
+	clearMu.Lock()
+	state := clearStateLocked()
+	listeners := append([]func(ClearState){}, clearListeners...)
+	clearMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(state)
+	}
+}
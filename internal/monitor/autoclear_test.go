@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+// fakeClipboard stands in for the system clipboard
+type fakeClipboard struct {
+	mu      sync.Mutex
+	content string
+}
+
+func (c *fakeClipboard) read() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.content
+}
+
+func (c *fakeClipboard) write(content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.content = content
+}
+
+func useFakeClipboard(t *testing.T, content string) *fakeClipboard {
+	cb := &fakeClipboard{content: content}
+	read, write := readClipboardText, writeClipboardText
+	readClipboardText, writeClipboardText = cb.read, cb.write
+	clearTick = 5 * time.Millisecond
+	t.Cleanup(func() {
+		clearMu.Lock()
+		if clearStop != nil {
+			resetClearLocked()
+		}
+		clearListeners = nil
+		clearMu.Unlock()
+		readClipboardText, writeClipboardText = read, write
+		clearTick = time.Second
+	})
+	return cb
+}
+
+// waitForClear waits until no clear is pending
+func waitForClear(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for CurrentClearState().Pending {
+		if time.Now().After(deadline) {
+			t.Fatal("the clipboard was not cleared in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduleClear_RestoresPrevious(t *testing.T) {
+	cb := useFakeClipboard(t, "hunter2")
+	var mu sync.Mutex
+	var states []ClearState
+	OnClearChange(func(state ClearState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, state)
+	})
+
+	scheduleClear("hunter2", "earlier text", 30*time.Millisecond)
+	if state := CurrentClearState(); !state.Pending || state.Remaining <= 0 {
+		t.Fatalf("expected a pending clear, got %+v", state)
+	}
+	waitForClear(t)
+
+	if got := cb.read(); got != "earlier text" {
+		t.Errorf("expected the previous content to be restored, got %q", got)
+	}
+	if !consumeSelfWrite(textFingerprint("earlier text")) {
+		t.Error("the restored content should not be captured again")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) < 2 || !states[0].Pending || states[len(states)-1].Pending {
+		t.Errorf("listeners should see the clear scheduled and done, got %+v", states)
+	}
+}
+
+func TestScheduleClear_LeavesOtherContent(t *testing.T) {
+	cb := useFakeClipboard(t, "hunter2")
+
+	scheduleClear("hunter2", "", time.Hour)
+	cb.write("copied elsewhere")
+	cancelClearUnless("copied elsewhere")
+	if CurrentClearState().Pending {
+		t.Fatal("copying something else should cancel the clear")
+	}
+
+	// Even without the monitor noticing, only the secret is cleared
+	scheduleClear("hunter2", "", 10*time.Millisecond)
+	cb.write("copied elsewhere")
+	waitForClear(t)
+	if got := cb.read(); got != "copied elsewhere" {
+		t.Errorf("the clipboard should be left alone, got %q", got)
+	}
+}
+
+func TestScheduleClear_SecondSecretRestoresFirstPrevious(t *testing.T) {
+	cb := useFakeClipboard(t, "token")
+
+	scheduleClear("password", "notes", time.Hour)
+	scheduleClear("token", "password", 10*time.Millisecond)
+	waitForClear(t)
+	if got := cb.read(); got != "notes" {
+		t.Errorf("expected the content from before both secrets, got %q", got)
+	}
+}
+
+func TestClearNow(t *testing.T) {
+	cb := useFakeClipboard(t, "hunter2")
+
+	scheduleClear("hunter2", "", time.Hour)
+	ClearNow()
+	if CurrentClearState().Pending || cb.read() != "" {
+		t.Errorf("expected an empty clipboard at once, got %q", cb.read())
+	}
+}
+
+func TestWriteSecretText_Disabled(t *testing.T) {
+	cb := useFakeClipboard(t, "before")
+	dir := t.TempDir()
+	defer config.Load(filepath.Join(dir, "missing.json"))
+	cfg := config.Default()
+	cfg.ClipboardClear.Enabled = false
+	if err := config.Save(filepath.Join(dir, config.FileName), cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	WriteSecretText("hunter2")
+	if cb.read() != "hunter2" || CurrentClearState().Pending {
+		t.Errorf("the secret should be copied without a clear, got %q, %+v", cb.read(), CurrentClearState())
+	}
+	consumeSelfWrite(textFingerprint("hunter2"))
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+// Detector recognises one kind of clipboard content
+type Detector interface {
+	Name() string // Type name stored with the item, e.g. "link"
+	Icon() fyne.Resource
+	Priority() int // Higher priorities are tried first
+	Detect(content string) bool
+}
+
+// Type names that are not produced by a text detector
+const (
+	TypeText  = "text"
+	TypeImage = "image"
+)
+
+var (
+	urlRegex   = regexp.MustCompile(`^(https?://|www\.)[^\s]+$`)
+	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	phoneRegex = regexp.MustCompile(`^[\d\s\-\+\(\)]{7,20}$`)
+
+	hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	rgbColorRegex = regexp.MustCompile(`^(?i)rgba?\(\s*\d{1,3}%?\s*,\s*\d{1,3}%?\s*,\s*\d{1,3}%?\s*(,\s*(0|1|0?\.\d+|\d{1,3}%)\s*)?\)$`)
+	uuidRegex     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashRegex     = regexp.MustCompile(`^([0-9a-fA-F]{32}|[0-9a-fA-F]{40}|[0-9a-fA-F]{64}|[0-9a-fA-F]{128})$`)
+	pathRegex     = regexp.MustCompile(`^(~?/|\./|\.\./|[a-zA-Z]:\\|\\\\)[^\n<>"|?*]*$`)
+
+	markdownHeadingRegex = regexp.MustCompile(`(?m)^#{1,6} \S`)
+	markdownLinkRegex    = regexp.MustCompile(`\[[^\]]+\]\([^)\s]+\)`)
+	markdownListRegex    = regexp.MustCompile(`(?m)^\s*([-*+]|\d+\.) \S`)
+)
+
+var (
+	detectorsMu sync.RWMutex
+	detectors   = make(map[string]Detector)
+)
+
+// RegisterDetector adds a detector to the registry, replacing any with the same name
+func RegisterDetector(d Detector) {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	detectors[d.Name()] = d
+}
+
+// Detectors returns all registered detectors, highest priority first
+func Detectors() []Detector {
+	detectorsMu.RLock()
+	list := make([]Detector, 0, len(detectors))
+	for _, d := range detectors {
+		list = append(list, d)
+	}
+	detectorsMu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Priority() != list[j].Priority() {
+			return list[i].Priority() > list[j].Priority()
+		}
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// IconForType returns the icon registered for an item type
+func IconForType(itemType string) fyne.Resource {
+	detectorsMu.RLock()
+	d, ok := detectors[itemType]
+	detectorsMu.RUnlock()
+
+	if !ok {
+		return theme.QuestionIcon()
+	}
+	return d.Icon()
+}
+
+// DetectContentType analyzes the content and returns the type of the
+// highest-priority enabled detector that matches, or "text"
+func DetectContentType(content string) string {
+	trimmed := strings.TrimSpace(content)
+	disabled := config.Get().Detectors.Disabled
+
+	for _, d := range Detectors() {
+		if isDisabled(d.Name(), disabled) {
+			continue
+		}
+		if d.Detect(trimmed) {
+			return d.Name()
+		}
+	}
+	return TypeText
+}
+
+func isDisabled(name string, disabled []string) bool {
+	for _, n := range disabled {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// detector is the built-in Detector implementation. A nil match registers
+// an icon for a type without ever producing it from text. Icons are looked up
+// when first needed, since the theme is only available once the app has started.
+type detector struct {
+	name     string
+	icon     func() fyne.Resource
+	priority int
+	match    func(string) bool
+}
+
+func (d *detector) Name() string        { return d.name }
+func (d *detector) Icon() fyne.Resource { return d.icon() }
+func (d *detector) Priority() int       { return d.priority }
+
+func (d *detector) Detect(content string) bool {
+	return d.match != nil && d.match(content)
+}
+
+func init() {
+	for _, d := range []*detector{
+		{name: TypeText, icon: theme.DocumentIcon},
+		{name: TypeImage, icon: theme.MediaPhotoIcon},
+		{name: "uuid", icon: theme.GridIcon, priority: 100, match: uuidRegex.MatchString},
+		{name: "color", icon: theme.ColorPaletteIcon, priority: 95, match: isColor},
+		{name: "ip", icon: theme.StorageIcon, priority: 90, match: isIPAddress},
+		{name: "hash", icon: theme.ConfirmIcon, priority: 85, match: hashRegex.MatchString},
+		{name: "link", icon: theme.ComputerIcon, priority: 80, match: urlRegex.MatchString},
+		{name: "email", icon: theme.MailComposeIcon, priority: 75, match: emailRegex.MatchString},
+		{name: "json", icon: theme.ListIcon, priority: 70, match: isJSON},
+		{name: "phone", icon: theme.AccountIcon, priority: 60, match: isPhone},
+		{name: "path", icon: theme.FolderIcon, priority: 50, match: pathRegex.MatchString},
+		{name: "markdown", icon: theme.DocumentCreateIcon, priority: 30, match: isMarkdown},
+		{name: "code", icon: theme.FileTextIcon, priority: 20, match: isCode},
+	} {
+		RegisterDetector(d)
+	}
+}
+
+func isColor(s string) bool {
+	return hexColorRegex.MatchString(s) || rgbColorRegex.MatchString(s)
+}
+
+// isIPAddress accepts bare IPv4 and IPv6 addresses
+func isIPAddress(s string) bool {
+	return strings.ContainsAny(s, ".:") && net.ParseIP(s) != nil
+}
+
+// isJSON only accepts objects and arrays; bare numbers and strings stay text
+func isJSON(s string) bool {
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
+
+func isPhone(s string) bool {
+	return phoneRegex.MatchString(s) && containsDigits(s, 7)
+}
+
+// isMarkdown needs a heading, fenced block or link, or several list items
+func isMarkdown(s string) bool {
+	if markdownHeadingRegex.MatchString(s) || strings.Contains(s, "```") || markdownLinkRegex.MatchString(s) {
+		return true
+	}
+	return len(markdownListRegex.FindAllString(s, 3)) >= 2
+}
+
+// isCode applies LooksLikeCode but also requires code punctuation or multiple
+// lines, so prose that merely mentions "import " is not classified as code
+func isCode(s string) bool {
+	return LooksLikeCode(s) && (strings.Contains(s, "\n") || strings.ContainsAny(s, "{};="))
+}
+
+// LooksLikeCode reports whether content resembles source code
+func LooksLikeCode(content string) bool {
+	codePatterns := []string{"func ", "class ", "import ", "def ", "var ", "const ", "#!/", "SELECT ", "INSERT ", "UPDATE ", "DELETE FROM"}
+	for _, pattern := range codePatterns {
+		if strings.Contains(content, pattern) {
+			return true
+		}
+	}
+	lines := strings.Split(content, "\n")
+	indentedLines := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+			indentedLines++
+		}
+	}
+	if len(lines) > 2 && indentedLines > len(lines)/2 {
+		return true
+	}
+	if strings.Contains(content, "{") && strings.Contains(content, "}") {
+		return true
+	}
+	return false
+}
+
+// containsDigits checks if the string contains at least n digits
+func containsDigits(s string, n int) bool {
+	count := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			count++
+			if count >= n {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+)
+
+func TestDetectContentType_BuiltinDetectors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#ff8800", "color"},
+		{"#fff", "color"},
+		{"rgb(255, 136, 0)", "color"},
+		{"rgba(0,0,0,0.5)", "color"},
+		{`{"name": "pastee", "tags": [1, 2]}`, "json"},
+		{`[1, 2, 3]`, "json"},
+		{"/usr/local/bin/pastee", "path"},
+		{"~/Documents/notes.txt", "path"},
+		{`C:\Users\pastee\file.txt`, "path"},
+		{"192.168.1.10", "ip"},
+		{"2001:db8::ff00:42:8329", "ip"},
+		{"::1", "ip"},
+		{"123e4567-e89b-12d3-a456-426614174000", "uuid"},
+		{"d41d8cd98f00b204e9800998ecf8427e", "hash"},
+		{"da39a3ee5e6b4b0d3255bfef95601890afd80709", "hash"},
+		{"# Title\n\nSome paragraph", "markdown"},
+		{"See [the docs](https://example.com) for more", "markdown"},
+		{"- first\n- second", "markdown"},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}", "code"},
+		{"SELECT * FROM users;", "code"},
+	}
+
+	for _, tt := range tests {
+		result := DetectContentType(tt.input)
+		if result != tt.expected {
+			t.Errorf("DetectContentType(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestDetectContentType_NotOverEager(t *testing.T) {
+	tests := []string{
+		"42",
+		`"just a string"`,
+		"{not json",
+		"This is an important import of goods",
+		"#hashtag",
+		"999.999.999.999",
+		"- a single bullet",
+	}
+
+	for _, input := range tests {
+		if result := DetectContentType(input); result != "text" {
+			t.Errorf("DetectContentType(%q) = %q, want %q", input, result, "text")
+		}
+	}
+}
+
+func TestDetectContentType_DisabledDetector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.FileName)
+	if err := os.WriteFile(path, []byte(`{"detectors": {"disabled": ["link"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(path); err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+	t.Cleanup(func() { config.Load(filepath.Join(t.TempDir(), config.FileName)) })
+
+	if result := DetectContentType("https://example.com"); result != "text" {
+		t.Errorf("disabled link detector still matched: got %q", result)
+	}
+}
+
+type testDetector struct{}
+
+func (testDetector) Name() string               { return "ticket" }
+func (testDetector) Icon() fyne.Resource        { return theme.InfoIcon() }
+func (testDetector) Priority() int              { return 200 }
+func (testDetector) Detect(content string) bool { return len(content) > 5 && content[:5] == "JIRA-" }
+
+func TestRegisterDetector_CustomDetectorAndIcon(t *testing.T) {
+	RegisterDetector(testDetector{})
+	t.Cleanup(func() {
+		detectorsMu.Lock()
+		delete(detectors, "ticket")
+		detectorsMu.Unlock()
+	})
+
+	if result := DetectContentType("JIRA-1234"); result != "ticket" {
+		t.Errorf("custom detector not used: got %q", result)
+	}
+	if IconForType("ticket") != theme.InfoIcon() {
+		t.Error("IconForType should return the custom detector icon")
+	}
+	if IconForType("unknown-type") != theme.QuestionIcon() {
+		t.Error("unknown types should fall back to the question icon")
+	}
+	if Detectors()[0].Name() != "ticket" {
+		t.Error("Detectors should be sorted by priority")
+	}
+}
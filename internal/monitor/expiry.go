@@ -0,0 +1,30 @@
+package monitor
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+)
+
+// expiryCheckInterval is how often items past their expiry time are deleted
+const expiryCheckInterval = time.Minute
+
+// startExpiryPruner deletes expired items now and every expiryCheckInterval
+// until the monitor stops
+func startExpiryPruner() {
+	monitorWG.Add(1)
+	go func() {
+		defer monitorWG.Done()
+		for {
+			if n, err := database.DeleteExpiredItems(); err != nil {
+				slog.Error("error deleting expired items", "err", err)
+			} else if n > 0 {
+				slog.Info("Deleted expired items", "count", n)
+			}
+			if !sleepUnlessStopped(expiryCheckInterval) {
+				return
+			}
+		}
+	}()
+}
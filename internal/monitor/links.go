@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/urlclean"
+)
+
+// cleanLink applies the built-in URL-cleaning rules plus any configured additions
+func cleanLink(link string) string {
+	cfg := config.Get().LinkCleaning
+	if !cfg.Enabled {
+		return link
+	}
+	return linkRules(cfg).Clean(link)
+}
+
+func linkRules(cfg config.LinkCleaningConfig) urlclean.Rules {
+	rules := urlclean.DefaultRules()
+	rules.StripParams = append(rules.StripParams, cfg.StripParams...)
+	rules.KeepParams = append(rules.KeepParams, cfg.KeepParams...)
+	for _, r := range cfg.Redirectors {
+		rules.Redirectors = append(rules.Redirectors, urlclean.Redirector{Host: r.Host, Path: r.Path, Param: r.Param})
+	}
+	for domain, params := range cfg.DomainParams {
+		rules.DomainParams[domain] = append(rules.DomainParams[domain], params...)
+	}
+	return rules
+}
@@ -2,38 +2,59 @@ package monitor
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"regexp"
-	"strings"
+	"log/slog"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/hooks"
 	"github.com/Sirpyerre/pasteeclipboard/internal/imageutil"
+	"github.com/Sirpyerre/pasteeclipboard/internal/logging"
 	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/rules"
+	"github.com/Sirpyerre/pasteeclipboard/internal/scripting"
 	"golang.design/x/clipboard"
 )
 
+// pollInterval is how often the clipboard is checked for new content
+const pollInterval = 1500 * time.Millisecond
+
+// ErrSkipped is returned when the user script, a capture rule or the secret
+// scanner keeps an item out of the history
+var ErrSkipped = errors.New("skipped by the user script, a capture rule or the secret scanner")
+
 var (
-	urlRegex   = regexp.MustCompile(`^(https?://|www\.)[^\s]+$`)
-	emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	phoneRegex = regexp.MustCompile(`^[\d\s\-\+\(\)]{7,20}$`)
+	stopMonitor = make(chan struct{})
+	stopOnce    sync.Once
+	monitorWG   sync.WaitGroup
 )
 
-func StartClipboardMonitor(onNewItem func(models.ClipboardItem)) {
-	// Initialize clipboard
-	err := clipboard.Init()
-	if err != nil {
-		log.Println("error initializing clipboard:", err)
-		return
+// StartClipboardMonitor polls the clipboard (and PRIMARY, if enabled) and stores
+// new content, calling onNewItem for each capture with whether it was a new
+// item rather than a duplicate moved to the top. It fails if the clipboard
+// cannot be accessed, e.g. without a display.
+func StartClipboardMonitor(onNewItem func(models.ClipboardItem, bool)) error {
+	if err := clipboard.Init(); err != nil {
+		return fmt.Errorf("error initializing clipboard: %w", err)
 	}
 
+	watchScreenLock()
+	startPrimaryMonitor(onNewItem)
+	startExpiryPruner()
+
+	monitorWG.Add(1)
 	go func() {
+		defer monitorWG.Done()
 		for {
-			if ignoreNextRead {
-				ignoreNextRead = false
-				time.Sleep(500 * time.Millisecond)
+			if IsPaused() {
+				skipClipboardWhilePaused()
+				if !sleepUnlessStopped(pollInterval) {
+					return
+				}
 				continue
 			}
 
@@ -41,7 +62,9 @@ func StartClipboardMonitor(onNewItem func(models.ClipboardItem)) {
 			imageData := clipboard.Read(clipboard.FmtImage)
 			if len(imageData) > 0 {
 				handleImageClipboard(imageData, onNewItem)
-				time.Sleep(1500 * time.Millisecond)
+				if !sleepUnlessStopped(pollInterval) {
+					return
+				}
 				continue
 			}
 
@@ -54,182 +77,348 @@ func StartClipboardMonitor(onNewItem func(models.ClipboardItem)) {
 				}
 			}
 
-			time.Sleep(1500 * time.Millisecond)
+			if !sleepUnlessStopped(pollInterval) {
+				return
+			}
 		}
 	}()
+	return nil
+}
+
+// StopClipboardMonitor stops polling and waits for a capture in progress to
+// finish, so the database can be closed safely afterwards
+func StopClipboardMonitor() {
+	stopOnce.Do(func() { close(stopMonitor) })
+	monitorWG.Wait()
+}
+
+// sleepUnlessStopped waits for d and reports false if the monitor was stopped meanwhile
+func sleepUnlessStopped(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-stopMonitor:
+		return false
+	}
 }
 
-func handleTextClipboard(content string, onNewItem func(models.ClipboardItem)) {
+// skipClipboardWhilePaused records the current clipboard as already seen,
+// so anything copied during a pause is not captured once capture resumes
+func skipClipboardWhilePaused() {
+	if imageData := clipboard.Read(clipboard.FmtImage); len(imageData) > 0 {
+		lastImageHash = ImageHash(imageData)
+		return
+	}
+	if textData := clipboard.Read(clipboard.FmtText); len(textData) > 0 {
+		lastContent = string(textData)
+	}
+}
+
+func handleTextClipboard(content string, onNewItem func(models.ClipboardItem, bool)) {
 	lastContent = content
+	cancelClearUnless(content)
+
+	// Content Pastee wrote itself is not a new capture, just a reuse of an existing item
+	if consumeSelfWrite(textFingerprint(content)) {
+		existingItem, err := database.GetItemByContent(content)
+		if errors.Is(err, sql.ErrNoRows) {
+			return // Written without being stored, e.g. "Copy as…"
+		}
+		if err != nil {
+			slog.Error("error getting self-written item", "err", err)
+			return
+		}
+		if err := database.UpdateItemTimestamp(existingItem.ID); err != nil {
+			slog.Error("error updating item timestamp", "err", err)
+		}
+		return
+	}
 
-	// Truncate if content exceeds max length
-	if len(content) > database.MaxTextLength {
-		content = content[:database.MaxTextLength] + "\n... (truncated)"
-		log.Printf("Content truncated to %d bytes\n", database.MaxTextLength)
+	if item, ok := captureText(content, models.SourceClipboard, onNewItem); ok && !item.IsSensitive {
+		mirrorClipboardToPrimary(content)
 	}
+}
+
+// captureText stores text read from the given source and notifies the UI. It
+// returns the stored item, or false if the text was skipped or not stored.
+func captureText(content, source string, onNewItem func(models.ClipboardItem, bool)) (models.ClipboardItem, bool) {
+	item, isNew, err := StoreText(models.ClipboardItem{Content: content, Source: source})
+	if errors.Is(err, ErrSkipped) {
+		return models.ClipboardItem{}, false
+	}
+	if err != nil {
+		slog.Error("error storing captured text", "err", err)
+		return models.ClipboardItem{}, false
+	}
+
+	if isNew {
+		slog.Debug("Captured text", "type", item.Type, "source", source, logging.Content(item.Content))
+	} else {
+		slog.Debug("Moving duplicate to top", "type", item.Type, logging.Content(item.Content))
+	}
+	onNewItem(item, isNew)
+	return item, true
+}
+
+// StoreText saves item's text to the history, moving an existing identical item
+// to the top instead of duplicating it. The capture rules run first and may
+// skip the item with ErrSkipped or change it, so that items they ignore or
+// flag never reach the transform hooks; then the hooks and the user script
+// may rewrite the text, or the script may skip it. The source and the
+// favorite and sensitive flags are stored as given unless a rule or the
+// script changes them. It reports whether a new item was inserted.
+func StoreText(item models.ClipboardItem) (models.ClipboardItem, bool, error) {
+	content := truncateText(item.Content)
 
 	// Detect content type
 	contentType := DetectContentType(content)
 
+	// Secrets are flagged before the rules, hooks and the user script see the item
+	item.Content, item.Type = content, contentType
+	if !flagSecret(&item) {
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+	scanned := content
+
+	// Capture rules may ignore, flag, tag, expire, retype or strip the item
+	outcome := rules.Apply(item)
+	if outcome.Ignore {
+		slog.Info("Ignored item by rule", "type", item.Type, "rule", outcome.Matched[len(outcome.Matched)-1])
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+	item = outcome.Item
+	content = truncateText(item.Content)
+	if content == "" {
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+	// A type forced by a rule is kept when the hooks rewrite the text
+	forcedType := item.Type != contentType
+
+	// Transform hooks may rewrite the text before it is stored
+	item.Content = content
+	if transformed := hooks.Transform(item); transformed != content {
+		item.Content = truncateText(transformed)
+		if !forcedType {
+			item.Type = DetectContentType(item.Content)
+		}
+	}
+
+	// The user script may rewrite or reclassify the item, or keep it out of the history
+	item, store := scripting.Apply(item)
+	if !store {
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+	content, contentType = truncateText(item.Content), item.Type
+	if content == "" {
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+	if content != scanned {
+		item.Content = content
+		if !flagSecret(&item) {
+			return models.ClipboardItem{}, false, ErrSkipped
+		}
+	}
+
+	// Strip tracking parameters from links, keeping what was copied
+	var original string
+	if contentType == "link" {
+		if cleaned := cleanLink(content); cleaned != content {
+			slog.Debug("Cleaned link", "removed_bytes", len(content)-len(cleaned))
+			original, content = content, cleaned
+		}
+	}
+
 	// Check if this content already exists in the database
 	isDuplicate, err := database.CheckDuplicateContent(content)
 	if err != nil {
-		log.Println("error checking for duplicate:", err)
+		slog.Error("error checking for duplicate", "err", err)
 	}
 
 	if isDuplicate {
 		// Get the existing item and move it to the top
 		existingItem, err := database.GetItemByContent(content)
 		if err != nil {
-			log.Println("error getting existing item:", err)
-			return
+			return models.ClipboardItem{}, false, fmt.Errorf("error getting existing item: %w", err)
 		}
 
 		// Update timestamp to move to top of history
-		err = database.UpdateItemTimestamp(existingItem.ID)
-		if err != nil {
-			log.Println("error updating item timestamp:", err)
-			return
+		if err := database.UpdateItemTimestamp(existingItem.ID); err != nil {
+			return models.ClipboardItem{}, false, fmt.Errorf("error updating item timestamp: %w", err)
 		}
+		return recapture(*existingItem, item), false, nil
+	}
 
-		log.Printf("Moving duplicate to top (%s): %s...\n", contentType, truncateString(content, 50))
-		onNewItem(*existingItem)
-	} else {
-		// Insert new item with detected type
-		id, err := database.InsertClipboardItem(content, contentType)
-		if err != nil {
-			log.Println("error inserting clipboard item:", err)
-		} else {
-			// Enforce history limit
-			if err := database.EnforceHistoryLimit(); err != nil {
-				log.Println("error enforcing history limit:", err)
-			}
+	// Insert new item with detected type
+	item.Content = content
+	item.Type = contentType
+	item.OriginalContent = original
+	id, err := database.InsertTextItem(item)
+	if err != nil {
+		return models.ClipboardItem{}, false, fmt.Errorf("error inserting clipboard item: %w", err)
+	}
+	item.ID = int(id)
 
-			items, err := database.GetClipboardHistory(1)
-			if err == nil && len(items) > 0 {
-				items[0].ID = int(id)
-				onNewItem(items[0])
-			}
-		}
+	// Enforce history limit
+	if err := database.EnforceHistoryLimit(); err != nil {
+		slog.Error("error enforcing history limit", "err", err)
 	}
-}
 
-// DetectContentType analyzes the content and returns the appropriate type
-func DetectContentType(content string) string {
-	trimmed := strings.TrimSpace(content)
+	return item, true, nil
+}
 
-	// Check for URL (http, https, www)
-	if urlRegex.MatchString(trimmed) {
-		return "link"
+// recapture gives an existing item what capturing it again decided, so a
+// re-copied item gets its rules' tags and expiry and secrets stored before
+// they could be detected are hidden. It returns the item as updated.
+func recapture(existing, captured models.ClipboardItem) models.ClipboardItem {
+	updated, changed := mergeCapture(existing, captured)
+	if !changed {
+		return existing
 	}
-
-	// Check for email
-	if emailRegex.MatchString(trimmed) {
-		return "email"
+	if err := database.UpdateItemCapture(updated.ID, updated.IsSensitive, updated.DetectedSecret, updated.Tags, updated.ExpiresAt); err != nil {
+		slog.Error("error updating recaptured item", "err", err)
+		return existing
 	}
+	return updated
+}
 
-	// Check for phone number (digits, spaces, dashes, parentheses)
-	if phoneRegex.MatchString(trimmed) && containsDigits(trimmed, 7) {
-		return "phone"
+// mergeCapture adds the sensitivity, tags and expiry of captured to existing,
+// reporting whether anything changed. Nothing is taken away: a new expiry
+// replaces the old one, but an item the rules no longer expire keeps its own.
+func mergeCapture(existing, captured models.ClipboardItem) (models.ClipboardItem, bool) {
+	changed := false
+	if captured.IsSensitive && !existing.IsSensitive {
+		existing.IsSensitive, changed = true, true
 	}
-
-	return "text"
+	if captured.DetectedSecret != "" && existing.DetectedSecret == "" {
+		existing.DetectedSecret, changed = captured.DetectedSecret, true
+	}
+	for _, tag := range captured.Tags {
+		if !slices.Contains(existing.Tags, tag) {
+			existing.Tags, changed = append(slices.Clone(existing.Tags), tag), true
+		}
+	}
+	if !captured.ExpiresAt.IsZero() && !captured.ExpiresAt.Equal(existing.ExpiresAt) {
+		existing.ExpiresAt, changed = captured.ExpiresAt, true
+	}
+	return existing, changed
 }
 
-// containsDigits checks if the string contains at least n digits
-func containsDigits(s string, n int) bool {
-	count := 0
-	for _, r := range s {
-		if r >= '0' && r <= '9' {
-			count++
-			if count >= n {
-				return true
-			}
-		}
+// truncateText cuts content exceeding the maximum stored length
+func truncateText(content string) string {
+	if len(content) <= database.MaxTextLength {
+		return content
 	}
-	return false
+	slog.Info("Content truncated", "max_bytes", database.MaxTextLength)
+	return content[:database.MaxTextLength] + "\n... (truncated)"
 }
 
-func handleImageClipboard(imageData []byte, onNewItem func(models.ClipboardItem)) {
+func handleImageClipboard(imageData []byte, onNewItem func(models.ClipboardItem, bool)) {
 	// Calculate hash to detect duplicates
-	hash := sha256.Sum256(imageData)
-	hashStr := fmt.Sprintf("%x", hash[:8])
+	hashStr := ImageHash(imageData)
 
 	if hashStr == lastImageHash {
 		return // Same image as last read in this session, skip
 	}
 	lastImageHash = hashStr
+	cancelClearUnless("")
+
+	// Image Pastee wrote itself is not a new capture, just a reuse of an existing item
+	if consumeSelfWrite(imageFingerprint(hashStr)) {
+		existingItem, err := database.GetItemByImageHash(hashStr)
+		if err != nil {
+			slog.Error("error getting self-written image item", "err", err)
+			return
+		}
+		if err := database.UpdateItemTimestamp(existingItem.ID); err != nil {
+			slog.Error("error updating image item timestamp", "err", err)
+		}
+		return
+	}
+
+	item, isNew, err := StoreImage(imageData, models.SourceClipboard)
+	if errors.Is(err, ErrSkipped) {
+		return
+	}
+	if err != nil {
+		slog.Error("error storing captured image", "err", err)
+		return
+	}
+	if !isNew {
+		slog.Debug("Moving duplicate image to top", "hash", hashStr)
+	}
+	onNewItem(item, isNew)
+}
+
+// StoreImage saves imageData read from source to the history, moving an
+// existing identical image to the top instead of duplicating it. Capture
+// rules may flag, tag or expire the image, or skip it with ErrSkipped. It
+// reports whether a new item was inserted.
+func StoreImage(imageData []byte, source string) (models.ClipboardItem, bool, error) {
+	outcome := rules.Apply(models.ClipboardItem{Type: TypeImage, Source: source})
+	if outcome.Ignore {
+		slog.Info("Ignored image by rule", "rule", outcome.Matched[len(outcome.Matched)-1])
+		return models.ClipboardItem{}, false, ErrSkipped
+	}
+
+	hashStr := ImageHash(imageData)
 
 	// Check if this image already exists in the database
 	isDuplicate, err := database.CheckDuplicateImageHash(hashStr)
 	if err != nil {
-		log.Println("error checking for duplicate image:", err)
+		slog.Error("error checking for duplicate image", "err", err)
 	}
 
 	if isDuplicate {
 		// Get the existing item and move it to the top
 		existingItem, err := database.GetItemByImageHash(hashStr)
 		if err != nil {
-			log.Println("error getting existing image item:", err)
-			return
+			return models.ClipboardItem{}, false, fmt.Errorf("error getting existing image item: %w", err)
 		}
 
 		// Update timestamp to move to top of history
-		err = database.UpdateItemTimestamp(existingItem.ID)
-		if err != nil {
-			log.Println("error updating image item timestamp:", err)
-			return
+		if err := database.UpdateItemTimestamp(existingItem.ID); err != nil {
+			return models.ClipboardItem{}, false, fmt.Errorf("error updating image item timestamp: %w", err)
 		}
-
-		log.Printf("Moving duplicate image to top (hash: %s)\n", hashStr)
-		onNewItem(*existingItem)
-		return
+		return recapture(*existingItem, outcome.Item), false, nil
 	}
 
 	// New image - detect format and save
-	format := detectImageFormat(imageData)
+	format := DetectImageFormat(imageData)
 	if format == "" {
-		log.Println("Unknown image format")
-		return
+		return models.ClipboardItem{}, false, errors.New("unknown image format")
 	}
 
-	log.Printf("Detected image format: %s, size: %d bytes\n", format, len(imageData))
+	slog.Debug("Detected image format", "format", format, "bytes", len(imageData))
 
 	// Save image and create thumbnail
 	fullPath, thumbPath, err := imageutil.SaveImage(imageData, format)
 	if err != nil {
-		log.Println("error saving image:", err)
-		return
+		return models.ClipboardItem{}, false, fmt.Errorf("error saving image: %w", err)
 	}
 
-	log.Printf("Saved image: %s, thumbnail: %s\n", fullPath, thumbPath)
+	slog.Debug("Saved image", "path", fullPath, "thumbnail", thumbPath)
 
 	// Insert into database with hash
-	id, err := database.InsertImageItem(fullPath, thumbPath, hashStr, "image")
+	item := outcome.Item
+	item.ImagePath, item.PreviewPath = fullPath, thumbPath
+	id, err := database.InsertImage(item, hashStr)
 	if err != nil {
-		log.Println("error inserting image item:", err)
 		// Clean up saved files if database insert fails
 		imageutil.DeleteImage(fullPath, thumbPath)
-		return
+		return models.ClipboardItem{}, false, fmt.Errorf("error inserting image item: %w", err)
 	}
 
 	// Enforce history limit
 	if err := database.EnforceHistoryLimit(); err != nil {
-		log.Println("error enforcing history limit:", err)
+		slog.Error("error enforcing history limit", "err", err)
 	}
 
-	// Notify UI
-	item := models.ClipboardItem{
-		ID:          int(id),
-		Type:        "image",
-		ImagePath:   fullPath,
-		PreviewPath: thumbPath,
-	}
-	onNewItem(item)
+	item.ID = int(id)
+	return item, true, nil
 }
 
-// detectImageFormat detects the image format from the data
-func detectImageFormat(data []byte) string {
+// DetectImageFormat detects the image format from the data
+func DetectImageFormat(data []byte) string {
 	if len(data) < 8 {
 		return ""
 	}
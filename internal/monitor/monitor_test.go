@@ -3,8 +3,10 @@ package monitor
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
 )
 
 func TestDetectContentType_URL(t *testing.T) {
@@ -237,3 +239,35 @@ func TestPhoneRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeCapture(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	existing := models.ClipboardItem{ID: 3, Content: "x", Tags: []string{"work"}}
+
+	got, changed := mergeCapture(existing, models.ClipboardItem{IsSensitive: true, Tags: []string{"work", "otp"}, ExpiresAt: expires})
+	if !changed {
+		t.Fatal("expected the captured rule outcome to change the item")
+	}
+	if !got.IsSensitive || len(got.Tags) != 2 || got.Tags[1] != "otp" || !got.ExpiresAt.Equal(expires) {
+		t.Errorf("mergeCapture = %+v, want sensitive with tags work,otp and the new expiry", got)
+	}
+	if len(existing.Tags) != 1 {
+		t.Errorf("the existing item's tags were modified: %v", existing.Tags)
+	}
+
+	if _, changed := mergeCapture(got, models.ClipboardItem{Tags: []string{"otp"}}); changed {
+		t.Error("an outcome adding nothing should not change the item")
+	}
+	if kept, _ := mergeCapture(got, models.ClipboardItem{}); !kept.IsSensitive || !kept.ExpiresAt.Equal(expires) {
+		t.Errorf("recapturing without rules should not clear sensitivity or expiry: %+v", kept)
+	}
+
+	// A secret detected now is recorded on an item stored before detection caught it
+	flagged, changed := mergeCapture(existing, models.ClipboardItem{IsSensitive: true, DetectedSecret: "aws"})
+	if !changed || flagged.DetectedSecret != "aws" {
+		t.Errorf("mergeCapture = %+v, %v, want the detected secret carried over", flagged, changed)
+	}
+	if kept, _ := mergeCapture(flagged, models.ClipboardItem{DetectedSecret: "high_entropy"}); kept.DetectedSecret != "aws" {
+		t.Errorf("an item's detected secret should not be replaced: %q", kept.DetectedSecret)
+	}
+}
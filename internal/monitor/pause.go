@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// PauseState describes whether capture is currently suspended and why
+type PauseState struct {
+	Paused        bool
+	Until         time.Time // Zero when paused until resumed manually
+	SessionLocked bool      // Paused automatically because the screen is locked
+}
+
+var (
+	pauseMu        sync.Mutex
+	manualPause    bool
+	pauseUntil     time.Time
+	pauseTimer     *time.Timer
+	sessionLocked  bool
+	pauseListeners []func(PauseState)
+)
+
+// Pause suspends clipboard capture for the given duration.
+// A duration of zero or less pauses until Resume is called.
+func Pause(d time.Duration) {
+	pauseMu.Lock()
+	if pauseTimer != nil {
+		pauseTimer.Stop()
+		pauseTimer = nil
+	}
+	manualPause = true
+	if d > 0 {
+		pauseUntil = now().Add(d)
+		pauseTimer = time.AfterFunc(d, expirePause)
+	} else {
+		pauseUntil = time.Time{}
+	}
+	pauseMu.Unlock()
+
+	notifyPauseChange()
+}
+
+// Resume ends a manual pause. Capture stays suspended while the session is locked.
+func Resume() {
+	pauseMu.Lock()
+	if pauseTimer != nil {
+		pauseTimer.Stop()
+		pauseTimer = nil
+	}
+	manualPause = false
+	pauseUntil = time.Time{}
+	pauseMu.Unlock()
+
+	notifyPauseChange()
+}
+
+// IsPaused reports whether clipboard capture is currently suspended
+func IsPaused() bool {
+	return CurrentPauseState().Paused
+}
+
+// CurrentPauseState returns a snapshot of the pause state
+func CurrentPauseState() PauseState {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	return pauseStateLocked()
+}
+
+// OnPauseChange registers a callback invoked whenever the pause state changes.
+// Callbacks run on the goroutine that caused the change.
+func OnPauseChange(fn func(PauseState)) {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	pauseListeners = append(pauseListeners, fn)
+}
+
+// setSessionLocked is driven by the platform screen lock watcher
+func setSessionLocked(locked bool) {
+	pauseMu.Lock()
+	changed := sessionLocked != locked
+	sessionLocked = locked
+	pauseMu.Unlock()
+
+	if changed {
+		notifyPauseChange()
+	}
+}
+
+func expirePause() {
+	pauseMu.Lock()
+	if !manualPause || pauseUntil.IsZero() || now().Before(pauseUntil) {
+		pauseMu.Unlock()
+		return
+	}
+	manualPause = false
+	pauseUntil = time.Time{}
+	pauseTimer = nil
+	pauseMu.Unlock()
+
+	notifyPauseChange()
+}
+
+// pauseStateLocked builds the current state. Callers must hold pauseMu.
+func pauseStateLocked() PauseState {
+	manual := manualPause && (pauseUntil.IsZero() || now().Before(pauseUntil))
+	state := PauseState{
+		Paused:        manual || sessionLocked,
+		SessionLocked: sessionLocked,
+	}
+	if manual {
+		state.Until = pauseUntil
+	}
+	return state
+}
+
+func notifyPauseChange() {
+	pauseMu.Lock()
+	state := pauseStateLocked()
+	listeners := append([]func(PauseState){}, pauseListeners...)
+	pauseMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(state)
+	}
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func resetPauseState(t *testing.T) *time.Time {
+	current := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	pauseMu.Lock()
+	manualPause = false
+	pauseUntil = time.Time{}
+	sessionLocked = false
+	pauseListeners = nil
+	pauseMu.Unlock()
+
+	t.Cleanup(func() {
+		Resume()
+		setSessionLocked(false)
+		now = time.Now
+	})
+	return &current
+}
+
+func TestPause_UntilResumed(t *testing.T) {
+	resetPauseState(t)
+
+	Pause(0)
+	state := CurrentPauseState()
+	if !state.Paused || !state.Until.IsZero() {
+		t.Fatalf("expected indefinite pause, got %+v", state)
+	}
+
+	Resume()
+	if IsPaused() {
+		t.Error("expected capture to resume")
+	}
+}
+
+func TestPause_Timed(t *testing.T) {
+	current := resetPauseState(t)
+
+	Pause(5 * time.Minute)
+	state := CurrentPauseState()
+	if !state.Paused || !state.Until.Equal(current.Add(5*time.Minute)) {
+		t.Fatalf("expected pause until +5m, got %+v", state)
+	}
+
+	*current = current.Add(5 * time.Minute)
+	if IsPaused() {
+		t.Error("timed pause should end once its deadline passes")
+	}
+}
+
+func TestPause_SessionLockIndependentOfManualPause(t *testing.T) {
+	resetPauseState(t)
+
+	var changes []PauseState
+	OnPauseChange(func(s PauseState) { changes = append(changes, s) })
+
+	setSessionLocked(true)
+	Resume()
+	if !IsPaused() {
+		t.Error("resuming must not lift the session lock pause")
+	}
+
+	setSessionLocked(false)
+	if IsPaused() {
+		t.Error("unlocking should resume capture when not manually paused")
+	}
+
+	setSessionLocked(false)
+	if len(changes) != 3 {
+		t.Errorf("expected 3 notifications, got %d", len(changes))
+	}
+}
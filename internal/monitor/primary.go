@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"golang.design/x/clipboard"
+)
+
+// primaryPollInterval is shorter than the clipboard interval so the debounce
+// window can tell a finished selection from one still being dragged
+const primaryPollInterval = 250 * time.Millisecond
+
+var (
+	primaryMu   sync.Mutex
+	lastPrimary string
+)
+
+// startPrimaryMonitor polls the X11 PRIMARY selection when enabled in the config
+func startPrimaryMonitor(onNewItem func(models.ClipboardItem, bool)) {
+	cfg := config.Get().Primary
+	if !cfg.Enabled {
+		return
+	}
+	if !primarySupported() {
+		slog.Warn("PRIMARY selection capture is enabled but not supported on this system")
+		return
+	}
+
+	debounce := time.Duration(cfg.DebounceMs) * time.Millisecond
+	watcher := &selectionDebouncer{debounce: debounce, minLength: cfg.MinLength}
+
+	monitorWG.Add(1)
+	go func() {
+		defer monitorWG.Done()
+		for {
+			if !sleepUnlessStopped(primaryPollInterval) {
+				return
+			}
+
+			text, err := readPrimary()
+			if err != nil || text == "" {
+				continue
+			}
+
+			if IsPaused() {
+				markPrimarySeen(text)
+				watcher.reset()
+				continue
+			}
+
+			if !watcher.observe(text, now()) || primarySeen(text) {
+				continue
+			}
+			markPrimarySeen(text)
+			handlePrimarySelection(text, cfg.Sync, onNewItem)
+		}
+	}()
+}
+
+func handlePrimarySelection(content string, syncSelections bool, onNewItem func(models.ClipboardItem, bool)) {
+	item, ok := captureText(content, models.SourcePrimary, onNewItem)
+
+	// Skipped and sensitive selections are not spread to the other selection
+	if syncSelections && ok && !item.IsSensitive {
+		// The clipboard monitor will see this write; mark it so it only bumps the item
+		MarkSelfWrittenText(content)
+		clipboard.Write(clipboard.FmtText, []byte(content))
+	}
+}
+
+// mirrorClipboardToPrimary copies a captured clipboard text into PRIMARY when
+// sync is on. Callers only pass text that was stored and is not sensitive.
+func mirrorClipboardToPrimary(content string) {
+	cfg := config.Get().Primary
+	if !cfg.Enabled || !cfg.Sync || !primarySupported() {
+		return
+	}
+
+	markPrimarySeen(content)
+	if err := writePrimary(content); err != nil {
+		slog.Error("error syncing clipboard to PRIMARY selection", "err", err)
+	}
+}
+
+func markPrimarySeen(content string) {
+	primaryMu.Lock()
+	defer primaryMu.Unlock()
+	lastPrimary = content
+}
+
+func primarySeen(content string) bool {
+	primaryMu.Lock()
+	defer primaryMu.Unlock()
+	return lastPrimary == content
+}
+
+// selectionDebouncer reports a selection only once it has stopped changing
+// for the debounce window and is long enough to be worth keeping.
+type selectionDebouncer struct {
+	debounce  time.Duration
+	minLength int
+
+	candidate string
+	since     time.Time
+	reported  bool
+}
+
+// observe feeds the current selection and returns true exactly once per stable selection
+func (d *selectionDebouncer) observe(text string, at time.Time) bool {
+	if text != d.candidate {
+		d.candidate = text
+		d.since = at
+		d.reported = false
+		return false
+	}
+	if d.reported || at.Sub(d.since) < d.debounce {
+		return false
+	}
+	d.reported = true
+	return len(strings.TrimSpace(text)) >= d.minLength
+}
+
+func (d *selectionDebouncer) reset() {
+	d.candidate = ""
+	d.reported = false
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"sync"
+
+	"github.com/jezek/xgb/xproto"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/x11"
+)
+
+var (
+	primaryOnce sync.Once
+	primarySel  *x11.Selection
+	primaryErr  error
+)
+
+// primarySelection connects to the X server on first use
+func primarySelection() (*x11.Selection, error) {
+	primaryOnce.Do(func() {
+		primarySel, primaryErr = x11.OpenSelection(xproto.AtomPrimary)
+	})
+	return primarySel, primaryErr
+}
+
+// primarySupported reports whether PRIMARY can be accessed: it needs an X11
+// display (or XWayland)
+func primarySupported() bool {
+	if os.Getenv("DISPLAY") == "" {
+		return false
+	}
+	_, err := primarySelection()
+	return err == nil
+}
+
+func readPrimary() (string, error) {
+	sel, err := primarySelection()
+	if err != nil {
+		return "", err
+	}
+	return sel.Read()
+}
+
+func writePrimary(content string) error {
+	sel, err := primarySelection()
+	if err != nil {
+		return err
+	}
+	return sel.Write(content)
+}
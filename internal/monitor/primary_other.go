@@ -0,0 +1,19 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+var errPrimaryUnsupported = errors.New("PRIMARY selection is only available on X11")
+
+func primarySupported() bool {
+	return false
+}
+
+func readPrimary() (string, error) {
+	return "", errPrimaryUnsupported
+}
+
+func writePrimary(string) error {
+	return errPrimaryUnsupported
+}
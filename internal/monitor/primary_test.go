@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectionDebouncer_WaitsForStableSelection(t *testing.T) {
+	d := &selectionDebouncer{debounce: time.Second, minLength: 3}
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Dragging a selection changes it on every poll
+	for i, text := range []string{"h", "he", "hel", "hello"} {
+		if d.observe(text, start.Add(time.Duration(i)*250*time.Millisecond)) {
+			t.Fatalf("selection %q reported while still changing", text)
+		}
+	}
+
+	changedAt := start.Add(750 * time.Millisecond)
+	if d.observe("hello", changedAt.Add(500*time.Millisecond)) {
+		t.Error("selection reported before debounce elapsed")
+	}
+	if !d.observe("hello", changedAt.Add(time.Second)) {
+		t.Error("stable selection should be reported after debounce")
+	}
+	if d.observe("hello", changedAt.Add(2*time.Second)) {
+		t.Error("stable selection should only be reported once")
+	}
+}
+
+func TestSelectionDebouncer_MinLength(t *testing.T) {
+	d := &selectionDebouncer{debounce: 0, minLength: 3}
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d.observe(" a ", at)
+	if d.observe(" a ", at) {
+		t.Error("selection shorter than min length should be ignored")
+	}
+
+	d.observe("abc", at)
+	if !d.observe("abc", at) {
+		t.Error("selection at min length should be reported")
+	}
+}
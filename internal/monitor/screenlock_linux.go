@@ -0,0 +1,54 @@
+//go:build linux
+
+package monitor
+
+import (
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	screenSaverName      = "org.freedesktop.ScreenSaver"
+	screenSaverPath      = "/org/freedesktop/ScreenSaver"
+	screenSaverInterface = "org.freedesktop.ScreenSaver"
+)
+
+// watchScreenLock pauses capture while org.freedesktop.ScreenSaver reports the session as locked
+func watchScreenLock() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		slog.Warn("screen lock detection unavailable", "err", err)
+		return
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface(screenSaverInterface),
+		dbus.WithMatchMember("ActiveChanged"),
+	); err != nil {
+		slog.Error("error subscribing to screen lock changes", "err", err)
+		conn.Close()
+		return
+	}
+
+	var active bool
+	obj := conn.Object(screenSaverName, screenSaverPath)
+	if err := obj.Call(screenSaverInterface+".GetActive", 0).Store(&active); err == nil {
+		setSessionLocked(active)
+	}
+
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+
+	go func() {
+		for sig := range signals {
+			if sig.Name != screenSaverInterface+".ActiveChanged" || len(sig.Body) == 0 {
+				continue
+			}
+			if active, ok := sig.Body[0].(bool); ok {
+				slog.Info("Session lock changed", "locked", active)
+				setSessionLocked(active)
+			}
+		}
+	}()
+}
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package monitor
+
+// watchScreenLock is a no-op on platforms without a screen lock watcher
+func watchScreenLock() {}
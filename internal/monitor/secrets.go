@@ -0,0 +1,31 @@
+package monitor
+
+import (
+	"log/slog"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/secrets"
+)
+
+// flagSecret marks item sensitive when its content holds a secret, recording
+// which detector found it. It reports false when detected secrets are not to
+// be stored at all.
+func flagSecret(item *models.ClipboardItem) bool {
+	cfg := config.Get().Secrets
+	if !cfg.Enabled || item.DetectedSecret != "" {
+		return true
+	}
+	name := secrets.Scan(item.Content, cfg.Disabled)
+	if name == "" {
+		return true
+	}
+	if cfg.SkipStorage {
+		slog.Info("Not storing detected secret", "type", item.Type, "detector", name)
+		return false
+	}
+	slog.Info("Marked detected secret sensitive", "type", item.Type, "detector", name)
+	item.IsSensitive = true
+	item.DetectedSecret = name
+	return true
+}
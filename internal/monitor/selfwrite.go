@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// selfWriteTTL is how long a write made by Pastee is remembered. It only has
+// to outlive a couple of poll cycles; anything older is treated as a real copy.
+const selfWriteTTL = 10 * time.Second
+
+var (
+	selfWritesMu sync.Mutex
+	selfWrites   = make(map[string]time.Time)
+
+	// now is replaced in tests to control expiry
+	now = time.Now
+)
+
+// MarkSelfWrittenText registers text that Pastee is about to write to the clipboard,
+// so the monitor recognises it instead of capturing it as a new item.
+// Call it before clipboard.Write to avoid racing the poll loop.
+func MarkSelfWrittenText(content string) {
+	markSelfWrite(textFingerprint(content))
+}
+
+// MarkSelfWrittenImage registers image data that Pastee is about to write to the clipboard
+func MarkSelfWrittenImage(data []byte) {
+	markSelfWrite(imageFingerprint(ImageHash(data)))
+}
+
+// ImageHash returns the short hash used to identify images in the database
+func ImageHash(data []byte) string {
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("%x", hash[:8])
+}
+
+func textFingerprint(content string) string {
+	return fmt.Sprintf("text:%x", sha256.Sum256([]byte(content)))
+}
+
+func imageFingerprint(hash string) string {
+	return "image:" + hash
+}
+
+func markSelfWrite(fingerprint string) {
+	selfWritesMu.Lock()
+	defer selfWritesMu.Unlock()
+
+	pruneSelfWrites()
+	selfWrites[fingerprint] = now().Add(selfWriteTTL)
+}
+
+// consumeSelfWrite reports whether the fingerprint belongs to a pending self-write,
+// removing it from the registry so it only suppresses a single capture.
+func consumeSelfWrite(fingerprint string) bool {
+	selfWritesMu.Lock()
+	defer selfWritesMu.Unlock()
+
+	pruneSelfWrites()
+	if _, ok := selfWrites[fingerprint]; !ok {
+		return false
+	}
+	delete(selfWrites, fingerprint)
+	return true
+}
+
+// pruneSelfWrites drops expired entries. Callers must hold selfWritesMu.
+func pruneSelfWrites() {
+	t := now()
+	for fp, expires := range selfWrites {
+		if !t.Before(expires) {
+			delete(selfWrites, fp)
+		}
+	}
+}
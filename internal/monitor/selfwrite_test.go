@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func withFakeClock(t *testing.T) *time.Time {
+	current := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+
+	selfWritesMu.Lock()
+	selfWrites = make(map[string]time.Time)
+	selfWritesMu.Unlock()
+
+	t.Cleanup(func() { now = time.Now })
+	return &current
+}
+
+func TestSelfWrite_TextConsumedOnce(t *testing.T) {
+	withFakeClock(t)
+
+	MarkSelfWrittenText("hello")
+
+	if !consumeSelfWrite(textFingerprint("hello")) {
+		t.Fatal("expected self-written text to be recognised")
+	}
+	if consumeSelfWrite(textFingerprint("hello")) {
+		t.Error("self-write should only suppress a single capture")
+	}
+}
+
+func TestSelfWrite_OtherContentNotSuppressed(t *testing.T) {
+	withFakeClock(t)
+
+	MarkSelfWrittenText("from pastee")
+
+	if consumeSelfWrite(textFingerprint("copied by user")) {
+		t.Error("content Pastee did not write must not be suppressed")
+	}
+	if !consumeSelfWrite(textFingerprint("from pastee")) {
+		t.Error("pending self-write should survive unrelated captures")
+	}
+}
+
+func TestSelfWrite_Expires(t *testing.T) {
+	current := withFakeClock(t)
+
+	MarkSelfWrittenText("stale")
+	*current = current.Add(selfWriteTTL)
+
+	if consumeSelfWrite(textFingerprint("stale")) {
+		t.Error("expired self-write should not be suppressed")
+	}
+}
+
+func TestSelfWrite_ImageByHash(t *testing.T) {
+	withFakeClock(t)
+
+	data := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x01}
+	MarkSelfWrittenImage(data)
+
+	if consumeSelfWrite(textFingerprint(string(data))) {
+		t.Error("image fingerprint must not match text fingerprint")
+	}
+	if !consumeSelfWrite(imageFingerprint(ImageHash(data))) {
+		t.Error("expected self-written image to be recognised")
+	}
+}
@@ -0,0 +1,163 @@
+// Package notify decides which captured items are announced with a desktop
+// notification and what it says. It knows nothing about the GUI toolkit;
+// the caller passes a function that shows the message.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/config"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/Sirpyerre/pasteeclipboard/internal/secrets"
+)
+
+// Notification modes, for all items or per type
+const (
+	ModeOn      = "on"      // Show the content
+	ModeSummary = "summary" // Show only the type of item
+	ModeOff     = "off"
+)
+
+// maxPreviewLength is how much content a notification shows, in characters
+const maxPreviewLength = 100
+
+// Message is a notification to show
+type Message struct {
+	Title   string
+	Content string
+}
+
+// Describe returns the notification for a captured item, or false if none
+// should be shown. Sensitive items never show their content. Do not disturb
+// and rate limiting are left to the Notifier.
+func Describe(cfg config.NotificationsConfig, item models.ClipboardItem, isNew bool) (Message, bool) {
+	if !isNew && !cfg.Duplicates {
+		return Message{}, false
+	}
+	mode := cfg.Mode
+	if m, ok := cfg.Types[item.Type]; ok {
+		mode = m
+	}
+
+	title := "New Clipboard Item"
+	if !isNew {
+		title = "Clipboard Item Moved to Top"
+	}
+	switch {
+	case mode == ModeOff:
+		return Message{}, false
+	case item.DetectedSecret != "":
+		return Message{title, secrets.Label(item.DetectedSecret) + " copied (hidden)"}, true
+	case item.IsSensitive:
+		return Message{title, "Sensitive item copied (hidden)"}, true
+	case item.Type == "image":
+		return Message{title, "New image copied"}, true
+	case mode == ModeOn:
+		return Message{title, preview(item.Content)}, true
+	default:
+		// Unknown modes get the summary, which never shows content
+		return Message{title, fmt.Sprintf("New %s copied", item.Type)}, true
+	}
+}
+
+// preview shortens content to its first line and maxPreviewLength characters
+func preview(content string) string {
+	content = strings.TrimSpace(content)
+	line, _, more := strings.Cut(content, "\n")
+	if utf8.RuneCountInString(line) > maxPreviewLength {
+		line, more = string([]rune(line)[:maxPreviewLength]), true
+	}
+	if more {
+		line += "…"
+	}
+	return line
+}
+
+// Notifier shows notifications for captured items following the config. It
+// shows at most one notification per MinIntervalMs; items captured sooner
+// are announced together once the interval is over.
+type Notifier struct {
+	send func(Message)
+
+	mu       sync.Mutex
+	timer    *time.Timer // Running while notifications are held back
+	held     int         // Notifications held back since the last one shown
+	lastHeld Message
+}
+
+// New returns a Notifier that shows notifications with send. send may be
+// called from any goroutine.
+func New(send func(Message)) *Notifier {
+	return &Notifier{send: send}
+}
+
+// Captured announces a captured item, unless the config says otherwise
+func (n *Notifier) Captured(item models.ClipboardItem, isNew bool) {
+	cfg := config.Get().Notifications
+	if cfg.DoNotDisturb {
+		return
+	}
+	msg, ok := Describe(cfg, item, isNew)
+	if !ok {
+		return
+	}
+	if cfg.MinIntervalMs <= 0 {
+		n.send(msg)
+		return
+	}
+
+	n.mu.Lock()
+	if n.timer != nil {
+		n.held++
+		n.lastHeld = msg
+		n.mu.Unlock()
+		return
+	}
+	n.timer = time.AfterFunc(time.Duration(cfg.MinIntervalMs)*time.Millisecond, n.flush)
+	n.mu.Unlock()
+	n.send(msg)
+}
+
+// flush shows what was held back during the interval that just ended, as a
+// count if there was more than one, and holds back the next ones again
+func (n *Notifier) flush() {
+	cfg := config.Get().Notifications
+	n.mu.Lock()
+	held, last := n.held, n.lastHeld
+	n.held, n.lastHeld = 0, Message{}
+	if held == 0 || cfg.DoNotDisturb || cfg.MinIntervalMs <= 0 {
+		n.timer = nil
+		n.mu.Unlock()
+		return
+	}
+	n.timer = time.AfterFunc(time.Duration(cfg.MinIntervalMs)*time.Millisecond, n.flush)
+	n.mu.Unlock()
+
+	if held == 1 {
+		n.send(last)
+	} else {
+		n.send(Message{"New Clipboard Items", fmt.Sprintf("%d new items", held)})
+	}
+}
+
+// Stop drops held back notifications
+func (n *Notifier) Stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+	n.held, n.lastHeld = 0, Message{}
+}
+
+// SetDoNotDisturb turns do not disturb on or off and saves it to the config
+func SetDoNotDisturb(on bool) error {
+	return config.Update(func(cfg *config.Config) {
+		cfg.Notifications.DoNotDisturb = on
+	})
+}
@@ -0,0 +1,253 @@
+// Package rules decides what happens to captured items. Rules are read from
+// rules.toml in the data directory and checked in order; each one matches
+// items by type, source, content and length, and ignores, flags, tags,
+// expires, retypes or strips the items it matches.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// FileName is the rules file inside the data directory
+const FileName = "rules.toml"
+
+// Rule is one [[rule]] table of the rules file
+type Rule struct {
+	Name string `toml:"name"`
+
+	// Conditions; an item must meet every one that is set
+	Types     []string `toml:"types"`      // Any of these types
+	Sources   []string `toml:"sources"`    // Any of these sources, e.g. "clipboard" or "primary"
+	Content   string   `toml:"content"`    // Regular expression found in the content
+	MinLength int      `toml:"min_length"` // In characters
+	MaxLength int      `toml:"max_length"`
+
+	// Actions
+	Ignore    bool          `toml:"ignore"`     // Don't store the item; later rules are skipped
+	Sensitive bool          `toml:"sensitive"`  // Mark the item sensitive
+	Tags      []string      `toml:"tags"`       // Tags added to the item
+	Expire    time.Duration `toml:"expire"`     // Delete the item this long after capture, e.g. "12h"
+	ForceType string        `toml:"force_type"` // Store the item with this type instead of the detected one
+	Strip     string        `toml:"strip"`      // Regular expression whose matches are removed from the content
+
+	content *regexp.Regexp
+	strip   *regexp.Regexp
+}
+
+// Set is a parsed rules file
+type Set struct {
+	Rules []Rule `toml:"rule"`
+}
+
+// Outcome is what the rules made of an item
+type Outcome struct {
+	Item    models.ClipboardItem // The item with the actions applied
+	Ignore  bool                 // The item should not be stored
+	Matched []string             // Names of the rules that matched, in order
+}
+
+// Parse reads a rules file, rejecting unknown keys and invalid patterns
+func Parse(data string) (*Set, error) {
+	var set Set
+	meta, err := toml.Decode(data, &set)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown setting %s", undecoded[0])
+	}
+
+	for i := range set.Rules {
+		r := &set.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.Content != "" {
+			if r.content, err = regexp.Compile(r.Content); err != nil {
+				return nil, fmt.Errorf("%s: content: %w", r.Name, err)
+			}
+		}
+		if r.Strip != "" {
+			if r.strip, err = regexp.Compile(r.Strip); err != nil {
+				return nil, fmt.Errorf("%s: strip: %w", r.Name, err)
+			}
+		}
+		if r.ForceType == "image" {
+			return nil, fmt.Errorf("%s: text cannot be stored as an image", r.Name)
+		}
+		if r.Expire < 0 {
+			return nil, fmt.Errorf("%s: expire must not be negative", r.Name)
+		}
+		for _, tag := range r.Tags {
+			if tag == "" || strings.Contains(tag, ",") {
+				return nil, fmt.Errorf("%s: invalid tag %q", r.Name, tag)
+			}
+		}
+	}
+	return &set, nil
+}
+
+// Matches reports whether item meets all of r's conditions. Images have no
+// text, so rules with content or length conditions never match them.
+func (r *Rule) Matches(item models.ClipboardItem) bool {
+	if len(r.Types) > 0 && !slices.Contains(r.Types, item.Type) {
+		return false
+	}
+	if len(r.Sources) > 0 && !slices.Contains(r.Sources, item.Source) {
+		return false
+	}
+	hasTextConditions := r.content != nil || r.MinLength > 0 || r.MaxLength > 0
+	if item.Type == "image" {
+		return !hasTextConditions
+	}
+	if r.content != nil && !r.content.MatchString(item.Content) {
+		return false
+	}
+	length := utf8.RuneCountInString(item.Content)
+	if r.MinLength > 0 && length < r.MinLength {
+		return false
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return false
+	}
+	return true
+}
+
+// Evaluate applies the actions of every rule matching item, in order. Each
+// rule sees the item as changed by the rules before it. Of several expiry
+// times the earliest wins.
+func (s *Set) Evaluate(item models.ClipboardItem) Outcome {
+	out := Outcome{Item: item}
+	if s == nil {
+		return out
+	}
+	for i := range s.Rules {
+		r := &s.Rules[i]
+		if !r.Matches(out.Item) {
+			continue
+		}
+		out.Matched = append(out.Matched, r.Name)
+		if r.Ignore {
+			out.Ignore = true
+			return out
+		}
+		r.apply(&out.Item)
+	}
+	return out
+}
+
+func (r *Rule) apply(item *models.ClipboardItem) {
+	if r.Sensitive {
+		item.IsSensitive = true
+	}
+	for _, tag := range r.Tags {
+		if !slices.Contains(item.Tags, tag) {
+			item.Tags = append(slices.Clone(item.Tags), tag)
+		}
+	}
+	if r.Expire > 0 {
+		expires := time.Now().Add(r.Expire)
+		if item.ExpiresAt.IsZero() || expires.Before(item.ExpiresAt) {
+			item.ExpiresAt = expires
+		}
+	}
+	if item.Type == "image" {
+		return
+	}
+	if r.ForceType != "" {
+		item.Type = r.ForceType
+	}
+	if r.strip != nil {
+		item.Content = r.strip.ReplaceAllString(item.Content, "")
+	}
+}
+
+var (
+	mu      sync.Mutex
+	path    string
+	current *Set
+	modTime time.Time
+	size    int64
+)
+
+// SetPath makes Apply read the rules from p instead of the data directory
+func SetPath(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	path, current, modTime, size = p, nil, time.Time{}, 0
+}
+
+// Path returns the rules file used by Apply
+func Path() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return rulesPath()
+}
+
+func rulesPath() (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dataDir, err := database.DataDir()
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Join(dataDir, FileName)
+	return path, nil
+}
+
+// Current returns the rules from the rules file, reading it again when it
+// has changed. An invalid file is reported and the rules read before it are
+// kept; without a file there are no rules.
+func Current() *Set {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, err := rulesPath()
+	if err != nil {
+		return current
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		current, modTime, size = nil, time.Time{}, 0
+		return nil
+	}
+	if err != nil || (info.ModTime().Equal(modTime) && info.Size() == size) {
+		return current
+	}
+	modTime, size = info.ModTime(), info.Size()
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		slog.Error("error reading rules", "err", err)
+		return current
+	}
+	set, err := Parse(string(data))
+	if err != nil {
+		slog.Error("Invalid rules file, keeping the previous rules", "path", p, "err", err)
+		return current
+	}
+	slog.Info("Loaded capture rules", "count", len(set.Rules), "path", p)
+	current = set
+	return current
+}
+
+// Apply evaluates the rules file against a captured item
+func Apply(item models.ClipboardItem) Outcome {
+	return Current().Evaluate(item)
+}
@@ -0,0 +1,214 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+const testRules = `
+[[rule]]
+name = "no short selections"
+sources = ["primary"]
+max_length = 3
+ignore = true
+
+[[rule]]
+name = "passwords"
+content = '(?i)^password[:=]'
+sensitive = true
+expire = "1h"
+tags = ["secret"]
+
+[[rule]]
+name = "jira keys"
+content = '^\s*[A-Z]+-[0-9]+\s*$'
+force_type = "jira"
+strip = '\s+'
+tags = ["jira"]
+
+[[rule]]
+name = "screenshots"
+types = ["image"]
+expire = "24h"
+tags = ["screenshot"]
+
+[[rule]]
+name = "short-lived secrets"
+tags = ["secret"]
+sensitive = true
+expire = "10m"
+content = "^password=temp"
+`
+
+func mustParse(t *testing.T, data string) *Set {
+	t.Helper()
+	set, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return set
+}
+
+func TestEvaluate(t *testing.T) {
+	set := mustParse(t, testRules)
+
+	tests := []struct {
+		name    string
+		item    models.ClipboardItem
+		ignore  bool
+		matched []string
+		check   func(t *testing.T, item models.ClipboardItem)
+	}{
+		{
+			name:    "short primary selection is ignored",
+			item:    models.ClipboardItem{Content: "ab", Type: "text", Source: models.SourcePrimary},
+			ignore:  true,
+			matched: []string{"no short selections"},
+		},
+		{
+			name: "short clipboard copy is kept",
+			item: models.ClipboardItem{Content: "ab", Type: "text", Source: models.SourceClipboard},
+		},
+		{
+			name:    "password is flagged, tagged and expires",
+			item:    models.ClipboardItem{Content: "Password: hunter2", Type: "text", Source: models.SourceClipboard},
+			matched: []string{"passwords"},
+			check: func(t *testing.T, item models.ClipboardItem) {
+				if !item.IsSensitive || !slices.Equal(item.Tags, []string{"secret"}) {
+					t.Errorf("expected a sensitive item tagged secret, got %+v", item)
+				}
+				if d := time.Until(item.ExpiresAt); d < 59*time.Minute || d > time.Hour {
+					t.Errorf("expected expiry in an hour, got %v", d)
+				}
+			},
+		},
+		{
+			name:    "jira key is retyped and stripped",
+			item:    models.ClipboardItem{Content: "  PAS-42\n", Type: "text"},
+			matched: []string{"jira keys"},
+			check: func(t *testing.T, item models.ClipboardItem) {
+				if item.Type != "jira" || item.Content != "PAS-42" || item.IsSensitive {
+					t.Errorf("got %+v", item)
+				}
+			},
+		},
+		{
+			name:    "images only match rules without text conditions",
+			item:    models.ClipboardItem{Type: "image", Source: models.SourceClipboard},
+			matched: []string{"screenshots"},
+			check: func(t *testing.T, item models.ClipboardItem) {
+				if item.Type != "image" || !slices.Equal(item.Tags, []string{"screenshot"}) {
+					t.Errorf("got %+v", item)
+				}
+			},
+		},
+		{
+			name:    "actions accumulate, tags are not repeated and the earliest expiry wins",
+			item:    models.ClipboardItem{Content: "password=temp123", Type: "text"},
+			matched: []string{"passwords", "short-lived secrets"},
+			check: func(t *testing.T, item models.ClipboardItem) {
+				if !slices.Equal(item.Tags, []string{"secret"}) {
+					t.Errorf("expected one secret tag, got %v", item.Tags)
+				}
+				if d := time.Until(item.ExpiresAt); d > 10*time.Minute {
+					t.Errorf("expected the 10 minute expiry, got %v", d)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := set.Evaluate(tt.item)
+			if out.Ignore != tt.ignore {
+				t.Errorf("Ignore = %v, want %v", out.Ignore, tt.ignore)
+			}
+			if !slices.Equal(out.Matched, tt.matched) {
+				t.Errorf("Matched = %v, want %v", out.Matched, tt.matched)
+			}
+			if tt.check != nil {
+				tt.check(t, out.Item)
+			}
+		})
+	}
+}
+
+func TestEvaluate_DoesNotChangeCallersTags(t *testing.T) {
+	set := mustParse(t, `
+[[rule]]
+tags = ["new"]
+`)
+	tags := make([]string, 1, 4)
+	tags[0] = "old"
+	out := set.Evaluate(models.ClipboardItem{Content: "x", Type: "text", Tags: tags})
+	if !slices.Equal(out.Item.Tags, []string{"old", "new"}) || out.Matched[0] != "rule 1" {
+		t.Errorf("got %+v", out)
+	}
+	if tags[:2][1] != "" {
+		t.Error("the caller's tag slice was written to")
+	}
+}
+
+func TestEvaluate_NilSet(t *testing.T) {
+	var set *Set
+	item := models.ClipboardItem{Content: "x", Type: "text"}
+	if out := set.Evaluate(item); out.Ignore || out.Item.Content != "x" || len(out.Matched) != 0 {
+		t.Errorf("no rules should leave the item alone, got %+v", out)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"bad regex":       "[[rule]]\ncontent = '(['",
+		"bad strip":       "[[rule]]\nstrip = '(['",
+		"unknown key":     "[[rule]]\nignroe = true",
+		"image type":      "[[rule]]\nforce_type = 'image'",
+		"bad duration":    "[[rule]]\nexpire = 'soon'",
+		"comma in tag":    "[[rule]]\ntags = ['a,b']",
+		"negative expiry": "[[rule]]\nexpire = '-1h'",
+		"not toml":        "[[rule]\n",
+	}
+	for name, data := range tests {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCurrent_ReloadsChangedFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), FileName)
+	SetPath(p)
+	defer SetPath("")
+
+	if Current() != nil {
+		t.Fatal("without a file there should be no rules")
+	}
+	write := func(data string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(p, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mtime, mtime)
+	}
+
+	write("[[rule]]\nname = 'first'\n", time.Now().Add(-time.Minute))
+	if set := Current(); set == nil || set.Rules[0].Name != "first" {
+		t.Fatalf("rules were not loaded: %+v", set)
+	}
+
+	// An invalid edit keeps the rules that worked
+	write("[[rule]]\ncontent = '(['\n", time.Now().Add(-30*time.Second))
+	if set := Current(); set == nil || set.Rules[0].Name != "first" {
+		t.Errorf("invalid rules should not replace valid ones: %+v", set)
+	}
+
+	write("[[rule]]\nname = 'second'\n", time.Now())
+	if out := Apply(models.ClipboardItem{Content: "x", Type: "text"}); !strings.Contains(strings.Join(out.Matched, ","), "second") {
+		t.Errorf("changed rules were not reloaded: %+v", out)
+	}
+}
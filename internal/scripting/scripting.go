@@ -0,0 +1,339 @@
+// Package scripting runs the user's Starlark script on captured items. The
+// script may define any of these functions, each taking the item:
+//
+//	transform(item)    returns new content, or None to keep it
+//	classify(item)     returns the item type, or None to keep the detected one
+//	should_store(item) returns False to keep the item out of the history
+//
+// Scripts run sandboxed: they cannot load modules, touch files or run
+// commands, and each call is stopped after a time budget.
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/database"
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+	"github.com/fsnotify/fsnotify"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// FileName is the script file inside the data directory
+const FileName = "pastee.star"
+
+const (
+	// callBudget is how long one call into the script may run
+	callBudget = 200 * time.Millisecond
+	// maxSteps bounds the work of one call independently of the machine's speed
+	maxSteps = 5_000_000
+)
+
+var (
+	mu      sync.Mutex
+	path    string
+	globals starlark.StringDict // The loaded script, nil without one
+	lastErr error
+	onError func(err error)
+)
+
+// Start loads the script from the data directory and reloads it whenever the
+// file changes. Call the returned function on exit.
+func Start() func() {
+	dataDir, err := database.DataDir()
+	if err != nil {
+		slog.Error("error locating data directory, scripts disabled", "err", err)
+		return func() {}
+	}
+	return Watch(filepath.Join(dataDir, FileName))
+}
+
+// Watch loads the script at p and reloads it when it changes
+func Watch(p string) func() {
+	mu.Lock()
+	path = p
+	mu.Unlock()
+	Reload()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		// Editors often replace the file, so watch its directory
+		err = watcher.Add(filepath.Dir(p))
+	}
+	if err != nil {
+		slog.Warn("Script: not watching for changes", "err", err)
+		if watcher != nil {
+			watcher.Close()
+		}
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		settle := time.NewTimer(time.Hour)
+		settle.Stop()
+		for {
+			select {
+			case change, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(change.Name) == filepath.Clean(p) {
+					settle.Reset(200 * time.Millisecond)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Script: watching for changes", "err", err)
+			case <-settle.C:
+				Reload()
+			}
+		}
+	}()
+	return func() {
+		watcher.Close()
+		<-done
+	}
+}
+
+// Reload reads the script file again. A missing file means no script.
+func Reload() {
+	mu.Lock()
+	p := path
+	mu.Unlock()
+
+	src, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		setScript(nil, nil)
+		return
+	}
+	if err != nil {
+		setScript(nil, err)
+		return
+	}
+	loaded, err := load(p, src)
+	if err != nil {
+		setScript(nil, err)
+		return
+	}
+	slog.Info("Loaded script", "path", p)
+	setScript(loaded, nil)
+}
+
+// load runs the script's top level, which defines its functions
+func load(filename string, src []byte) (starlark.StringDict, error) {
+	thread, stop := newThread()
+	defer stop()
+	loaded, err := starlark.ExecFile(thread, filename, src, predeclared)
+	if err != nil {
+		return nil, describe(err)
+	}
+	for _, name := range []string{"transform", "classify", "should_store"} {
+		if fn, ok := loaded[name]; ok {
+			if _, ok := fn.(starlark.Callable); !ok {
+				return nil, fmt.Errorf("%s is a %s, not a function", name, fn.Type())
+			}
+		}
+	}
+	return loaded, nil
+}
+
+func setScript(loaded starlark.StringDict, err error) {
+	mu.Lock()
+	globals = loaded
+	mu.Unlock()
+	report(err)
+}
+
+// OnError sets a function called when the script fails, and with nil once it
+// works again. It is called from the goroutine that ran the script.
+func OnError(fn func(err error)) {
+	mu.Lock()
+	defer mu.Unlock()
+	onError = fn
+}
+
+// LastError returns the error of the last load or call, nil if it succeeded
+func LastError() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return lastErr
+}
+
+// report records err and tells the error handler when it changed
+func report(err error) {
+	mu.Lock()
+	changed := (err == nil) != (lastErr == nil) || (err != nil && err.Error() != lastErr.Error())
+	lastErr = err
+	fn := onError
+	mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err != nil {
+		slog.Error("Script error", "err", err)
+	}
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// Apply runs the script on a captured text item and returns the item as it
+// should be stored, and whether it should be stored at all. Without a script,
+// or if the script fails, the item is returned unchanged.
+func Apply(item models.ClipboardItem) (models.ClipboardItem, bool) {
+	mu.Lock()
+	loaded := globals
+	mu.Unlock()
+	if loaded == nil {
+		return item, true
+	}
+
+	result, store, err := apply(loaded, item)
+	report(err)
+	if err != nil {
+		return item, true
+	}
+	return result, store
+}
+
+func apply(loaded starlark.StringDict, item models.ClipboardItem) (models.ClipboardItem, bool, error) {
+	if out, ok, err := call(loaded, "transform", item); err != nil {
+		return item, true, err
+	} else if ok && out != starlark.None {
+		s, ok := starlark.AsString(out)
+		if !ok {
+			return item, true, fmt.Errorf("transform returned a %s, not a string", out.Type())
+		}
+		item.Content = s
+	}
+
+	if out, ok, err := call(loaded, "classify", item); err != nil {
+		return item, true, err
+	} else if ok && out != starlark.None {
+		s, ok := starlark.AsString(out)
+		if !ok || s == "" || s == "image" {
+			return item, true, fmt.Errorf("classify returned %s, not a text type", out.String())
+		}
+		item.Type = s
+	}
+
+	if out, ok, err := call(loaded, "should_store", item); err != nil {
+		return item, true, err
+	} else if ok {
+		return item, bool(out.Truth()), nil
+	}
+	return item, true, nil
+}
+
+// call runs the script function name on item, reporting false if the script
+// does not define it
+func call(loaded starlark.StringDict, name string, item models.ClipboardItem) (out starlark.Value, ok bool, err error) {
+	fn, ok := loaded[name].(starlark.Callable)
+	if !ok {
+		return nil, false, nil
+	}
+	thread, stop := newThread()
+	defer stop()
+	// A bug in the interpreter must not take the monitor down with it
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+
+	out, err = starlark.Call(thread, fn, starlark.Tuple{itemValue(item)}, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", name, describe(err))
+	}
+	return out, true, nil
+}
+
+// newThread returns a sandboxed thread that is cancelled after callBudget
+func newThread() (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name:  "pastee",
+		Print: func(_ *starlark.Thread, msg string) { slog.Info("Script output", "msg", msg) },
+		// Load is left unset, so load() statements fail
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	timer := time.AfterFunc(callBudget, func() {
+		thread.Cancel(fmt.Sprintf("took longer than %v", callBudget))
+	})
+	return thread, func() { timer.Stop() }
+}
+
+// describe adds the script's stack trace to evaluation errors
+func describe(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
+
+// itemValue is the item as scripts see it
+func itemValue(item models.ClipboardItem) starlark.Value {
+	s := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"content":   starlark.String(item.Content),
+		"type":      starlark.String(item.Type),
+		"source":    starlark.String(item.Source),
+		"sensitive": starlark.Bool(item.IsSensitive),
+		"favorite":  starlark.Bool(item.IsFavorite),
+	})
+	s.Freeze()
+	return s
+}
+
+// predeclared are the names scripts can use besides the Starlark built-ins
+var predeclared = starlark.StringDict{
+	"json":    json.Module,
+	"matches": starlark.NewBuiltin("matches", matches),
+}
+
+// matches(pattern, text) reports whether the regular expression matches text
+func matches(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, text string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &text); err != nil {
+		return nil, err
+	}
+	re, err := compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return starlark.Bool(re.MatchString(text)), nil
+}
+
+var (
+	patternsMu sync.Mutex
+	patterns   = make(map[string]*regexp.Regexp)
+)
+
+// compile caches patterns, since scripts use the same ones on every capture
+func compile(pattern string) (*regexp.Regexp, error) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) >= 256 {
+		clear(patterns) // Patterns built from content would otherwise pile up
+	}
+	patterns[pattern] = re
+	return re, nil
+}
@@ -0,0 +1,169 @@
+package scripting
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirpyerre/pasteeclipboard/internal/models"
+)
+
+// useScript loads src as the script for the rest of the test
+func useScript(t *testing.T, src string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(p, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	path = p
+	mu.Unlock()
+	Reload()
+	t.Cleanup(func() {
+		setScript(nil, nil)
+		OnError(nil)
+	})
+	return p
+}
+
+func TestApply(t *testing.T) {
+	useScript(t, `
+def transform(item):
+    if item.type == "link":
+        return item.content.replace("http://", "https://")
+
+def classify(item):
+    if matches(r"^[A-Z]+-[0-9]+$", item.content):
+        return "jira"
+
+def should_store(item):
+    return not item.content.startswith("tmp:")
+`)
+
+	item, store := Apply(models.ClipboardItem{Content: "http://example.com", Type: "link"})
+	if !store || item.Content != "https://example.com" || item.Type != "link" {
+		t.Errorf("transform: got %+v, store %v", item, store)
+	}
+	item, _ = Apply(models.ClipboardItem{Content: "PAS-123", Type: "text"})
+	if item.Type != "jira" || item.Content != "PAS-123" {
+		t.Errorf("classify: got %+v", item)
+	}
+	if _, store := Apply(models.ClipboardItem{Content: "tmp: scratch", Type: "text"}); store {
+		t.Error("should_store returning False should skip the item")
+	}
+	if err := LastError(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestApply_WithoutScript(t *testing.T) {
+	useScript(t, "")
+	setScript(nil, nil)
+
+	in := models.ClipboardItem{Content: "hello", Type: "text"}
+	if item, store := Apply(in); !store || item.Content != in.Content || item.Type != in.Type {
+		t.Errorf("without a script the item should be stored unchanged, got %+v, %v", item, store)
+	}
+}
+
+func TestApply_ErrorsKeepItemAndAreReported(t *testing.T) {
+	var mu sync.Mutex
+	var reported []error
+	useScript(t, `
+def transform(item):
+    return item.content[0]
+
+def should_store(item):
+    return False
+`)
+	OnError(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, err)
+	})
+
+	in := models.ClipboardItem{Content: "", Type: "text"}
+	item, store := Apply(in)
+	if !store || item.Content != in.Content || item.Type != in.Type {
+		t.Errorf("a failing script should leave the item alone, got %+v, %v", item, store)
+	}
+	Apply(in) // The same error is reported once
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "transform") {
+		t.Fatalf("expected one transform error, got %v", reported)
+	}
+
+	// The next successful call clears it
+	Apply(models.ClipboardItem{Content: "x", Type: "text"})
+	if len(reported) != 2 || reported[1] != nil || LastError() != nil {
+		t.Errorf("the error should be cleared, got %v", reported)
+	}
+}
+
+func TestApply_TimeBudget(t *testing.T) {
+	useScript(t, `
+def transform(item):
+    n = 0
+    for i in range(1000000000):
+        n += i
+    return str(n)
+`)
+
+	start := time.Now()
+	item, store := Apply(models.ClipboardItem{Content: "x", Type: "text"})
+	if time.Since(start) > 2*time.Second {
+		t.Error("a runaway call should be stopped")
+	}
+	if !store || item.Content != "x" || LastError() == nil {
+		t.Errorf("a stopped call should keep the item and report an error, got %+v, %v", item, LastError())
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	for name, src := range map[string]string{
+		"load":       `load("os.star", "system")`,
+		"bad syntax": "def transform(item)\n    return item",
+		"not a func": `transform = "x"`,
+	} {
+		useScript(t, src)
+		if LastError() == nil {
+			t.Errorf("%s: the script should be rejected", name)
+		}
+		if item, store := Apply(models.ClipboardItem{Content: "x"}); !store || item.Content != "x" {
+			t.Errorf("%s: a rejected script should not run", name)
+		}
+	}
+}
+
+func TestClassifyRejectsImage(t *testing.T) {
+	useScript(t, `
+def classify(item):
+    return "image"
+`)
+	if item, _ := Apply(models.ClipboardItem{Content: "x", Type: "text"}); item.Type != "text" || LastError() == nil {
+		t.Errorf("text must not be classified as an image, got %+v", item)
+	}
+}
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	p := filepath.Join(t.TempDir(), FileName)
+	stop := Watch(p)
+	defer stop()
+	defer setScript(nil, nil)
+
+	if err := os.WriteFile(p, []byte(`def transform(item):
+    return item.content.upper()
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if item, _ := Apply(models.ClipboardItem{Content: "x"}); item.Content == "X" {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("the script was not loaded after the file was written")
+}
@@ -0,0 +1,231 @@
+// Package secrets recognizes credentials and other sensitive data in copied
+// text, so it can be hidden without the user marking it by hand.
+package secrets
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Detector finds one kind of secret
+type Detector struct {
+	Name  string // Used in the config and stored with flagged items
+	Label string // Shown to the user
+	match func(text string) bool
+}
+
+var detectors = []Detector{
+	{"private_key", "Private key", matchRegexp(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"aws", "AWS key", matchAny(
+		`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`,
+		`(?i)aws.{0,20}secret.{0,20}[:=]\s*["']?[A-Za-z0-9/+]{40}\b`,
+	)},
+	{"gcp", "Google Cloud key", matchAny(
+		`\bAIza[0-9A-Za-z_\-]{35}\b`,
+		`"type"\s*:\s*"service_account"`,
+	)},
+	{"github", "GitHub token", matchAny(
+		`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`,
+		`\bgithub_pat_[A-Za-z0-9_]{22,255}\b`,
+	)},
+	{"slack", "Slack token", matchAny(
+		`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+		`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]+`,
+	)},
+	{"jwt", "JSON Web Token", matchRegexp(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{16,}`)},
+	{"password", "Password", matchRegexp(`(?i)(?:^|[^a-z0-9])(?:password|passwd|pwd|passphrase|secret|api[_-]?key|access[_-]?token)["']?\s*[:=]\s*["']?[^\s"']{4,}`)},
+	{"credit_card", "Credit card number", hasCardNumber},
+	{"iban", "IBAN", hasIBAN},
+	{"high_entropy", "Random-looking token", hasHighEntropyToken},
+}
+
+// Detectors returns the detectors in the order they are tried
+func Detectors() []Detector {
+	return slices.Clone(detectors)
+}
+
+// Label returns the user-facing name of a detector, or name if it is unknown
+func Label(name string) string {
+	for _, d := range detectors {
+		if d.Name == name {
+			return d.Label
+		}
+	}
+	return name
+}
+
+// Scan returns the name of the first detector, not among disabled, that finds
+// a secret in text, or "" if none does
+func Scan(text string, disabled []string) string {
+	for _, d := range detectors {
+		if !slices.Contains(disabled, d.Name) && d.match(text) {
+			return d.Name
+		}
+	}
+	return ""
+}
+
+func matchRegexp(pattern string) func(string) bool {
+	return regexp.MustCompile(pattern).MatchString
+}
+
+func matchAny(patterns ...string) func(string) bool {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return func(text string) bool {
+		return slices.ContainsFunc(res, func(re *regexp.Regexp) bool { return re.MatchString(text) })
+	}
+}
+
+// cardCandidate is 13 to 19 digits, optionally grouped with spaces or dashes
+var cardCandidate = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+func hasCardNumber(text string) bool {
+	for _, candidate := range cardCandidate.FindAllString(text, -1) {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(candidate)
+		if hasCardPrefix(digits) && luhnValid(digits) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCardPrefix reports whether digits start like a Visa, Mastercard, Amex,
+// Discover, JCB or Diners card number
+func hasCardPrefix(digits string) bool {
+	switch {
+	case digits[0] == '4':
+		return true
+	case digits[0] == '5' && digits[1] >= '1' && digits[1] <= '5':
+		return true
+	case digits[0] == '2' && digits[1] >= '2' && digits[1] <= '7':
+		return true
+	case strings.HasPrefix(digits, "34"), strings.HasPrefix(digits, "37"):
+		return true
+	case strings.HasPrefix(digits, "6011"), strings.HasPrefix(digits, "65"), strings.HasPrefix(digits, "35"):
+		return true
+	case strings.HasPrefix(digits, "36"), strings.HasPrefix(digits, "30"):
+		return true
+	}
+	return false
+}
+
+// luhnValid checks the Luhn checksum of a string of digits
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanCandidate is a country code, check digits and 11 to 30 letters or
+// digits, optionally grouped in fours with spaces
+var ibanCandidate = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+
+func hasIBAN(text string) bool {
+	for _, candidate := range ibanCandidate.FindAllString(text, -1) {
+		if ibanValid(strings.ReplaceAll(candidate, " ", "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ibanValid checks the ISO 13616 mod-97 checksum
+func ibanValid(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	var numeric strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+const (
+	minEntropyTokenLength = 24
+	// minEntropy in bits per character; English words and identifiers stay well below
+	minEntropy = 4.0
+)
+
+// isTokenSeparator reports whether r cannot be part of a base64 or similar
+// encoded key
+func isTokenSeparator(r rune) bool {
+	return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || strings.ContainsRune("+/=_-", r))
+}
+
+// hasHighEntropyToken reports whether text holds a long token of mixed-case
+// letters and digits that looks random, like an API key. Hex strings are
+// left out, as they are usually hashes or ids rather than secrets, and so
+// are URLs, whose paths often hold random-looking document ids.
+func hasHighEntropyToken(text string) bool {
+	for _, word := range strings.Fields(text) {
+		if strings.Contains(word, "://") {
+			continue
+		}
+		for _, token := range strings.FieldsFunc(word, isTokenSeparator) {
+			token = strings.TrimRight(token, "=")
+			if len(token) < minEntropyTokenLength || isHex(token) || !hasMixedClasses(token) {
+				continue
+			}
+			if shannonEntropy(token) >= minEntropy {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isHex(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F' || r == '-')
+	}) < 0
+}
+
+func hasMixedClasses(s string) bool {
+	return strings.IndexFunc(s, unicode.IsUpper) >= 0 &&
+		strings.IndexFunc(s, unicode.IsLower) >= 0 &&
+		strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
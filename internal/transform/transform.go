@@ -0,0 +1,235 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Transform rewrites text before it is pasted
+type Transform struct {
+	Name  string // Identifier used on the command line, e.g. "snake"
+	Label string // Menu label, e.g. "snake_case"
+	Apply func(string) (string, error)
+}
+
+var builtins = []Transform{
+	{Name: "trim", Label: "Trimmed", Apply: infallible(strings.TrimSpace)},
+	{Name: "upper", Label: "UPPERCASE", Apply: infallible(strings.ToUpper)},
+	{Name: "lower", Label: "lowercase", Apply: infallible(strings.ToLower)},
+	{Name: "title", Label: "Title Case", Apply: infallible(titleCase)},
+	{Name: "snake", Label: "snake_case", Apply: infallible(snakeCase)},
+	{Name: "camel", Label: "camelCase", Apply: infallible(camelCase)},
+	{Name: "json-pretty", Label: "JSON (pretty)", Apply: jsonPretty},
+	{Name: "json-minify", Label: "JSON (minified)", Apply: jsonMinify},
+	{Name: "base64-encode", Label: "Base64 encoded", Apply: infallible(base64Encode)},
+	{Name: "base64-decode", Label: "Base64 decoded", Apply: base64Decode},
+	{Name: "url-encode", Label: "URL encoded", Apply: infallible(url.QueryEscape)},
+	{Name: "url-decode", Label: "URL decoded", Apply: url.QueryUnescape},
+	{Name: "shell-escape", Label: "Shell argument", Apply: infallible(shellEscape)},
+	{Name: "json-escape", Label: "JSON string", Apply: jsonEscape},
+	{Name: "go-escape", Label: "Go string", Apply: infallible(strconv.Quote)},
+	{Name: "sort-lines", Label: "Sorted lines", Apply: infallible(sortLines)},
+	{Name: "dedupe-lines", Label: "Deduplicated lines", Apply: infallible(dedupeLines)},
+	{Name: "strip-formatting", Label: "Plain text", Apply: infallible(stripFormatting)},
+}
+
+// All returns the built-in transforms in menu order
+func All() []Transform {
+	return append([]Transform{}, builtins...)
+}
+
+// Get looks up a transform by name
+func Get(name string) (Transform, bool) {
+	for _, t := range builtins {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Transform{}, false
+}
+
+// Apply runs the named transform on s
+func Apply(name, s string) (string, error) {
+	t, ok := Get(name)
+	if !ok {
+		return "", fmt.Errorf("unknown transform %q", name)
+	}
+	return t.Apply(s)
+}
+
+func infallible(fn func(string) string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		return fn(s), nil
+	}
+}
+
+// splitWords breaks text into words on separators and camelCase boundaries
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Split "fooBar" before B, and "HTTPServer" before S
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+func titleCase(s string) string {
+	var b strings.Builder
+	startOfWord := true
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			startOfWord = true
+			b.WriteRune(r)
+			continue
+		}
+		if startOfWord {
+			b.WriteRune(unicode.ToUpper(r))
+			startOfWord = false
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+func snakeCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, "_")
+}
+
+func camelCase(s string) string {
+	words := splitWords(s)
+	for i, w := range words {
+		lower := []rune(strings.ToLower(w))
+		if i > 0 {
+			lower[0] = unicode.ToUpper(lower[0])
+		}
+		words[i] = string(lower)
+	}
+	return strings.Join(words, "")
+}
+
+func jsonPretty(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(s)), "", "  "); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func jsonMinify(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(strings.TrimSpace(s))); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// base64Decode accepts standard and URL-safe alphabets, with or without padding
+func base64Decode(s string) (string, error) {
+	trimmed := strings.TrimSpace(s)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(trimmed); err == nil {
+			return string(decoded), nil
+		}
+	}
+	return "", fmt.Errorf("invalid base64 input")
+}
+
+// shellEscape quotes s as a single POSIX shell argument
+func shellEscape(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func jsonEscape(s string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func sortLines(s string) string {
+	lines := strings.Split(s, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// dedupeLines drops repeated lines, keeping the first occurrence in place
+func dedupeLines(s string) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	blankRunRegex   = regexp.MustCompile(`\n{3,}`)
+	typographic     = strings.NewReplacer(
+		"\u2018", "'", "\u2019", "'", "\u201c", `"`, "\u201d", `"`,
+		"\u2013", "-", "\u2014", "-", "\u2026", "...",
+		"\u00a0", " ", "\u200b", "", "\u200c", "", "\u200d", "", "\ufeff", "",
+		"\r\n", "\n",
+	)
+)
+
+// stripFormatting removes terminal colors, typographic punctuation, invisible
+// characters and trailing whitespace that rich-text sources tend to leave behind
+func stripFormatting(s string) string {
+	s = ansiEscapeRegex.ReplaceAllString(s, "")
+	s = typographic.Replace(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	s = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankRunRegex.ReplaceAllString(s, "\n\n"))
+}
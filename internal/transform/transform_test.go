@@ -0,0 +1,75 @@
+package transform
+
+import "testing"
+
+func TestApply_Builtins(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"trim", "  hello \n", "hello"},
+		{"upper", "Hello", "HELLO"},
+		{"lower", "Hello", "hello"},
+		{"title", "hello wORLD", "Hello World"},
+		{"snake", "HTTPServer fooBar-baz", "http_server_foo_bar_baz"},
+		{"snake", "userID2Name", "user_id2_name"},
+		{"camel", "hello_world example", "helloWorldExample"},
+		{"json-pretty", `{"a":1,"b":[1,2]}`, "{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ]\n}"},
+		{"json-minify", "{\n  \"a\": 1\n}", `{"a":1}`},
+		{"base64-encode", "pastee", "cGFzdGVl"},
+		{"base64-decode", "cGFzdGVl", "pastee"},
+		{"base64-decode", "cGFzdGVl\n", "pastee"},
+		{"url-encode", "a b&c", "a+b%26c"},
+		{"url-decode", "a+b%26c", "a b&c"},
+		{"shell-escape", "it's", `'it'\''s'`},
+		{"json-escape", "say \"hi\" <b>\n", `"say \"hi\" <b>\n"`},
+		{"go-escape", "tab\there", `"tab\there"`},
+		{"sort-lines", "b\nc\na", "a\nb\nc"},
+		{"dedupe-lines", "a\nb\na\nc\nb", "a\nb\nc"},
+		{"strip-formatting", "\x1b[31m\u201cQuoted\u201d\x1b[0m  \n\n\n\nnext line\u200b", "\"Quoted\"\n\nnext line"},
+	}
+
+	for _, tt := range tests {
+		result, err := Apply(tt.name, tt.input)
+		if err != nil {
+			t.Errorf("Apply(%q, %q) returned error: %v", tt.name, tt.input, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("Apply(%q, %q) = %q, want %q", tt.name, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"json-pretty", "{not json"},
+		{"json-minify", "[1,"},
+		{"base64-decode", "***"},
+		{"url-decode", "%zz"},
+		{"no-such-transform", "x"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Apply(tt.name, tt.input); err == nil {
+			t.Errorf("Apply(%q, %q) expected error", tt.name, tt.input)
+		}
+	}
+}
+
+func TestAll_UniqueNames(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, tr := range All() {
+		if tr.Name == "" || tr.Label == "" || tr.Apply == nil {
+			t.Errorf("transform %+v is incomplete", tr)
+		}
+		if seen[tr.Name] {
+			t.Errorf("duplicate transform name %q", tr.Name)
+		}
+		seen[tr.Name] = true
+	}
+}
@@ -0,0 +1,181 @@
+package urlclean
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxUnwrapDepth bounds how many nested redirect wrappers are peeled off
+const maxUnwrapDepth = 5
+
+// Redirector describes a link wrapper that carries the real destination in a query parameter
+type Redirector struct {
+	Host  string // Matches the host and its subdomains
+	Path  string // Path prefix; empty matches any path
+	Param string // Query parameter holding the destination URL
+}
+
+// Rules decide which parts of a link are tracking noise
+type Rules struct {
+	StripParams  []string            // Parameter names; a trailing "*" matches a prefix
+	KeepParams   []string            // Names never stripped, even if matched above
+	Redirectors  []Redirector        // Wrappers to unwrap before stripping
+	DomainParams map[string][]string // Extra parameters stripped only on a domain and its subdomains
+}
+
+// DefaultRules returns the built-in rule set for common trackers and redirectors
+func DefaultRules() Rules {
+	return Rules{
+		StripParams: []string{
+			"utm_*", "fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid",
+			"mc_cid", "mc_eid", "yclid", "igshid", "_hsenc", "_hsmi", "mkt_tok",
+			"oly_anon_id", "oly_enc_id", "vero_id", "rb_clickid", "s_cid", "twclid", "ttclid",
+		},
+		Redirectors: []Redirector{
+			{Host: "google.com", Path: "/url", Param: "q"},
+			{Host: "google.com", Path: "/url", Param: "url"},
+			{Host: "l.facebook.com", Path: "/l.php", Param: "u"},
+			{Host: "lm.facebook.com", Path: "/l.php", Param: "u"},
+			{Host: "l.instagram.com", Param: "u"},
+			{Host: "safelinks.protection.outlook.com", Param: "url"},
+			{Host: "slack-redir.net", Path: "/link", Param: "url"},
+			{Host: "youtube.com", Path: "/redirect", Param: "q"},
+			{Host: "linkedin.com", Path: "/redir/redirect", Param: "url"},
+			{Host: "out.reddit.com", Param: "url"},
+			{Host: "steamcommunity.com", Path: "/linkfilter/", Param: "url"},
+		},
+		DomainParams: map[string][]string{
+			"amazon.com":    {"ref", "ref_", "pf_rd_*", "pd_rd_*", "psc", "th"},
+			"youtube.com":   {"si", "feature", "pp"},
+			"youtu.be":      {"si", "feature"},
+			"twitter.com":   {"s", "t", "ref_src"},
+			"x.com":         {"s", "t", "ref_src"},
+			"spotify.com":   {"si", "nd", "context"},
+			"linkedin.com":  {"trk", "trackingId", "refId", "lipi"},
+			"instagram.com": {"igsh"},
+		},
+	}
+}
+
+// Clean unwraps known redirectors and strips tracking parameters.
+// Input that is not an http(s) or www link is returned unchanged, as is a
+// link with nothing to clean, so its original formatting is preserved.
+func (r Rules) Clean(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+
+	// "www." links have no scheme; parse them as https and drop it again afterwards
+	bareWWW := strings.HasPrefix(strings.ToLower(trimmed), "www.")
+	toParse := trimmed
+	if bareWWW {
+		toParse = "https://" + trimmed
+	}
+
+	u, err := url.Parse(toParse)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return raw
+	}
+
+	changed := false
+	for i := 0; i < maxUnwrapDepth; i++ {
+		target, ok := r.unwrap(u)
+		if !ok {
+			break
+		}
+		u = target
+		bareWWW = false
+		changed = true
+	}
+
+	if query, stripped := r.stripQuery(u); stripped {
+		u.RawQuery = query
+		changed = true
+	}
+
+	if !changed {
+		return raw
+	}
+
+	cleaned := u.String()
+	if bareWWW {
+		cleaned = strings.TrimPrefix(cleaned, "https://")
+	}
+	return cleaned
+}
+
+// unwrap returns the destination of a redirector link
+func (r Rules) unwrap(u *url.URL) (*url.URL, bool) {
+	for _, rd := range r.Redirectors {
+		if !hostMatches(u.Hostname(), rd.Host) || !strings.HasPrefix(u.Path, rd.Path) {
+			continue
+		}
+		dest := u.Query().Get(rd.Param)
+		if dest == "" {
+			continue
+		}
+		target, err := url.Parse(dest)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			continue
+		}
+		return target, true
+	}
+	return nil, false
+}
+
+// stripQuery removes tracking parameters while keeping the order and
+// encoding of the remaining ones untouched
+func (r Rules) stripQuery(u *url.URL) (string, bool) {
+	if u.RawQuery == "" {
+		return "", false
+	}
+
+	patterns := r.StripParams
+	for domain, params := range r.DomainParams {
+		if hostMatches(u.Hostname(), domain) {
+			patterns = append(append([]string{}, patterns...), params...)
+		}
+	}
+
+	var kept []string
+	stripped := false
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key = pair[:i]
+		}
+		if name, err := url.QueryUnescape(key); err == nil {
+			key = name
+		}
+
+		if matchesAny(key, patterns) && !matchesAny(key, r.KeepParams) {
+			stripped = true
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&"), stripped
+}
+
+func matchesAny(name string, patterns []string) bool {
+	lower := strings.ToLower(name)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(lower, prefix) {
+				return true
+			}
+		} else if lower == p {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches reports whether host is domain or one of its subdomains
+func hostMatches(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
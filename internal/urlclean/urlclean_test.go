@@ -0,0 +1,72 @@
+package urlclean
+
+import "testing"
+
+func TestClean_DefaultRules(t *testing.T) {
+	rules := DefaultRules()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/page?utm_source=news&utm_medium=email&id=7", "https://example.com/page?id=7"},
+		{"https://example.com/?fbclid=abc123", "https://example.com/"},
+		{"https://shop.example.com/item?b=2&gclid=x&a=1", "https://shop.example.com/item?b=2&a=1"},
+		{"www.example.com/post?utm_campaign=spring", "www.example.com/post"},
+		{"https://example.com/page?id=7#section", "https://example.com/page?id=7#section"},
+		{"https://www.google.com/url?q=https://example.com/doc%3Futm_source%3Dg&sa=D", "https://example.com/doc"},
+		{"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.org%2F%3Ffbclid%3Dz&h=AT0", "https://example.org/"},
+		{"https://nam02.safelinks.protection.outlook.com/?url=https%3A%2F%2Fexample.com%2Fa&data=04", "https://example.com/a"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&si=abcdef", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://www.amazon.com/dp/B000?ref=sr_1_1&pf_rd_p=x&keywords=go", "https://www.amazon.com/dp/B000?keywords=go"},
+	}
+
+	for _, tt := range tests {
+		if result := rules.Clean(tt.input); result != tt.expected {
+			t.Errorf("Clean(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestClean_LeavesOtherInputUnchanged(t *testing.T) {
+	rules := DefaultRules()
+
+	tests := []string{
+		"https://example.com/page?id=7",
+		"https://example.com/search?q=a%20b&sort=desc",
+		"ftp://example.com/?utm_source=x",
+		"not a url",
+		"https://www.google.com/url?q=javascript:alert(1)",
+		"https://example.org/?si=keep",
+	}
+
+	for _, input := range tests {
+		if result := rules.Clean(input); result != input {
+			t.Errorf("Clean(%q) = %q, want unchanged", input, result)
+		}
+	}
+}
+
+func TestClean_CustomRules(t *testing.T) {
+	rules := DefaultRules()
+	rules.StripParams = append(rules.StripParams, "ref")
+	rules.KeepParams = []string{"utm_content"}
+	rules.DomainParams["example.net"] = []string{"session"}
+	rules.Redirectors = append(rules.Redirectors, Redirector{Host: "go.corp.example", Path: "/r", Param: "to"})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/?ref=hn&utm_content=banner&utm_source=x", "https://example.com/?utm_content=banner"},
+		{"https://app.example.net/?session=1&tab=2", "https://app.example.net/?tab=2"},
+		{"https://example.com/?session=1", "https://example.com/?session=1"},
+		{"https://go.corp.example/r?to=https%3A%2F%2Fwiki.example%2Fpage", "https://wiki.example/page"},
+	}
+
+	for _, tt := range tests {
+		if result := rules.Clean(tt.input); result != tt.expected {
+			t.Errorf("Clean(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package x11 holds helpers shared by the packages that talk to the X server
+// directly over their own xgb connection. It is empty on other systems.
+package x11
@@ -0,0 +1,27 @@
+package x11
+
+import (
+	"fmt"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// KeycodeFor finds the first keycode whose mapping includes keysym
+func KeycodeFor(conn *xgb.Conn, keysym xproto.Keysym) (xproto.Keycode, error) {
+	setup := xproto.Setup(conn)
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+
+	mapping, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		return 0, err
+	}
+
+	perKeycode := int(mapping.KeysymsPerKeycode)
+	for i, sym := range mapping.Keysyms {
+		if sym == keysym {
+			return setup.MinKeycode + xproto.Keycode(i/perKeycode), nil
+		}
+	}
+	return 0, fmt.Errorf("no keycode for keysym %#x", keysym)
+}
@@ -0,0 +1,216 @@
+package x11
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// selectionTimeout is how long Read waits for the selection owner to answer
+const selectionTimeout = time.Second
+
+// ErrNoSelection is returned by Read when nobody owns the selection or the
+// owner has no text to give
+var ErrNoSelection = errors.New("selection is empty")
+
+// Selection reads and owns an X selection such as PRIMARY as text, over its
+// own connection and an unmapped window. It is safe for concurrent use.
+type Selection struct {
+	conn      *xgb.Conn
+	win       xproto.Window
+	selection xproto.Atom
+	utf8      xproto.Atom
+	targets   xproto.Atom
+	property  xproto.Atom // Where converted selections are delivered
+	maxSize   int         // Largest text that fits in one property request
+
+	readMu sync.Mutex // One conversion at a time
+	notify chan xproto.SelectionNotifyEvent
+
+	mu     sync.Mutex
+	owning bool
+	owned  []byte // Text served while the selection is ours
+}
+
+// OpenSelection connects to the X server to access selection, e.g.
+// xproto.AtomPrimary
+func OpenSelection(selection xproto.Atom) (*Selection, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	s, err := newSelection(conn, selection)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go s.listen()
+	return s, nil
+}
+
+func newSelection(conn *xgb.Conn, selection xproto.Atom) (*Selection, error) {
+	setup := xproto.Setup(conn)
+	screen := setup.DefaultScreen(conn)
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		return nil, err
+	}
+	err = xproto.CreateWindowChecked(conn, 0, win, screen.Root, 0, 0, 1, 1, 0,
+		xproto.WindowClassInputOnly, screen.RootVisual, 0, nil).Check()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create selection window: %w", err)
+	}
+
+	s := &Selection{
+		conn:      conn,
+		win:       win,
+		selection: selection,
+		// The ChangeProperty request header takes 24 bytes of the maximum
+		maxSize: int(setup.MaximumRequestLength)*4 - 24,
+		notify:  make(chan xproto.SelectionNotifyEvent, 1),
+	}
+	for name, atom := range map[string]*xproto.Atom{"UTF8_STRING": &s.utf8, "TARGETS": &s.targets, "PASTEE_SELECTION": &s.property} {
+		reply, err := xproto.InternAtom(conn, false, uint16(len(name)), name).Reply()
+		if err != nil {
+			return nil, err
+		}
+		*atom = reply.Atom
+	}
+	return s, nil
+}
+
+// Read returns the text in the selection, or ErrNoSelection. It asks the
+// server even while the selection is ours, so the answer never lags behind
+// another client taking it.
+func (s *Selection) Read() (string, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	// Drop the late answer to a conversion that timed out
+	select {
+	case <-s.notify:
+	default:
+	}
+	xproto.ConvertSelection(s.conn, s.win, s.selection, s.utf8, s.property, xproto.TimeCurrentTime)
+
+	var ev xproto.SelectionNotifyEvent
+	select {
+	case ev = <-s.notify:
+	case <-time.After(selectionTimeout):
+		return "", errors.New("selection owner did not answer")
+	}
+	if ev.Property == xproto.AtomNone {
+		return "", ErrNoSelection
+	}
+
+	reply, err := xproto.GetProperty(s.conn, true, s.win, s.property, xproto.GetPropertyTypeAny, 0, uint32(s.maxSize/4)).Reply()
+	if err != nil {
+		return "", err
+	}
+	// Owners send large selections in increments (INCR), which are not read
+	if reply.Format != 8 || (reply.Type != s.utf8 && reply.Type != xproto.AtomString) {
+		return "", errors.New("selection is too large or not text")
+	}
+	return string(reply.Value), nil
+}
+
+// Write makes text the content of the selection until another client takes it
+func (s *Selection) Write(text string) error {
+	if len(text) > s.maxSize {
+		return fmt.Errorf("text of %d bytes is too large for the selection", len(text))
+	}
+	s.mu.Lock()
+	s.owning, s.owned = true, []byte(text)
+	s.mu.Unlock()
+
+	if err := xproto.SetSelectionOwnerChecked(s.conn, s.win, s.selection, xproto.TimeCurrentTime).Check(); err != nil {
+		return err
+	}
+	reply, err := xproto.GetSelectionOwner(s.conn, s.selection).Reply()
+	if err != nil {
+		return err
+	}
+	if reply.Owner != s.win {
+		s.release()
+		return errors.New("another client kept the selection")
+	}
+	return nil
+}
+
+// Close gives up the selection and closes the connection
+func (s *Selection) Close() {
+	s.conn.Close()
+}
+
+func (s *Selection) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owning, s.owned = false, nil
+}
+
+// listen answers other clients asking for the selection and hands conversion
+// results to Read until the connection closes
+func (s *Selection) listen() {
+	for {
+		ev, err := s.conn.WaitForEvent()
+		if ev == nil && err == nil {
+			return
+		}
+		switch e := ev.(type) {
+		case xproto.SelectionNotifyEvent:
+			select {
+			case s.notify <- e:
+			default:
+			}
+		case xproto.SelectionRequestEvent:
+			s.serve(e)
+		case xproto.SelectionClearEvent:
+			// Write may have taken the selection back since it was lost
+			if reply, err := xproto.GetSelectionOwner(s.conn, s.selection).Reply(); err != nil || reply.Owner != s.win {
+				s.release()
+			}
+		}
+	}
+}
+
+// serve answers a request for the selection we own with its text or the
+// list of targets it can be converted to
+func (s *Selection) serve(req xproto.SelectionRequestEvent) {
+	s.mu.Lock()
+	owning, data := s.owning, s.owned
+	s.mu.Unlock()
+
+	property := req.Property
+	if property == xproto.AtomNone {
+		// Obsolete clients leave the property to the owner
+		property = req.Target
+	}
+	switch {
+	case !owning || req.Selection != s.selection:
+		property = xproto.AtomNone
+	case req.Target == s.targets:
+		targets := []xproto.Atom{s.targets, s.utf8, xproto.AtomString}
+		buf := make([]byte, 4*len(targets))
+		for i, atom := range targets {
+			xgb.Put32(buf[i*4:], uint32(atom))
+		}
+		xproto.ChangeProperty(s.conn, xproto.PropModeReplace, req.Requestor, property, xproto.AtomAtom, 32, uint32(len(targets)), buf)
+	case req.Target == s.utf8 || req.Target == xproto.AtomString:
+		xproto.ChangeProperty(s.conn, xproto.PropModeReplace, req.Requestor, property, req.Target, 8, uint32(len(data)), data)
+	default:
+		property = xproto.AtomNone
+	}
+
+	ev := xproto.SelectionNotifyEvent{
+		Time:      req.Time,
+		Requestor: req.Requestor,
+		Selection: req.Selection,
+		Target:    req.Target,
+		Property:  property,
+	}
+	xproto.SendEvent(s.conn, false, req.Requestor, xproto.EventMaskNoEvent, string(ev.Bytes()))
+}
@@ -0,0 +1,45 @@
+package x11
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// TestSelection_RoundTrip needs an X server, e.g. "xvfb-run go test ./internal/x11"
+func TestSelection_RoundTrip(t *testing.T) {
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY not set; run under Xvfb")
+	}
+
+	owner, err := OpenSelection(xproto.AtomSecondary)
+	if err != nil {
+		t.Skipf("cannot connect to X server: %v", err)
+	}
+	defer owner.Close()
+	reader, err := OpenSelection(xproto.AtomSecondary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := owner.Write("héllo selection"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got != "héllo selection" {
+		t.Errorf("Read = %q, want %q", got, "héllo selection")
+	}
+
+	// Taking the selection clears the previous owner
+	if err := reader.Write("mine now"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got, err := owner.Read(); err != nil || got != "mine now" {
+		t.Errorf("Read after losing the selection = %q, %v", got, err)
+	}
+}